@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compressThreshold is the smallest payload worth compressing
+const compressThreshold = 1024
+
+// writeJSON writes a JSON response, compressing it with gzip or deflate when
+// the client accepts it and the payload is large enough to benefit
+func writeJSON(c *gin.Context, status int, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Vary", "Accept-Encoding")
+	encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+	if encoding == "" || len(body) < compressThreshold {
+		c.Data(status, "application/json; charset=utf-8", body)
+		return
+	}
+
+	var compressed bytes.Buffer
+	var writer io.WriteCloser
+	if encoding == "gzip" {
+		writer = gzip.NewWriter(&compressed)
+	} else {
+		writer, _ = flate.NewWriter(&compressed, flate.DefaultCompression)
+	}
+	writer.Write(body)
+	writer.Close()
+
+	c.Header("Content-Encoding", encoding)
+	c.Data(status, "application/json; charset=utf-8", compressed.Bytes())
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring gzip, or returns "" if neither is accepted
+func negotiateEncoding(header string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(strings.TrimSpace(params), " ", "") == "q=0" {
+			continue
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	switch {
+	case accepted["gzip"]:
+		return "gzip"
+	case accepted["deflate"]:
+		return "deflate"
+	}
+	return ""
+}
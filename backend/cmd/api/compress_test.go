@@ -0,0 +1,72 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/matching"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOrderBookGzip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine = matching.NewMatchingEngine()
+	router := setupRouter()
+
+	for i := 0; i < 100; i++ {
+		engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0-float64(i)*0.01))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orderbook/AAPL", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip content encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Response is not valid gzip: %v", err)
+	}
+
+	var snapshot orderbook.OrderBookSnapshot
+	if err := json.NewDecoder(reader).Decode(&snapshot); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+
+	if snapshot.Symbol != "AAPL" || len(snapshot.Bids) != 100 {
+		t.Errorf("Expected 100 AAPL bid levels, got %s with %d", snapshot.Symbol, len(snapshot.Bids))
+	}
+}
+
+func TestSmallResponseNotCompressed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine = matching.NewMatchingEngine()
+	router := setupRouter()
+
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orderbook/AAPL", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected small payload to be sent uncompressed, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	var snapshot orderbook.OrderBookSnapshot
+	if err := json.Unmarshal(w.Body.Bytes(), &snapshot); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+}
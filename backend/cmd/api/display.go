@@ -0,0 +1,10 @@
+package main
+
+import "github.com/acagliol/arbitrax/backend/internal/orderbook"
+
+// displaySnapshot rounds a snapshot's derived prices for display. Level
+// prices rest on-tick already and are left alone.
+func displaySnapshot(snapshot *orderbook.OrderBookSnapshot) *orderbook.OrderBookSnapshot {
+	snapshot.MidPrice = engine.DisplayPrice(snapshot.Symbol, snapshot.MidPrice)
+	return snapshot
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/matching"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDerivedPricesTickRounded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine = matching.NewMatchingEngine()
+	engine.SetSymbolConfig("AAPL", matching.SymbolConfig{TickSize: 0.05, RoundPrices: true})
+	router := setupRouter()
+
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.00))
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 20, 100.05))
+
+	// VWAP of 10 @ 100.00 and 20 @ 100.05 is 100.0333...
+	body := bytes.NewBufferString(`{"symbol":"AAPL","type":"limit","side":"buy","quantity":30,"price":100.05}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response OrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Summary == nil || response.Summary.AveragePrice != 100.05 {
+		t.Errorf("Expected average price rounded to 100.05, got %+v", response.Summary)
+	}
+
+	// Mid of 100.00 and 100.15 is 100.075, which is off-tick
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.00))
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.15))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/orderbook/AAPL", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var snapshot orderbook.OrderBookSnapshot
+	if err := json.Unmarshal(w.Body.Bytes(), &snapshot); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	if snapshot.MidPrice != 100.05 && snapshot.MidPrice != 100.10 {
+		t.Errorf("Expected mid price rounded to a 0.05 tick, got %v", snapshot.MidPrice)
+	}
+
+	// The book keeps full precision internally
+	if mid := engine.GetOrderBook("AAPL").GetMidPrice(); mid == snapshot.MidPrice {
+		t.Errorf("Expected internal mid to stay unrounded, got %v", mid)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/matching"
+)
+
+// housekeepingTask is engine maintenance that must run periodically
+type housekeepingTask struct {
+	name     string
+	interval time.Duration
+	run      func()
+}
+
+// housekeepingTasks returns the periodic maintenance the server runs on an
+// engine
+func housekeepingTasks(me *matching.MatchingEngine) []housekeepingTask {
+	return []housekeepingTask{
+		{name: "prune terminal orders", interval: time.Minute, run: func() { me.PruneTerminalOrders() }},
+		{name: "release hidden prints", interval: 100 * time.Millisecond, run: func() { me.ReleaseHiddenPrints() }},
+		{name: "prune delayed market data", interval: time.Minute, run: me.PruneDelayed},
+		{name: "continue parked orders", interval: time.Second, run: func() { me.ContinueParkedOrders() }},
+		{name: "expire stale orders", interval: time.Second, run: func() { me.ExpireStaleOrders() }},
+		{name: "release throttled orders", interval: 10 * time.Millisecond, run: func() { me.ReleaseThrottled() }},
+		{name: "run due auctions", interval: 100 * time.Millisecond, run: func() { me.RunDueAuctions() }},
+	}
+}
+
+// startHousekeeping runs each task on its own ticker until stop is closed
+func startHousekeeping(tasks []housekeepingTask, stop <-chan struct{}) {
+	for _, task := range tasks {
+		go func(task housekeepingTask) {
+			ticker := time.NewTicker(task.interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					task.run()
+				case <-stop:
+					return
+				}
+			}
+		}(task)
+	}
+}
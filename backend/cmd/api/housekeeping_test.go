@@ -0,0 +1,103 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/matching"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestHousekeepingRunsTasks(t *testing.T) {
+	var runs atomic.Int32
+	stop := make(chan struct{})
+	startHousekeeping([]housekeepingTask{{name: "count", interval: time.Millisecond, run: func() { runs.Add(1) }}}, stop)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runs.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the task to run repeatedly, ran %d times", runs.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(stop)
+	time.Sleep(10 * time.Millisecond)
+	after := runs.Load()
+	time.Sleep(10 * time.Millisecond)
+	if runs.Load() != after {
+		t.Error("Expected the task to stop with housekeeping")
+	}
+}
+
+func TestHousekeepingPrunesTerminalOrders(t *testing.T) {
+	me := matching.NewMatchingEngine()
+	me.SetOrderRetention(time.Nanosecond)
+	order := me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 1, 0)
+	me.SubmitOrder(order)
+	time.Sleep(time.Millisecond)
+
+	for _, task := range housekeepingTasks(me) {
+		task.run()
+	}
+	if _, exists := me.LookupOrder(order.ID); exists {
+		t.Error("Expected the terminal order to be pruned")
+	}
+}
+
+func TestHousekeepingExpiresStaleOrders(t *testing.T) {
+	me := matching.NewMatchingEngine()
+	me.SetMaxOrderLifetime(time.Nanosecond)
+	order := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 99.0)
+	me.SubmitOrder(order)
+	time.Sleep(time.Millisecond)
+
+	for _, task := range housekeepingTasks(me) {
+		task.run()
+	}
+	if order.Status != models.OrderStatusCancelled {
+		t.Errorf("Expected the stale order to expire, got %s", order.Status)
+	}
+}
+
+func TestHousekeepingReleasesThrottledOrders(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	me := matching.NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetMatchRate(1)
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 99.0))
+	waiting := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 98.0)
+	me.SubmitOrder(waiting)
+	mock.Advance(time.Second)
+
+	for _, task := range housekeepingTasks(me) {
+		task.run()
+	}
+	if _, resting := me.GetOrderBook("AAPL").GetOrder(waiting.ID); !resting {
+		t.Error("Expected the throttled order released into the book")
+	}
+}
+
+func TestHousekeepingRunsDueAuctions(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	me := matching.NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetVolatilityAuctions(matching.VolatilityAuctionConfig{Duration: time.Minute, MaxImbalance: 0.8})
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 200, 99.0))
+	if !me.InVolatilityAuction("AAPL") {
+		t.Fatal("Expected the imbalanced book to start an auction")
+	}
+	mock.Advance(time.Minute)
+
+	for _, task := range housekeepingTasks(me) {
+		task.run()
+	}
+	if me.InVolatilityAuction("AAPL") {
+		t.Error("Expected the due auction run without waiting for another order")
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/gin-gonic/gin"
+)
+
+// JitterDistribution is the shape of the random delay added to a response
+type JitterDistribution string
+
+const (
+	// JitterUniform draws delays evenly from [Min, Max]
+	JitterUniform JitterDistribution = "uniform"
+	// JitterExponential draws delays mostly near Min with a long tail,
+	// capped at Max
+	JitterExponential JitterDistribution = "exponential"
+)
+
+// maxJitterConnections bounds how many connections keep their own random
+// source before the set is started afresh
+const maxJitterConnections = 1024
+
+// JitterConfig configures synthetic latency added before order
+// acknowledgments and market-data responses, so clients can be tested
+// against variable and reordered delivery
+type JitterConfig struct {
+	Min          time.Duration
+	Max          time.Duration
+	Distribution JitterDistribution
+	Seed         int64 // Combined with each connection's address to seed its source
+}
+
+// latencyJitter delays responses by a random amount drawn from a source
+// kept per connection, so each client sees its own sequence of delays
+type latencyJitter struct {
+	config  JitterConfig
+	clock   clock.Clock
+	sleep   func(time.Duration)
+	sources map[string]*rand.Rand // Random source by remote address
+	mutex   sync.Mutex
+}
+
+// newLatencyJitter creates a jitter source measuring delays with c
+func newLatencyJitter(config JitterConfig, c clock.Clock) *latencyJitter {
+	if config.Distribution == "" {
+		config.Distribution = JitterUniform
+	}
+	return &latencyJitter{
+		config:  config,
+		clock:   c,
+		sleep:   time.Sleep,
+		sources: make(map[string]*rand.Rand),
+	}
+}
+
+// jitterFromEnv reads JITTER_MIN, JITTER_MAX and JITTER_DISTRIBUTION,
+// returning nil when no jitter is configured
+func jitterFromEnv() (*latencyJitter, error) {
+	maxStr := os.Getenv("JITTER_MAX")
+	if maxStr == "" {
+		return nil, nil
+	}
+
+	var config JitterConfig
+	var err error
+	if config.Max, err = time.ParseDuration(maxStr); err != nil {
+		return nil, fmt.Errorf("invalid JITTER_MAX: %w", err)
+	}
+	if minStr := os.Getenv("JITTER_MIN"); minStr != "" {
+		if config.Min, err = time.ParseDuration(minStr); err != nil {
+			return nil, fmt.Errorf("invalid JITTER_MIN: %w", err)
+		}
+	}
+	if config.Min < 0 || config.Max < config.Min {
+		return nil, fmt.Errorf("JITTER_MAX %s must be at least JITTER_MIN %s", config.Max, config.Min)
+	}
+
+	config.Distribution = JitterDistribution(os.Getenv("JITTER_DISTRIBUTION"))
+	switch config.Distribution {
+	case "", JitterUniform, JitterExponential:
+	default:
+		return nil, fmt.Errorf("unknown JITTER_DISTRIBUTION %q", config.Distribution)
+	}
+
+	config.Seed = time.Now().UnixNano()
+	return newLatencyJitter(config, clock.Real{}), nil
+}
+
+// handler runs the request, then holds its response back by a random amount
+// before it is flushed, reporting the delay applied in the
+// X-Synthetic-Latency header. A nil jitter passes requests straight through.
+func (j *latencyJitter) handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if j == nil {
+			c.Next()
+			return
+		}
+
+		writer := &heldWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		start := j.clock.Now()
+		j.sleep(j.delay(c.Request.RemoteAddr))
+		c.Header("X-Synthetic-Latency", j.clock.Now().Sub(start).String())
+		writer.flush()
+	}
+}
+
+// heldWriter buffers a response so it can be delayed after the handler has
+// produced it
+type heldWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *heldWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *heldWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *heldWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *heldWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *heldWriter) Status() int {
+	return w.status
+}
+
+func (w *heldWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *heldWriter) Written() bool {
+	return w.written
+}
+
+// flush sends the held status and body on to the client
+func (w *heldWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// delay draws the next delay for a connection
+func (j *latencyJitter) delay(connection string) time.Duration {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	source, exists := j.sources[connection]
+	if !exists {
+		if len(j.sources) >= maxJitterConnections {
+			j.sources = make(map[string]*rand.Rand)
+		}
+		hash := fnv.New64a()
+		hash.Write([]byte(connection))
+		source = rand.New(rand.NewSource(j.config.Seed ^ int64(hash.Sum64())))
+		j.sources[connection] = source
+	}
+
+	spread := j.config.Max - j.config.Min
+	if spread <= 0 {
+		return j.config.Min
+	}
+
+	var offset time.Duration
+	switch j.config.Distribution {
+	case JitterExponential:
+		// Mean a quarter of the way into the range, with the tail capped
+		offset = time.Duration(source.ExpFloat64() * float64(spread) / 4)
+		if offset > spread {
+			offset = spread
+		}
+	default:
+		offset = time.Duration(source.Int63n(int64(spread) + 1))
+	}
+	return j.config.Min + offset
+}
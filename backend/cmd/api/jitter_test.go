@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestJitterDelaysAcknowledgmentsWithinBounds(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, distribution := range []JitterDistribution{JitterUniform, JitterExponential} {
+		mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+		j := newLatencyJitter(JitterConfig{
+			Min:          5 * time.Millisecond,
+			Max:          50 * time.Millisecond,
+			Distribution: distribution,
+			Seed:         42,
+		}, mock)
+		j.sleep = mock.Advance
+
+		router := gin.New()
+		router.POST("/ack", j.handler(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 100; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/ack", nil)
+			req.RemoteAddr = fmt.Sprintf("10.0.0.%d:5000", i%4)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			delay, err := time.ParseDuration(w.Header().Get("X-Synthetic-Latency"))
+			if err != nil {
+				t.Fatalf("Expected a latency header, got %q", w.Header().Get("X-Synthetic-Latency"))
+			}
+			if delay < 5*time.Millisecond || delay > 50*time.Millisecond {
+				t.Errorf("Expected %s delay within [5ms, 50ms], got %s", distribution, delay)
+			}
+			seen[delay] = true
+		}
+		if len(seen) < 10 {
+			t.Errorf("Expected %s delays to vary, got %d distinct values", distribution, len(seen))
+		}
+	}
+}
+
+func TestNilJitterPassesThrough(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var j *latencyJitter
+	router := gin.New()
+	router.GET("/book", j.handler(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/book", nil))
+	if w.Code != http.StatusOK || w.Header().Get("X-Synthetic-Latency") != "" {
+		t.Errorf("Expected an undelayed 200, got %d with latency %q", w.Code, w.Header().Get("X-Synthetic-Latency"))
+	}
+}
+
+func TestJitterHoldsTheResponseAfterTheHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	j := newLatencyJitter(JitterConfig{Min: 20 * time.Millisecond, Max: 20 * time.Millisecond, Seed: 42}, mock)
+	handled := false
+	j.sleep = func(d time.Duration) {
+		if !handled {
+			t.Error("Expected the handler to run before the delay")
+		}
+		mock.Advance(d)
+	}
+
+	var acknowledged time.Time
+	router := gin.New()
+	router.POST("/ack", j.handler(), func(c *gin.Context) {
+		handled = true
+		acknowledged = mock.Now()
+		c.JSON(http.StatusCreated, gin.H{"status": "accepted"})
+	})
+
+	start := mock.Now()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ack", nil))
+
+	if !acknowledged.Equal(start) {
+		t.Errorf("Expected the order acknowledged before the delay, got %s after", acknowledged.Sub(start))
+	}
+	if w.Code != http.StatusCreated || w.Body.String() != `{"status":"accepted"}` {
+		t.Errorf("Expected the held 201 response delivered, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Synthetic-Latency") != "20ms" {
+		t.Errorf("Expected a 20ms latency header, got %q", w.Header().Get("X-Synthetic-Latency"))
+	}
+}
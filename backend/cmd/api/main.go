@@ -1,41 +1,128 @@
 package main
 
 import (
+	"crypto/subtle"
+	"errors"
+	"log"
 	"net/http"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/acagliol/arbitrax/backend/internal/execreport"
 	"github.com/acagliol/arbitrax/backend/internal/matching"
 	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/acagliol/arbitrax/backend/internal/webhook"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type HealthResponse struct {
-	Status    string    `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	Service   string    `json:"service"`
+	Status          string    `json:"status"`
+	Timestamp       time.Time `json:"timestamp"`
+	Service         string    `json:"service"`
+	DegradedSymbols []string  `json:"degraded_symbols,omitempty"`
+	StaleSymbols    []string  `json:"stale_symbols,omitempty"`
 }
 
 type OrderRequest struct {
-	Symbol   string  `json:"symbol" binding:"required"`
-	Type     string  `json:"type" binding:"required,oneof=market limit stop_loss"`
-	Side     string  `json:"side" binding:"required,oneof=buy sell"`
-	Quantity float64 `json:"quantity" binding:"required,gt=0"`
-	Price    float64 `json:"price"` // Required for limit and stop_loss orders
+	Symbol        string  `json:"symbol" binding:"required"`
+	Type          string  `json:"type" binding:"required,oneof=market limit stop_loss stop_limit"`
+	Side          string  `json:"side" binding:"required,oneof=buy sell"`
+	Quantity      float64 `json:"quantity" binding:"required,gt=0"`
+	Price         float64 `json:"price"`                      // Required for limit, stop_loss and stop_limit orders
+	StopPrice     float64 `json:"stop_price" binding:"gte=0"` // Required for stop_limit orders
+	Currency      string  `json:"currency"`
+	AccountID     string  `json:"account_id"`
+	STPGroup      string  `json:"stp_group"`
+	ClientOrderID string  `json:"client_order_id"`
+	MinFill       float64 `json:"min_fill_quantity" binding:"gte=0"`
+	Hidden        bool    `json:"hidden"`
+	PostOnly      bool    `json:"post_only"`
+	ConfirmRest   bool    `json:"confirm_rest"`
+	Peg           string  `json:"peg" binding:"omitempty,oneof=midpoint primary"`
+	PegOffset     float64 `json:"peg_offset"`
+	TimeInForce   string  `json:"time_in_force" binding:"omitempty,oneof=GTC IOC"`
 }
 
 type OrderResponse struct {
-	Order  *models.Order   `json:"order"`
-	Trades []*models.Trade `json:"trades,omitempty"`
+	Order    *models.Order         `json:"order"`
+	Trades   []*models.Trade       `json:"trades,omitempty"`
+	Summary  *matching.FillSummary `json:"fill_summary,omitempty"`
+	Warnings []string              `json:"warnings,omitempty"`
 }
 
 var engine *matching.MatchingEngine
 
+// staleThreshold is how long a book may go without changing before it is
+// reported stale, 0 to disable
+var staleThreshold time.Duration
+
+// jitter adds synthetic latency to acknowledgments and market data, nil
+// when disabled
+var jitter *latencyJitter
+
 func main() {
 	// Initialize matching engine
 	engine = matching.NewMatchingEngine()
+	if delay := os.Getenv("MARKET_DATA_DELAY"); delay != "" {
+		d, err := time.ParseDuration(delay)
+		if err != nil {
+			log.Fatalf("invalid MARKET_DATA_DELAY: %v", err)
+		}
+		engine.SetMarketDataDelay(d)
+	}
+	if history := os.Getenv("TRADE_HISTORY"); history != "" {
+		n, err := strconv.Atoi(history)
+		if err != nil || n < 0 {
+			log.Fatalf("invalid TRADE_HISTORY: %q", history)
+		}
+		engine.SetTradeHistory(n)
+	}
+	if threshold := os.Getenv("STALE_QUOTE_THRESHOLD"); threshold != "" {
+		d, err := time.ParseDuration(threshold)
+		if err != nil {
+			log.Fatalf("invalid STALE_QUOTE_THRESHOLD: %v", err)
+		}
+		staleThreshold = d
+	}
+	j, err := jitterFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	jitter = j
+	if url := os.Getenv("TRADE_WEBHOOK_URL"); url != "" {
+		config := webhook.Config{URL: url}
+		if format := os.Getenv("TRADE_WEBHOOK_FORMAT"); format != "" {
+			formatter, err := execreport.ByName(format)
+			if err != nil {
+				log.Fatalf("invalid TRADE_WEBHOOK_FORMAT: %v", err)
+			}
+			config.Formatter, config.Orders = formatter, engine.LookupOrder
+		}
+		notifier := webhook.NewNotifier(config)
+		engine.OnEvent(notifier.HandleEvent)
+	}
+	if err := engine.Validate(); err != nil {
+		log.Fatalf("matching engine config is invalid: %v", err)
+	}
+	if errs := engine.ValidateState(); len(errs) > 0 {
+		log.Fatalf("matching engine state is invalid: %v", errors.Join(errs...))
+	}
 
-	// Create Gin router
+	startHousekeeping(housekeepingTasks(engine), make(chan struct{}))
+
+	router := setupRouter()
+
+	// Start server
+	router.Run(":8080")
+}
+
+// setupRouter creates the Gin router with all routes registered
+func setupRouter() *gin.Engine {
 	router := gin.Default()
 
 	// Enable CORS
@@ -52,11 +139,21 @@ func main() {
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, HealthResponse{
+		response := HealthResponse{
 			Status:    "healthy",
 			Timestamp: time.Now(),
 			Service:   "arbitrax-backend",
-		})
+		}
+		if degraded := engine.DegradedSymbols(); len(degraded) > 0 {
+			response.Status = "degraded"
+			response.DegradedSymbols = degraded
+		}
+		if staleThreshold > 0 {
+			if stale := engine.StaleSymbols(staleThreshold); len(stale) > 0 {
+				response.StaleSymbols = stale
+			}
+		}
+		c.JSON(http.StatusOK, response)
 	})
 
 	// Serve static frontend
@@ -75,13 +172,164 @@ func main() {
 		})
 
 		// Order endpoints
-		v1.POST("/orders", submitOrder)
-		v1.GET("/orderbook/:symbol", getOrderBook)
-		v1.GET("/trades/:symbol", getTrades)
+		v1.POST("/orders", jitter.handler(), submitOrder)
+		v1.GET("/orders/:symbol/:id/position", getQueuePosition)
+		v1.GET("/conditionals/:symbol", getPendingConditionals)
+
+		// Market data endpoints serve the delayed tier delayed data, or
+		// refuse it where there is no delayed form
+		tier := marketDataTier(os.Getenv("MARKET_DATA_TOKEN"))
+		v1.GET("/orderbook/:symbol", jitter.handler(), tier, getOrderBook)
+		v1.GET("/orderbook/:symbol/sweep", tier, realtimeOnly, getSweepCost)
+		v1.GET("/orderbook/:symbol/state", jitter.handler(), tier, realtimeOnly, getBookState)
+		v1.GET("/orderbook/:symbol/checksums", tier, realtimeOnly, getBookChecksums)
+		v1.GET("/ws/orderbook/:symbol", tier, streamOrderBook)
+		v1.GET("/ladder/:symbol", jitter.handler(), tier, realtimeOnly, getLadder)
+		v1.GET("/trades/:symbol", jitter.handler(), tier, getTrades)
+		v1.GET("/ws/trades/:symbol", tier, streamTrades)
+		v1.GET("/prices/:symbol", tier, getPriceHistory)
+		v1.GET("/volume/:symbol", tier, getVolumeProfile)
+		v1.GET("/rates/:symbol", tier, realtimeOnly, getRates)
+		v1.GET("/volume/:symbol/prices", tier, getVolumeAtPrice)
+
+		// Admin endpoints
+		admin := v1.Group("/admin", requireAdmin(os.Getenv("ADMIN_TOKEN")))
+		admin.POST("/kill", killSwitch)
+		admin.GET("/stats/:symbol", getSymbolStats)
+		admin.GET("/trades", getTradesInRange)
+		admin.PUT("/reference/:symbol", setReferencePrice)
+		admin.PUT("/quotes/:symbol", replaceQuotes)
 	}
 
-	// Start server
-	router.Run(":8080")
+	return router
+}
+
+// requireAdmin only lets through requests bearing the admin token, which
+// are served real-time data. Admin endpoints are disabled when no token is
+// configured.
+func requireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin endpoints are disabled"})
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Set("realtime", true)
+		c.Next()
+	}
+}
+
+// marketDataTier marks requests bearing the real-time market-data token as
+// real-time. Everyone else gets the delayed tier when a market-data delay
+// is configured. Without a token every request is real-time.
+func marketDataTier(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		realtime := token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+		c.Set("realtime", realtime)
+		c.Next()
+	}
+}
+
+// delayedTier reports whether a request, tagged by marketDataTier, must be
+// served delayed data
+func delayedTier(c *gin.Context) bool {
+	return !c.GetBool("realtime") && engine.GetMarketDataDelay() > 0
+}
+
+// realtimeOnly refuses the delayed tier market data that has no delayed
+// form
+func realtimeOnly(c *gin.Context) {
+	if delayedTier(c) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this market data needs a real-time market data token"})
+		return
+	}
+	c.Next()
+}
+
+// killSwitch halts all trading and cancels every resting order
+func killSwitch(c *gin.Context) {
+	cancelled := engine.KillSwitch()
+	c.JSON(http.StatusOK, gin.H{
+		"halted":    true,
+		"cancelled": cancelled,
+	})
+}
+
+// ReplaceQuotesRequest is a market maker's full two-sided quote set
+type ReplaceQuotesRequest struct {
+	AccountID string                `json:"account_id" binding:"required"`
+	Bids      []matching.LevelQuote `json:"bids"`
+	Asks      []matching.LevelQuote `json:"asks"`
+}
+
+// replaceQuotes swaps an account's resting orders in a symbol for a new
+// quote set in one call
+func replaceQuotes(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	var req ReplaceQuotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trades, err := engine.ReplaceBook(symbol, req.AccountID, req.Bids, req.Asks)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"symbol": symbol,
+		"trades": trades,
+	})
+}
+
+// setReferencePrice seeds the price a symbol's bands are measured from
+// until it first trades
+func setReferencePrice(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	var req struct {
+		Price float64 `json:"price" binding:"required,gt=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	engine.SetReferencePrice(symbol, req.Price)
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":          symbol,
+		"reference_price": req.Price,
+	})
+}
+
+// getSymbolStats returns a symbol's resting totals, hidden orders included,
+// its current activity rates and its execution latency
+func getSymbolStats(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	ob := engine.GetOrderBook(symbol)
+	if ob == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order book not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":       symbol,
+		"resting":      ob.TotalResting(),
+		"rates":        engine.GetRates(symbol),
+		"latency":      engine.ExecutionLatency(symbol),
+		"last_updated": ob.LastUpdated(),
+		"stale":        staleThreshold > 0 && time.Since(ob.LastUpdated()) > staleThreshold,
+	})
 }
 
 // submitOrder handles order submission
@@ -92,33 +340,179 @@ func submitOrder(c *gin.Context) {
 		return
 	}
 
-	// Validate price for limit and stop_loss orders
-	if (req.Type == "limit" || req.Type == "stop_loss") && req.Price <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "price is required for limit and stop_loss orders"})
+	// Validate price for limit and stop orders. Pegged orders are priced by
+	// the engine.
+	if (req.Type == "limit" || req.Type == "stop_loss" || req.Type == "stop_limit") && req.Price <= 0 && req.Peg == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price is required for limit, stop_loss and stop_limit orders"})
+		return
+	}
+	if req.Type == "stop_limit" && req.StopPrice <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stop_price is required for stop_limit orders"})
+		return
+	}
+
+	if req.MinFill > req.Quantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_fill_quantity cannot exceed quantity"})
 		return
 	}
 
 	// Create order
-	order := models.NewOrder(
+	order := engine.NewOrder(
 		req.Symbol,
 		models.OrderType(req.Type),
 		models.OrderSide(req.Side),
 		req.Quantity,
 		req.Price,
 	)
+	order.AccountID = req.AccountID
+	order.STPGroup = req.STPGroup
+	order.Currency = req.Currency
+	order.ClientOrderID = req.ClientOrderID
+	order.MinFillQuantity = req.MinFill
+	order.Hidden = req.Hidden
+	order.PostOnly = req.PostOnly
+	order.ConfirmRest = req.ConfirmRest
+	order.Peg = models.PegType(req.Peg)
+	order.PegOffset = req.PegOffset
+	order.TimeInForce = models.TimeInForce(req.TimeInForce)
+	order.StopPrice = req.StopPrice
 
 	// Submit to matching engine
 	trades := engine.SubmitOrder(order)
+	if order.Status == models.OrderStatusRejected {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": order.RejectReason})
+		return
+	}
 
-	c.JSON(http.StatusOK, OrderResponse{
-		Order:  order,
-		Trades: trades,
-	})
+	response := OrderResponse{
+		Order:    order,
+		Trades:   trades,
+		Warnings: order.Warnings,
+	}
+	if len(trades) > 0 {
+		response.Summary = engine.DisplaySummary(order.Symbol, engine.SummarizeOrder(order, trades))
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
-// getOrderBook returns the current order book for a symbol
+// getOrderBook returns the current order book for a symbol. The delayed tier
+// gets the book as it was one market-data delay ago.
 func getOrderBook(c *gin.Context) {
-	symbol := c.Param("symbol")
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	var snapshot *orderbook.OrderBookSnapshot
+	var ob *orderbook.OrderBook
+	if delayedTier(c) {
+		delayed, ok := engine.DelayedSnapshot(symbol)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order book not found"})
+			return
+		}
+		c.Header("X-Market-Data-Delay", engine.GetMarketDataDelay().String())
+		snapshot = delayed
+	} else if ob = engine.GetOrderBook(symbol); ob == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order book not found"})
+		return
+	}
+
+	// A side selects one page of levels, counted from the touch
+	if side := c.Query("side"); side != "" {
+		getOrderBookPage(c, symbol, ob, snapshot, side)
+		return
+	}
+
+	// Optional depth limit and price grouping
+	depth := 0
+	if depthStr := c.Query("depth"); depthStr != "" {
+		d, err := strconv.Atoi(depthStr)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "depth must be a positive integer"})
+			return
+		}
+		depth = d
+	}
+
+	grouping := 0.0
+	if groupingStr := c.Query("grouping"); groupingStr != "" {
+		g, err := strconv.ParseFloat(groupingStr, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "grouping must be a number"})
+			return
+		}
+		if err := orderbook.ValidateGrouping(g, engine.GetSymbolConfig(symbol).TickSize); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		grouping = g
+	}
+
+	switch {
+	case snapshot != nil:
+		if depth > 0 || grouping > 0 {
+			snapshot = snapshot.Regroup(depth, grouping)
+		}
+	case depth == 0 && grouping == 0:
+		snapshot = ob.Snapshot()
+	default:
+		snapshot = ob.Depth(depth, grouping)
+	}
+	writeJSON(c, http.StatusOK, displaySnapshot(snapshot))
+}
+
+// getOrderBookPage returns one page of a side's levels, for books too deep
+// to fetch in a single snapshot. The page is read from the delayed snapshot
+// if there is one, otherwise from the live book.
+func getOrderBookPage(c *gin.Context, symbol string, ob *orderbook.OrderBook, delayed *orderbook.OrderBookSnapshot, side string) {
+	if side != string(models.OrderSideBuy) && side != string(models.OrderSideSell) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "side must be buy or sell"})
+		return
+	}
+
+	from, err := strconv.Atoi(c.DefaultQuery("from", "0"))
+	if err != nil || from < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a non-negative integer"})
+		return
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", "100"))
+	if err != nil || count <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be a positive integer"})
+		return
+	}
+
+	var levels []orderbook.PriceLevelSnapshot
+	if delayed != nil {
+		levels = delayed.Page(models.OrderSide(side), from, count)
+	} else {
+		levels = ob.SnapshotPage(models.OrderSide(side), from, count)
+	}
+	writeJSON(c, http.StatusOK, gin.H{
+		"symbol": symbol,
+		"side":   side,
+		"from":   from,
+		"levels": levels,
+	})
+}
+
+// getBookState returns the snapshot, BBO, stats and checksum read together
+func getBookState(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	ob := engine.GetOrderBook(symbol)
+	if ob == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order book not found"})
+		return
+	}
+
+	state := ob.FullState()
+	displaySnapshot(state.Snapshot)
+	writeJSON(c, http.StatusOK, state)
+}
+
+// getBookChecksums returns the book's recent sequence and checksum pairs,
+// oldest first, so a client can find where its local copy diverged
+func getBookChecksums(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
 
 	ob := engine.GetOrderBook(symbol)
 	if ob == nil {
@@ -126,15 +520,276 @@ func getOrderBook(c *gin.Context) {
 		return
 	}
 
-	snapshot := ob.Snapshot()
-	c.JSON(http.StatusOK, snapshot)
+	checksums := ob.RecentChecksums(historyLimit(c))
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":    symbol,
+		"checksums": checksums,
+		"count":     len(checksums),
+	})
+}
+
+// getSweepCost returns the quantity and notional needed to move the price
+// to a target by sweeping one side of the book
+func getSweepCost(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	ob := engine.GetOrderBook(symbol)
+	if ob == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order book not found"})
+		return
+	}
+
+	side := models.OrderSide(c.Query("side"))
+	if side != models.OrderSideBuy && side != models.OrderSideSell {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "side must be buy or sell"})
+		return
+	}
+
+	target, err := strconv.ParseFloat(c.Query("price"), 64)
+	if err != nil || target <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price must be a positive number"})
+		return
+	}
+
+	quantity, notional := ob.CostToMoveTo(side, target)
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":   symbol,
+		"side":     side,
+		"price":    target,
+		"quantity": quantity,
+		"notional": notional,
+	})
+}
+
+// getLadder returns the book as a single price-sorted ladder
+func getLadder(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	ob := engine.GetOrderBook(symbol)
+	if ob == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order book not found"})
+		return
+	}
+
+	levels := 0
+	if levelsStr := c.Query("levels"); levelsStr != "" {
+		l, err := strconv.Atoi(levelsStr)
+		if err != nil || l <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "levels must be a positive integer"})
+			return
+		}
+		levels = l
+	}
+
+	ladder := ob.Ladder(levels)
+	ladder.MidPrice = engine.DisplayPrice(symbol, ladder.MidPrice)
+	writeJSON(c, http.StatusOK, ladder)
+}
+
+// getQueuePosition returns an order's place in the queue at its price level
+func getQueuePosition(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	ob := engine.GetOrderBook(symbol)
+	if ob == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order book not found"})
+		return
+	}
+
+	rank, aheadQuantity, err := ob.QueuePosition(orderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order_id":       orderID,
+		"rank":           rank,
+		"ahead_quantity": aheadQuantity,
+	})
+}
+
+// getPendingConditionals returns the caller's stop and conditional orders
+// for a symbol that have not triggered yet. The account is required, as
+// dormant stops are never shown to other accounts.
+func getPendingConditionals(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+	account := c.Query("account")
+	if account == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account is required"})
+		return
+	}
+
+	orders := make([]*models.Order, 0)
+	for _, order := range engine.GetPendingConditionals(symbol) {
+		if order.AccountID == account {
+			orders = append(orders, order)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"symbol": symbol,
+		"orders": orders,
+		"count":  len(orders),
+	})
 }
 
 // getTrades returns recent trades for a symbol
 func getTrades(c *gin.Context) {
-	symbol := c.Param("symbol")
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
 
-	// Get limit from query param (default 50, max 500)
+	limit := historyLimit(c)
+
+	var trades []*models.Trade
+	if delayedTier(c) {
+		trades = engine.DelayedTrades(symbol, limit)
+	} else {
+		trades = engine.GetPublicTrades(symbol, limit)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"symbol": symbol,
+		"trades": trades,
+		"count":  len(trades),
+	})
+}
+
+// getTradesInRange returns all trades between the from and to query params
+// for reconciliation, including hidden-order and odd-lot trades the public
+// tape holds back
+func getTradesInRange(c *gin.Context) {
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+
+	trades := engine.GetTradesInRange(from, to)
+	c.JSON(http.StatusOK, gin.H{
+		"from":   from,
+		"to":     to,
+		"trades": trades,
+		"count":  len(trades),
+	})
+}
+
+// getRates returns a symbol's rolling order and trade rates
+func getRates(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+	c.JSON(http.StatusOK, engine.GetRates(symbol))
+}
+
+// maxVolumeBuckets bounds the size of a volume profile response
+const maxVolumeBuckets = 1000
+
+// getVolumeProfile returns trade count and volume per time bucket
+func getVolumeProfile(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+
+	bucket, err := time.ParseDuration(c.DefaultQuery("bucket", "1m"))
+	if err != nil || bucket <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be a positive duration"})
+		return
+	}
+	if to.Sub(from)/bucket > maxVolumeBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many buckets for the requested range"})
+		return
+	}
+
+	buckets := engine.VolumeProfile(symbol, bucket, from, to)
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":  symbol,
+		"bucket":  bucket.String(),
+		"buckets": buckets,
+	})
+}
+
+// getVolumeAtPrice returns traded volume by execution price and the point
+// of control
+func getVolumeAtPrice(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+
+	volumes := engine.VolumeAtPrice(symbol, from, to)
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":           symbol,
+		"volumes":          volumes,
+		"point_of_control": matching.PointOfControl(volumes),
+	})
+}
+
+// timeRange reads the from and to query params as RFC 3339 timestamps,
+// writing a 400 response and returning false if they are missing or invalid.
+// For the delayed tier the range ends no later than one market-data delay
+// ago.
+func timeRange(c *gin.Context) (from, to time.Time, ok bool) {
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+		return from, to, false
+	}
+
+	to, err = time.Parse(time.RFC3339, c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+		return from, to, false
+	}
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return from, to, false
+	}
+
+	if delayedTier(c) {
+		if cutoff := engine.DelayedCutoff(); to.After(cutoff) {
+			to = cutoff
+		}
+		if !from.Before(to) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the delayed tier has no data in the requested range yet"})
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
+
+// getPriceHistory returns the recent last-trade price series for a symbol
+func getPriceHistory(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+	limit := historyLimit(c)
+
+	var points []matching.PricePoint
+	if delayedTier(c) {
+		points = engine.DelayedPriceHistory(symbol, limit)
+	} else {
+		points = engine.GetPriceHistory(symbol, limit)
+	}
+
+	// Newest first by default, oldest first on request
+	if c.Query("order") == "asc" {
+		slices.Reverse(points)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"symbol": symbol,
+		"prices": points,
+		"count":  len(points),
+	})
+}
+
+// historyLimit reads the limit query param (default 50, max 500)
+func historyLimit(c *gin.Context) int {
 	limit := 50
 	if limitStr := c.Query("limit"); limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
@@ -144,11 +799,5 @@ func getTrades(c *gin.Context) {
 			}
 		}
 	}
-
-	trades := engine.GetRecentTrades(symbol, limit)
-	c.JSON(http.StatusOK, gin.H{
-		"symbol": symbol,
-		"trades": trades,
-		"count":  len(trades),
-	})
+	return limit
 }
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/matching"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSubmitOrderReturnsWarnings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine = matching.NewMatchingEngine()
+	engine.SetCircuitBreaker(matching.CircuitBreakerConfig{
+		Tiers: []matching.PriceBandTier{{MinPrice: 0, BandPercent: 0.05}},
+	})
+	router := setupRouter()
+
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 120.0))
+
+	body := bytes.NewBufferString(`{"symbol":"AAPL","type":"market","side":"buy","quantity":20}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response OrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Trades) != 1 {
+		t.Errorf("Expected 1 trade, got %d", len(response.Trades))
+	}
+	if len(response.Warnings) != 1 || !strings.Contains(response.Warnings[0], "collared") {
+		t.Errorf("Expected a collar warning, got %v", response.Warnings)
+	}
+}
+
+func TestOrderBookDelayedTier(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("MARKET_DATA_TOKEN", "realtime-secret")
+	engine = matching.NewMatchingEngine()
+	engine.SetMarketDataDelay(time.Hour)
+	router := setupRouter()
+
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+
+	get := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/orderbook/AAPL", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The book is younger than the delay, so the delayed tier can't see it
+	if w := get(""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 on the delayed tier, got %d", w.Code)
+	}
+	if w := get("realtime-secret"); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 on the real-time tier, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSubmitIOCOrderReportsCancelledRemainder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine = matching.NewMatchingEngine()
+	router := setupRouter()
+
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 4, 100.0))
+
+	body := bytes.NewBufferString(`{"symbol":"AAPL","type":"limit","side":"buy","quantity":10,"price":100,"time_in_force":"IOC"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response OrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Order.Status != models.OrderStatusCancelled || response.Order.CancelledQuantity != 6 {
+		t.Errorf("Expected the remaining 6 reported cancelled, got %s with %g", response.Order.Status, response.Order.CancelledQuantity)
+	}
+	if response.Order.FilledQuantity != 4 || engine.GetOrderBook("AAPL").GetBestBid() != 0 {
+		t.Error("Expected 4 filled and nothing left resting")
+	}
+}
+
+func TestSubmitStopLimitRequiresStopPrice(t *testing.T) {
+	engine = matching.NewMatchingEngine()
+	router := setupRouter()
+
+	body := bytes.NewBufferString(`{"symbol":"AAPL","type":"stop_limit","side":"sell","quantity":5,"price":95}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPendingConditionalsByAccount(t *testing.T) {
+	engine = matching.NewMatchingEngine()
+	router := setupRouter()
+
+	mine := models.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideSell, 5, 95.0)
+	mine.AccountID = "acct-1"
+	theirs := models.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideSell, 5, 94.0)
+	theirs.AccountID = "acct-2"
+	engine.SubmitOrder(mine)
+	engine.SubmitOrder(theirs)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/conditionals/aapl?account=acct-1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Orders []*models.Order `json:"orders"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Orders) != 1 || response.Orders[0].ID != mine.ID {
+		t.Errorf("Expected only acct-1's stop, got %d orders", len(response.Orders))
+	}
+}
+
+func TestPendingConditionalsNeedAnAccount(t *testing.T) {
+	engine = matching.NewMatchingEngine()
+	router := setupRouter()
+
+	stop := models.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideSell, 5, 95.0)
+	stop.AccountID = "acct-1"
+	engine.SubmitOrder(stop)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/conditionals/aapl", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without an account, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTradesInRangeIsAdminOnly(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "admin-secret")
+	engine = matching.NewMatchingEngine()
+	engine.SetHiddenPrintDelay(time.Hour)
+	router := setupRouter()
+
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+	hidden.Hidden = true
+	engine.SubmitOrder(hidden)
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+
+	now := time.Now()
+	query := "?from=" + now.Add(-time.Hour).Format(time.RFC3339) + "&to=" + now.Add(time.Hour).Format(time.RFC3339)
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/api/v1/trades", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected no public range route, got %d", w.Code)
+	}
+	if w := get("/api/v1/admin/trades", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the range refused without the admin token, got %d", w.Code)
+	}
+
+	// Back office sees the hidden trade the tape is still holding back
+	w := get("/api/v1/admin/trades", "admin-secret")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"count":1`) {
+		t.Errorf("Expected the hidden trade in the admin range, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDelayedTierMarketData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("MARKET_DATA_TOKEN", "realtime-secret")
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	engine = matching.NewMatchingEngine()
+	engine.SetClock(mock)
+	engine.SetMarketDataDelay(15 * time.Minute)
+	router := setupRouter()
+
+	for i := 0; i < 10; i++ {
+		engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0-float64(i)*0.01))
+	}
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 100.0))
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 5, 0))
+	mock.Advance(20 * time.Minute)
+
+	// Trading after the snapshot the delayed tier sees
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 101.0))
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 5, 0))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The delayed book honours depth and grouping
+	w := get("/api/v1/orderbook/AAPL?depth=2&grouping=0.05")
+	var snapshot orderbook.OrderBookSnapshot
+	if err := json.Unmarshal(w.Body.Bytes(), &snapshot); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected a delayed book, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(snapshot.Bids) != 2 || snapshot.Bids[0].Price != 99.0 || snapshot.Bids[1].Orders != 5 {
+		t.Errorf("Expected bid buckets at 99 and 98.95, got %+v", snapshot.Bids)
+	}
+
+	// So does a page
+	w = get("/api/v1/orderbook/AAPL?side=buy&from=8&count=5")
+	var page struct {
+		Levels []orderbook.PriceLevelSnapshot `json:"levels"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || len(page.Levels) != 2 {
+		t.Errorf("Expected the last 2 delayed bid levels, got %s", w.Body.String())
+	}
+
+	// Trades and prices stop one delay ago
+	w = get("/api/v1/trades/AAPL")
+	var trades struct {
+		Trades []models.Trade `json:"trades"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &trades); err != nil || len(trades.Trades) != 1 || trades.Trades[0].Price != 100.0 {
+		t.Errorf("Expected only the delayed trade at 100, got %s", w.Body.String())
+	}
+	w = get("/api/v1/prices/AAPL")
+	if !strings.Contains(w.Body.String(), `"count":1`) {
+		t.Errorf("Expected one delayed price, got %s", w.Body.String())
+	}
+
+	// Data without a delayed form is refused
+	for _, path := range []string{"/api/v1/ladder/AAPL", "/api/v1/orderbook/AAPL/state", "/api/v1/orderbook/AAPL/sweep?side=buy&price=101", "/api/v1/orderbook/AAPL/checksums", "/api/v1/rates/AAPL"} {
+		if w := get(path); w.Code != http.StatusForbidden {
+			t.Errorf("Expected %s refused to the delayed tier, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// bookStreamInterval is the most often a book stream sends a snapshot;
+	// changes in between are conflated into the next one
+	bookStreamInterval = 100 * time.Millisecond
+	// streamWriteTimeout bounds how long a send to a stalled client may take
+	streamWriteTimeout = 5 * time.Second
+	// streamPingInterval is how often idle streams are pinged, and
+	// streamPongTimeout how long a client has to answer before it is dropped
+	streamPingInterval = 30 * time.Second
+	streamPongTimeout  = 2 * streamPingInterval
+)
+
+// upgrader upgrades stream requests to WebSockets. Any origin is accepted,
+// matching the API's CORS policy.
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// openStream upgrades a request to a WebSocket and starts reading from it,
+// so pongs are handled and a disconnect is noticed. The returned channel is
+// closed once the client goes away.
+func openStream(c *gin.Context) (*websocket.Conn, <-chan struct{}, bool) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already replied with an error
+		return nil, nil, false
+	}
+
+	conn.SetReadDeadline(time.Now().Add(streamPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongTimeout))
+	})
+
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	return conn, gone, true
+}
+
+// sendStream writes one message to a stream, reporting whether the client is
+// still there to receive more
+func sendStream(conn *websocket.Conn, message any) bool {
+	conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	if err := conn.WriteJSON(message); err != nil {
+		log.Printf("closing stream to %s: %v", conn.RemoteAddr(), err)
+		return false
+	}
+	return true
+}
+
+// pingStream pings an idle stream, reporting whether the ping was sent
+func pingStream(conn *websocket.Conn) bool {
+	return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)) == nil
+}
+
+// realtimeStream rejects a stream request from the delayed market-data tier,
+// reporting whether it may go ahead. Streams are real-time only; the delayed
+// tier keeps to polling.
+func realtimeStream(c *gin.Context) bool {
+	if delayedTier(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "streams need a real-time market data token"})
+		return false
+	}
+	return true
+}
+
+// streamOrderBook upgrades to a WebSocket and pushes a snapshot of a
+// symbol's book each time it changes, at most once per bookStreamInterval.
+// The current book is sent first, if there is one.
+func streamOrderBook(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	if !realtimeStream(c) {
+		return
+	}
+
+	conn, gone, ok := openStream(c)
+	if !ok {
+		return
+	}
+	defer conn.Close()
+
+	sub := engine.SubscribeBook(symbol, bookStreamInterval)
+	defer sub.Close()
+
+	if ob := engine.GetOrderBook(symbol); ob != nil {
+		if !sendStream(conn, displaySnapshot(ob.Snapshot())) {
+			return
+		}
+	}
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case snapshot := <-sub.C:
+			if !sendStream(conn, displaySnapshot(snapshot)) {
+				return
+			}
+		case <-ping.C:
+			if !pingStream(conn) {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// streamTrades upgrades to a WebSocket and sends each of a symbol's public
+// trades as it executes, in execution order. A client that falls too far
+// behind is disconnected rather than sent a feed with gaps.
+func streamTrades(c *gin.Context) {
+	symbol := engine.NormalizeSymbol(c.Param("symbol"))
+
+	if !realtimeStream(c) {
+		return
+	}
+
+	// Subscribe before the upgrade completes, so no trade executed after the
+	// client sees the connection open is missed
+	sub := engine.SubscribeTrades(symbol, 0)
+	defer sub.Close()
+
+	conn, gone, ok := openStream(c)
+	if !ok {
+		return
+	}
+	defer conn.Close()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case trade, open := <-sub.C:
+			if !open {
+				if sub.Lagged() {
+					message := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow to keep up with trades")
+					conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(streamWriteTimeout))
+				}
+				return
+			}
+			if !sendStream(conn, trade) {
+				return
+			}
+		case <-ping.C:
+			if !pingStream(conn) {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
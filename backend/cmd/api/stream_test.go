@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/matching"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// dialStream opens a WebSocket to a path on a test server
+func dialStream(t *testing.T, server *httptest.Server, path string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + path
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial %s: %v", path, err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return conn
+}
+
+func TestStreamOrderBook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine = matching.NewMatchingEngine()
+	server := httptest.NewServer(setupRouter())
+	defer server.Close()
+
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+
+	conn := dialStream(t, server, "/api/v1/ws/orderbook/AAPL")
+	defer conn.Close()
+
+	var snapshot orderbook.OrderBookSnapshot
+	if err := conn.ReadJSON(&snapshot); err != nil {
+		t.Fatalf("Failed to read the initial snapshot: %v", err)
+	}
+	if len(snapshot.Bids) != 1 || snapshot.Bids[0].Price != 99.0 {
+		t.Fatalf("Expected the initial snapshot to hold the 99 bid, got %+v", snapshot.Bids)
+	}
+
+	// A burst of changes arrives conflated, ending at the latest state
+	for i := 0; i < 50; i++ {
+		engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 101.0+float64(i)*0.01))
+	}
+	updates := 0
+	for {
+		if err := conn.ReadJSON(&snapshot); err != nil {
+			t.Fatalf("Failed to read an update: %v", err)
+		}
+		updates++
+		if len(snapshot.Asks) == 50 {
+			break
+		}
+	}
+	if updates >= 50 {
+		t.Errorf("Expected the burst to be conflated, got %d updates", updates)
+	}
+}
+
+func TestStreamOrderBookStopsOnDisconnect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine = matching.NewMatchingEngine()
+	server := httptest.NewServer(setupRouter())
+	defer server.Close()
+
+	before := runtime.NumGoroutine()
+	conn := dialStream(t, server, "/api/v1/ws/orderbook/AAPL")
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	var snapshot orderbook.OrderBookSnapshot
+	if err := conn.ReadJSON(&snapshot); err != nil {
+		t.Fatalf("Failed to read an update: %v", err)
+	}
+	conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the stream's goroutines to exit, %d left over %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamTrades(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine = matching.NewMatchingEngine()
+	server := httptest.NewServer(setupRouter())
+	defer server.Close()
+
+	conn := dialStream(t, server, "/api/v1/ws/trades/AAPL")
+	defer conn.Close()
+
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 100.0))
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 101.0))
+	engine.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideSell, 5, 50.0))
+	engine.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeMarket, models.OrderSideBuy, 5, 0))
+	expected := engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 8, 0))
+	if len(expected) != 2 {
+		t.Fatalf("Expected 2 trades, got %d", len(expected))
+	}
+
+	for _, next := range expected {
+		var trade models.Trade
+		if err := conn.ReadJSON(&trade); err != nil {
+			t.Fatalf("Failed to read a trade: %v", err)
+		}
+		if trade.ID != next.ID || trade.Price != next.Price || trade.Symbol != "AAPL" {
+			t.Errorf("Expected trade %s at %g, got %s at %g", next.ID, next.Price, trade.ID, trade.Price)
+		}
+	}
+}
+
+func TestStreamTradesRealtimeOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("MARKET_DATA_TOKEN", "realtime-secret")
+	engine = matching.NewMatchingEngine()
+	engine.SetMarketDataDelay(time.Hour)
+	server := httptest.NewServer(setupRouter())
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws/trades/AAPL"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected the delayed tier refused with 403, got %v", err)
+	}
+
+	header := http.Header{"Authorization": []string{"Bearer realtime-secret"}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("Expected the real-time tier to connect, got %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 100.0))
+	engine.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 5, 0))
+	var trade models.Trade
+	if err := conn.ReadJSON(&trade); err != nil || trade.Price != 100.0 {
+		t.Errorf("Expected the trade at 100 on the real-time tier, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time so time-dependent logic can be tested
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is a Clock backed by the system time
+type Real struct{}
+
+// Now returns the current system time
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Mock is a manually controlled Clock for tests
+type Mock struct {
+	now   time.Time
+	mutex sync.Mutex
+}
+
+// NewMock creates a mock clock starting at the given time
+func NewMock(now time.Time) *Mock {
+	return &Mock{now: now}
+}
+
+// Now returns the mock's current time
+func (m *Mock) Now() time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.now
+}
+
+// Advance moves the mock's time forward by d
+func (m *Mock) Advance(d time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = m.now.Add(d)
+}
+
+// Set moves the mock to the given time
+func (m *Mock) Set(now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
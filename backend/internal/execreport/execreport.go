@@ -0,0 +1,189 @@
+package execreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// Formatter renders the execution report for one order's side of a trade
+type Formatter interface {
+	// ContentType is the media type of the rendered report
+	ContentType() string
+	// Format renders the report. The order's cumulative fields are those the
+	// trade recorded for it, or as they stand if it recorded none.
+	Format(trade *models.Trade, order *models.Order) ([]byte, error)
+}
+
+// ByName returns the formatter for a name: "json" or "fix"
+func ByName(name string) (Formatter, error) {
+	switch name {
+	case "json":
+		return JSONFormatter{}, nil
+	case "fix":
+		return FIXFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown execution report format %q", name)
+	}
+}
+
+// Report is an execution report for one order's fill, modelled on the FIX
+// ExecutionReport
+type Report struct {
+	ExecID        uuid.UUID          `json:"exec_id"` // The trade's ID
+	OrderID       uuid.UUID          `json:"order_id"`
+	ClientOrderID string             `json:"client_order_id,omitempty"`
+	AccountID     string             `json:"account_id,omitempty"`
+	Symbol        string             `json:"symbol"`
+	Side          models.OrderSide   `json:"side"`
+	Status        models.OrderStatus `json:"status"`
+	OrderQty      float64            `json:"order_qty"`
+	LastQty       float64            `json:"last_qty"` // Quantity of this fill
+	LastPx        float64            `json:"last_px"`  // Price of this fill
+	CumQty        float64            `json:"cum_qty"`
+	LeavesQty     float64            `json:"leaves_qty"` // Still open, 0 once filled or cancelled
+	AvgPx         float64            `json:"avg_px"`
+	Liquidity     string             `json:"liquidity"` // "taker" or "maker"
+	TransactTime  time.Time          `json:"transact_time"`
+}
+
+// NewReport builds the execution report for an order's side of a trade.
+// Status, CumQty, LeavesQty and AvgPx are as the fill left them, so each
+// report of a multi-fill order shows its own progress. A trade made outside
+// the engine has no fills recorded, so the order's current state is read
+// under the default tolerance instead.
+func NewReport(trade *models.Trade, order *models.Order) Report {
+	fill, recorded := trade.FillFor(order.ID)
+	if !recorded {
+		fill = order.FillState(models.DefaultQuantityEpsilon)
+	}
+
+	report := Report{
+		ExecID:        trade.ID,
+		OrderID:       order.ID,
+		ClientOrderID: order.ClientOrderID,
+		AccountID:     order.AccountID,
+		Symbol:        trade.Symbol,
+		Side:          order.Side,
+		Status:        fill.Status,
+		OrderQty:      order.Quantity,
+		LastQty:       trade.Quantity,
+		LastPx:        trade.Price,
+		CumQty:        fill.CumQty,
+		LeavesQty:     fill.LeavesQty,
+		AvgPx:         fill.AvgPx,
+		Liquidity:     "maker",
+		TransactTime:  trade.Timestamp,
+	}
+	if trade.TakerSide == order.Side {
+		report.Liquidity = "taker"
+	}
+	return report
+}
+
+// JSONFormatter renders reports as JSON objects
+type JSONFormatter struct{}
+
+// ContentType returns the JSON media type
+func (JSONFormatter) ContentType() string {
+	return "application/json"
+}
+
+// Format renders the report as JSON
+func (JSONFormatter) Format(trade *models.Trade, order *models.Order) ([]byte, error) {
+	return json.Marshal(NewReport(trade, order))
+}
+
+// fixSOH is the standard FIX field delimiter
+const fixSOH = "\x01"
+
+// fixTimeFormat is the FIX UTCTimestamp format with milliseconds
+const fixTimeFormat = "20060102-15:04:05.000"
+
+// FIXFormatter renders reports as the body fields of a FIX 4.4
+// ExecutionReport (35=8) in tag=value form. The session layer adds the
+// header, BodyLength and CheckSum.
+type FIXFormatter struct {
+	Delimiter string // Between fields, SOH if empty
+}
+
+// ContentType returns the FIX media type
+func (FIXFormatter) ContentType() string {
+	return "application/fix"
+}
+
+// Format renders the report as FIX tags
+func (f FIXFormatter) Format(trade *models.Trade, order *models.Order) ([]byte, error) {
+	report := NewReport(trade, order)
+
+	side := "1"
+	if report.Side == models.OrderSideSell {
+		side = "2"
+	}
+	liquidity := "2" // Removed liquidity
+	if report.Liquidity == "maker" {
+		liquidity = "1" // Added liquidity
+	}
+
+	fields := [][2]string{
+		{"35", "8"},
+		{"37", report.OrderID.String()},
+		{"11", report.ClientOrderID},
+		{"17", report.ExecID.String()},
+		{"150", "F"}, // Trade
+		{"39", fixOrdStatus(report.Status)},
+		{"1", report.AccountID},
+		{"55", report.Symbol},
+		{"54", side},
+		{"38", fixFloat(report.OrderQty)},
+		{"32", fixFloat(report.LastQty)},
+		{"31", fixFloat(report.LastPx)},
+		{"151", fixFloat(report.LeavesQty)},
+		{"14", fixFloat(report.CumQty)},
+		{"6", fixFloat(report.AvgPx)},
+		{"851", liquidity},
+		{"60", report.TransactTime.UTC().Format(fixTimeFormat)},
+	}
+
+	delimiter := f.Delimiter
+	if delimiter == "" {
+		delimiter = fixSOH
+	}
+	var b strings.Builder
+	for _, field := range fields {
+		if field[1] == "" {
+			continue // Optional fields the order doesn't have
+		}
+		b.WriteString(field[0])
+		b.WriteByte('=')
+		b.WriteString(field[1])
+		b.WriteString(delimiter)
+	}
+	return []byte(b.String()), nil
+}
+
+// fixOrdStatus maps an order status to the FIX OrdStatus (39) code
+func fixOrdStatus(status models.OrderStatus) string {
+	switch status {
+	case models.OrderStatusPartial:
+		return "1"
+	case models.OrderStatusFilled:
+		return "2"
+	case models.OrderStatusCancelled:
+		return "4"
+	case models.OrderStatusRejected:
+		return "8"
+	default:
+		return "0"
+	}
+}
+
+// fixFloat formats a quantity or price with no more digits than it needs
+func fixFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,81 @@
+package execreport
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// fill returns a partially filled buy and the trade that took it to 4 of 10
+func fill() (*models.Trade, *models.Order) {
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.5)
+	order.ID = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	order.ClientOrderID = "client-1"
+	order.AccountID = "acct-7"
+
+	at := time.Date(2024, 1, 2, 9, 30, 15, 250_000_000, time.UTC)
+	order.Fill(4, 150.25, at, models.DefaultQuantityEpsilon)
+
+	trade := models.NewTrade("AAPL", order.ID, uuid.MustParse("22222222-2222-2222-2222-222222222222"), 150.25, 4)
+	trade.ID = uuid.MustParse("33333333-3333-3333-3333-333333333333")
+	trade.Timestamp = at
+	trade.TakerSide = models.OrderSideBuy
+	return trade, order
+}
+
+func TestJSONFormatter(t *testing.T) {
+	trade, order := fill()
+
+	body, err := JSONFormatter{}.Format(trade, order)
+	if err != nil {
+		t.Fatalf("Expected JSON, got %v", err)
+	}
+
+	expected := `{"exec_id":"33333333-3333-3333-3333-333333333333",` +
+		`"order_id":"11111111-1111-1111-1111-111111111111","client_order_id":"client-1",` +
+		`"account_id":"acct-7","symbol":"AAPL","side":"buy","status":"partial",` +
+		`"order_qty":10,"last_qty":4,"last_px":150.25,"cum_qty":4,"leaves_qty":6,` +
+		`"avg_px":150.25,"liquidity":"taker","transact_time":"2024-01-02T09:30:15.25Z"}`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+	if ct := (JSONFormatter{}).ContentType(); ct != "application/json" {
+		t.Errorf("Expected application/json, got %s", ct)
+	}
+}
+
+func TestFIXFormatter(t *testing.T) {
+	trade, order := fill()
+
+	body, err := FIXFormatter{Delimiter: "|"}.Format(trade, order)
+	if err != nil {
+		t.Fatalf("Expected FIX tags, got %v", err)
+	}
+
+	expected := "35=8|37=11111111-1111-1111-1111-111111111111|11=client-1|" +
+		"17=33333333-3333-3333-3333-333333333333|150=F|39=1|1=acct-7|55=AAPL|54=1|" +
+		"38=10|32=4|31=150.25|151=6|14=4|6=150.25|851=2|60=20240102-09:30:15.250|"
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+
+	// SOH is the default delimiter
+	soh, _ := FIXFormatter{}.Format(trade, order)
+	if string(soh) != strings.ReplaceAll(expected, "|", "\x01") {
+		t.Errorf("Expected SOH-delimited fields, got %q", soh)
+	}
+}
+
+func TestByName(t *testing.T) {
+	for _, name := range []string{"json", "fix"} {
+		if _, err := ByName(name); err != nil {
+			t.Errorf("Expected a %s formatter, got %v", name, err)
+		}
+	}
+	if _, err := ByName("binary"); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,80 @@
+package matching
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// AdjustSymbol restates a symbol after a split or similar corporate action:
+// prices are multiplied by priceFactor and quantities by qtyFactor, for the
+// resting book and the last and reference prices as well as every order
+// still waiting to reach it (stops, parked, pause-queued, throttled and
+// conditional orders). A 2:1 split is AdjustSymbol(symbol, 0.5, 2). Time
+// priority is preserved.
+func (me *MatchingEngine) AdjustSymbol(symbol string, priceFactor, qtyFactor float64) error {
+	for _, factor := range []float64{priceFactor, qtyFactor} {
+		if math.IsNaN(factor) || math.IsInf(factor, 0) || factor <= 0 {
+			return fmt.Errorf("adjustment factors must be positive finite numbers, got %g and %g", priceFactor, qtyFactor)
+		}
+	}
+
+	symbol = me.NormalizeSymbol(symbol)
+
+	// The whole restatement happens under the engine lock, so nothing waiting
+	// on it can reach the book with prices from before the adjustment
+	me.mutex.Lock()
+	ob, exists := me.orderBooks[symbol]
+	if !exists {
+		me.mutex.Unlock()
+		return fmt.Errorf("no order book for %s", symbol)
+	}
+
+	ob.Rescale(priceFactor, qtyFactor)
+	if reference, exists := me.references[symbol]; exists {
+		me.references[symbol] = reference * priceFactor
+	}
+	if auction, exists := me.auctions[symbol]; exists {
+		auction.reference *= priceFactor
+	}
+	me.rescalePending(symbol, priceFactor, qtyFactor)
+	me.mutex.Unlock()
+
+	me.bookChanged(symbol)
+	me.checkBBO(symbol, BBOCauseAdjust)
+	return nil
+}
+
+// rescalePending restates the orders for a symbol that are held outside its
+// book. The caller must hold the mutex.
+func (me *MatchingEngine) rescalePending(symbol string, priceFactor, qtyFactor float64) {
+	rescale := func(orders []*models.Order) {
+		for _, order := range orders {
+			if order.Symbol == symbol {
+				order.Rescale(priceFactor, qtyFactor)
+			}
+		}
+	}
+
+	rescale(me.stopOrders[symbol])
+	rescale(me.parked[symbol])
+	if pause, exists := me.paused[symbol]; exists {
+		rescale(pause.queued)
+	}
+	if me.throttle != nil {
+		rescale(me.throttle.pending)
+	}
+
+	// Conditional orders may trade in the symbol, watch it, or both
+	for referenceSymbol, pending := range me.conditionals {
+		for _, conditional := range pending {
+			if conditional.Order.Symbol == symbol {
+				conditional.Order.Rescale(priceFactor, qtyFactor)
+			}
+			if referenceSymbol == symbol {
+				conditional.TriggerPrice *= priceFactor
+			}
+		}
+	}
+}
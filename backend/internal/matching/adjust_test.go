@@ -0,0 +1,83 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestAdjustSymbolForSplit(t *testing.T) {
+	me := NewMatchingEngine()
+	first := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0)
+	second := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 20, 100.0)
+	ask := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 102.0)
+	me.SubmitOrder(first)
+	me.SubmitOrder(second)
+	me.SubmitOrder(ask)
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideSell, 4, 0))
+
+	if err := me.AdjustSymbol("AAPL", 0.5, 2); err != nil {
+		t.Fatalf("Expected the adjustment to succeed, got %v", err)
+	}
+
+	if first.Price != 50.0 || first.Quantity != 20 || first.RemainingQuantity(models.DefaultQuantityEpsilon) != 12 {
+		t.Errorf("Expected the partly filled bid at 50 with 12 left of 20, got %g with %g of %g", first.Price, first.RemainingQuantity(models.DefaultQuantityEpsilon), first.Quantity)
+	}
+	if second.Price != 50.0 || second.Quantity != 40 || ask.Price != 51.0 || ask.Quantity != 20 {
+		t.Errorf("Expected prices halved and quantities doubled, got %g x %g and %g x %g", second.Price, second.Quantity, ask.Price, ask.Quantity)
+	}
+
+	ob := me.GetOrderBook("AAPL")
+	if ob.LastPrice != 50.0 || ob.GetBestBid() != 50.0 || ob.GetBestAsk() != 51.0 {
+		t.Errorf("Expected last 50 and 50 / 51 after the split, got %g and %g / %g", ob.LastPrice, ob.GetBestBid(), ob.GetBestAsk())
+	}
+
+	// Time priority survives: the first bid still fills first
+	trades := me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideSell, 12, 0))
+	if len(trades) != 1 || trades[0].BuyOrderID != first.ID || trades[0].Price != 50.0 {
+		t.Errorf("Expected the first bid to keep priority, got %d trades", len(trades))
+	}
+
+	if err := me.AdjustSymbol("AAPL", 0, 2); err == nil {
+		t.Error("Expected a zero price factor to be rejected")
+	}
+}
+
+func TestAdjustSymbolRestatesPendingOrders(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+	me.SubmitOrder(me.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideBuy, 10, 300.0))
+
+	stop := me.NewOrder("AAPL", models.OrderTypeStopLimit, models.OrderSideSell, 10, 94.0)
+	stop.StopPrice = 95.0
+	me.SubmitOrder(stop)
+
+	conditional := me.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideSell, 10, 310.0)
+	if err := me.SubmitConditional(conditional, "AAPL", TriggerAtOrBelow, 90.0); err != nil {
+		t.Fatalf("Expected the conditional order accepted, got %v", err)
+	}
+
+	me.PauseMatching("AAPL", PauseQueue)
+	queued := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+	me.SubmitOrder(queued)
+
+	if err := me.AdjustSymbol("AAPL", 0.5, 2); err != nil {
+		t.Fatalf("Expected the adjustment to succeed, got %v", err)
+	}
+
+	if stop.StopPrice != 47.5 || stop.Price != 47.0 || stop.Quantity != 20 {
+		t.Errorf("Expected the stop restated to 47.5 / 47 x 20, got %g / %g x %g", stop.StopPrice, stop.Price, stop.Quantity)
+	}
+	if queued.Price != 50.0 || queued.Quantity != 20 {
+		t.Errorf("Expected the queued order restated to 50 x 20, got %g x %g", queued.Price, queued.Quantity)
+	}
+	pending := me.GetConditionalOrders("AAPL")
+	if len(pending) != 1 || pending[0].TriggerPrice != 45.0 || conditional.Price != 310.0 {
+		t.Errorf("Expected only the AAPL trigger restated, got %+v", pending)
+	}
+
+	// The queued order meets the restated bid on resume
+	if trades := me.ResumeMatching("AAPL"); len(trades) != 1 || trades[0].Price != 50.0 || trades[0].Quantity != 20 {
+		t.Errorf("Expected the queued order to fill 20 at 50, got %d trades", len(trades))
+	}
+}
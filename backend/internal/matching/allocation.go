@@ -0,0 +1,209 @@
+package matching
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/google/uuid"
+)
+
+// MatchingMode decides how an incoming order's quantity is shared between
+// the resting orders at a price level
+type MatchingMode string
+
+const (
+	// MatchingModeFIFO fills resting orders in time priority
+	MatchingModeFIFO MatchingMode = "fifo"
+	// MatchingModeProRata shares the fill in proportion to resting size
+	MatchingModeProRata MatchingMode = "pro_rata"
+	// MatchingModeSizePriority fills the largest resting orders first
+	MatchingModeSizePriority MatchingMode = "size_priority"
+)
+
+// SetMatchingMode switches the allocation used at each price level. It is
+// safe to call while orders are being submitted: a submission reads the mode
+// once before matching, so the switch only affects subsequent submissions
+// and never changes allocation part-way through an order.
+func (me *MatchingEngine) SetMatchingMode(mode MatchingMode) error {
+	switch mode {
+	case MatchingModeFIFO, MatchingModeProRata, MatchingModeSizePriority:
+	default:
+		return fmt.Errorf("unknown matching mode %q", mode)
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.matchingMode = mode
+	return nil
+}
+
+// GetMatchingMode returns the current matching mode
+func (me *MatchingEngine) GetMatchingMode() MatchingMode {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	return me.matchingMode
+}
+
+// allocation is the quantity an incoming order takes from one resting order
+type allocation struct {
+	order    *models.Order
+	quantity float64
+}
+
+// matchLevel matches an incoming order against a single price level using
+// the given mode, filling both sides and pruning consumed resting orders.
+// At most maxTrades trades are made if maxTrades is positive. Resting orders
+// in unsettled are skipped, and any whose fill can't settle are added to it.
+func (me *MatchingEngine) matchLevel(ob *orderbook.OrderBook, order *models.Order, level *orderbook.PriceLevel, mode MatchingMode, maxTrades int, unsettled map[uuid.UUID]bool) []*models.Trade {
+	if !me.preventSelfTrades(ob, order, level) {
+		return nil
+	}
+
+	var allocations []allocation
+	remaining := order.RemainingQuantity(me.tolerance.Quantity)
+	for _, queue := range me.levelQueues(settleable(level.Orders, unsettled)) {
+		for _, alloc := range allocate(mode, queue, remaining, me.tolerance.Quantity) {
+			allocations = append(allocations, alloc)
+			remaining -= alloc.quantity
+		}
+	}
+
+	if maxTrades > 0 && len(allocations) > maxTrades {
+		allocations = allocations[:maxTrades]
+	}
+
+	oddLots := me.GetSymbolConfig(ob.Symbol).OddLots
+
+	trades := make([]*models.Trade, 0, len(allocations))
+	for _, alloc := range allocations {
+		oppositeOrder := alloc.order
+		tradeQty := alloc.quantity
+		tradePrice := me.tradePrice(ob.Symbol, order, oppositeOrder)
+
+		// Skip a resting order that can't settle before anything is printed
+		if !me.settles(oppositeOrder, tradeQty, tradePrice) {
+			order.Warn(fmt.Sprintf("resting order %s could not settle %g at %g and was skipped", oppositeOrder.ID, tradeQty, tradePrice))
+			unsettled[oppositeOrder.ID] = true
+			continue
+		}
+
+		// Create trade
+		trade := me.newTrade(order, oppositeOrder, tradePrice, tradeQty)
+
+		// Fill both orders
+		order.Fill(tradeQty, tradePrice, trade.Timestamp, me.tolerance.Quantity)
+		oppositeOrder.Fill(tradeQty, tradePrice, trade.Timestamp, me.tolerance.Quantity)
+		if oppositeOrder.Status == models.OrderStatusFilled {
+			me.recordLatency(oppositeOrder)
+		}
+
+		// Update last price
+		if !trade.OddLot || !oddLots.SuppressLastPrice {
+			ob.LastPrice = tradePrice
+			ob.LastTrade = trade
+		}
+
+		// Update account positions
+		me.recordFill(trade)
+		if order.Side == models.OrderSideBuy {
+			trade.RecordFills(order, oppositeOrder, me.tolerance.Quantity)
+		} else {
+			trade.RecordFills(oppositeOrder, order, me.tolerance.Quantity)
+		}
+
+		trades = append(trades, trade)
+	}
+
+	// Remove filled resting orders from the book
+	ob.PruneLevel(level)
+	if len(trades) > 0 {
+		ob.MarkChanged(orderbook.ChangeTrade)
+	}
+
+	return trades
+}
+
+// allocate shares quantity between a queue of resting orders using mode,
+// leaving out shares within epsilon
+func allocate(mode MatchingMode, queue []*models.Order, quantity, epsilon float64) []allocation {
+	if quantity <= epsilon || len(queue) == 0 {
+		return nil
+	}
+
+	switch mode {
+	case MatchingModeProRata:
+		return allocateProRata(queue, quantity, epsilon)
+	case MatchingModeSizePriority:
+		bySize := make([]*models.Order, len(queue))
+		copy(bySize, queue)
+		// Stable so equal sizes keep time priority
+		sort.SliceStable(bySize, func(i, j int) bool {
+			return bySize[i].RemainingQuantity(epsilon) > bySize[j].RemainingQuantity(epsilon)
+		})
+		return allocateInSequence(bySize, quantity, epsilon)
+	default:
+		return allocateInSequence(queue, quantity, epsilon)
+	}
+}
+
+// allocateInSequence fills resting orders one after another until quantity
+// is used up
+func allocateInSequence(orders []*models.Order, quantity, epsilon float64) []allocation {
+	allocations := make([]allocation, 0)
+	for _, resting := range orders {
+		if quantity <= epsilon {
+			break
+		}
+		qty := min(quantity, resting.RemainingQuantity(epsilon))
+		if qty <= epsilon {
+			continue
+		}
+		allocations = append(allocations, allocation{order: resting, quantity: qty})
+		quantity -= qty
+	}
+	return allocations
+}
+
+// allocateProRata shares quantity between resting orders in proportion to
+// their remaining size. Whatever float rounding leaves over goes to the
+// orders in time priority.
+func allocateProRata(orders []*models.Order, quantity, epsilon float64) []allocation {
+	total := 0.0
+	for _, resting := range orders {
+		total += resting.RemainingQuantity(epsilon)
+	}
+	if total <= quantity {
+		return allocateInSequence(orders, quantity, epsilon)
+	}
+
+	shares := make([]float64, len(orders))
+	allocated := 0.0
+	for i, resting := range orders {
+		shares[i] = quantity * resting.RemainingQuantity(epsilon) / total
+		allocated += shares[i]
+	}
+
+	leftover := quantity - allocated
+	for i, resting := range orders {
+		if leftover <= epsilon {
+			break
+		}
+		extra := min(leftover, resting.RemainingQuantity(epsilon)-shares[i])
+		if extra > 0 {
+			shares[i] += extra
+			leftover -= extra
+		}
+	}
+
+	allocations := make([]allocation, 0, len(orders))
+	for i, resting := range orders {
+		if shares[i] > 0 {
+			allocations = append(allocations, allocation{order: resting, quantity: shares[i]})
+		}
+	}
+	return allocations
+}
@@ -0,0 +1,68 @@
+package matching
+
+import (
+	"math"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestSwitchMatchingModeMidSession(t *testing.T) {
+	me := NewMatchingEngine()
+
+	// Under FIFO the first resting order takes the whole fill
+	first := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0)
+	second := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 300, 150.0)
+	me.SubmitOrder(first)
+	me.SubmitOrder(second)
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 40, 150.0))
+	if first.FilledQuantity != 40 || second.FilledQuantity != 0 {
+		t.Fatalf("Expected FIFO fill of 40/0, got %f/%f", first.FilledQuantity, second.FilledQuantity)
+	}
+
+	if err := me.SetMatchingMode(MatchingModeProRata); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Remaining sizes are 60 and 300, so 72 splits 12/60
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 72, 150.0))
+	if len(trades) != 2 {
+		t.Fatalf("Expected pro-rata fill against both orders, got %d trades", len(trades))
+	}
+
+	if math.Abs(first.FilledQuantity-52) > 1e-9 {
+		t.Errorf("Expected first order filled 52 in total, got %f", first.FilledQuantity)
+	}
+	if math.Abs(second.FilledQuantity-60) > 1e-9 {
+		t.Errorf("Expected second order filled 60, got %f", second.FilledQuantity)
+	}
+}
+
+func TestSizePriorityMode(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetMatchingMode(MatchingModeSizePriority)
+
+	small := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 150.0)
+	large := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 200, 150.0)
+	me.SubmitOrder(small)
+	me.SubmitOrder(large)
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 100, 0))
+
+	if large.FilledQuantity != 100 || small.FilledQuantity != 0 {
+		t.Errorf("Expected the larger order to fill first, got small %f and large %f", small.FilledQuantity, large.FilledQuantity)
+	}
+}
+
+func TestSetMatchingModeRejectsUnknown(t *testing.T) {
+	me := NewMatchingEngine()
+
+	if err := me.SetMatchingMode("random"); err == nil {
+		t.Error("Expected unknown matching mode to be rejected")
+	}
+
+	if me.GetMatchingMode() != MatchingModeFIFO {
+		t.Errorf("Expected mode to stay FIFO, got %s", me.GetMatchingMode())
+	}
+}
@@ -0,0 +1,110 @@
+package matching
+
+import (
+	"sync"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// asyncQueueSize is how many orders may wait for async matching before
+// SubmitOrder blocks
+const asyncQueueSize = 1024
+
+// asyncQueue is the queue of orders awaiting the async matcher
+type asyncQueue struct {
+	orders  chan *models.Order
+	done    chan struct{}  // Closed once the matcher has drained orders
+	senders sync.WaitGroup // Submissions still handing an order over
+}
+
+// SetAsyncMatching switches between synchronous matching, the default, and
+// asynchronous matching. In async mode SubmitOrder acknowledges the order
+// with EventOrderAccepted and returns at once; orders are then matched in
+// arrival order and fills arrive as EventTrade events. The submitted order
+// is owned by the engine until its events arrive. Switching back to
+// synchronous mode waits for queued orders to finish matching.
+func (me *MatchingEngine) SetAsyncMatching(enabled bool) {
+	me.queueMutex.Lock()
+	q := me.queue
+	if enabled == (q != nil) {
+		me.queueMutex.Unlock()
+		return
+	}
+
+	if enabled {
+		q = &asyncQueue{
+			orders: make(chan *models.Order, asyncQueueSize),
+			done:   make(chan struct{}),
+		}
+		me.queue = q
+		me.queueMutex.Unlock()
+		go me.matchQueued(q.orders, q.done)
+		return
+	}
+
+	// New submissions match synchronously from here on; the queue closes
+	// once those already handing an order over have done so
+	me.queue = nil
+	me.queueMutex.Unlock()
+
+	q.senders.Wait()
+	close(q.orders)
+	<-q.done
+}
+
+// enqueue hands an order to the async matcher, returning false if the
+// engine is matching synchronously. The lock is only held to find the
+// queue, so event handlers may submit orders and a full queue holds up only
+// the submission waiting on it.
+func (me *MatchingEngine) enqueue(order *models.Order) bool {
+	me.queueMutex.Lock()
+	q := me.queue
+	if q == nil {
+		me.queueMutex.Unlock()
+		return false
+	}
+	q.senders.Add(1)
+	me.queueMutex.Unlock()
+	defer q.senders.Done()
+
+	order.Symbol = me.NormalizeSymbol(order.Symbol)
+	me.emit(Event{Type: EventOrderAccepted, Symbol: order.Symbol, OrderID: order.ID})
+	q.orders <- order
+	return true
+}
+
+// drainQueue takes the orders the async matcher has not yet started on off
+// the queue, returning them unmatched
+func (me *MatchingEngine) drainQueue() []*models.Order {
+	me.queueMutex.Lock()
+	q := me.queue
+	me.queueMutex.Unlock()
+
+	drained := make([]*models.Order, 0)
+	if q == nil {
+		return drained
+	}
+	for {
+		select {
+		case order, ok := <-q.orders:
+			if !ok {
+				return drained
+			}
+			drained = append(drained, order)
+		default:
+			return drained
+		}
+	}
+}
+
+// matchQueued matches queued orders one at a time until the queue closes
+func (me *MatchingEngine) matchQueued(queue <-chan *models.Order, done chan<- struct{}) {
+	defer close(done)
+
+	for order := range queue {
+		me.submitOrder(order, nil)
+		if order.Status == models.OrderStatusRejected {
+			me.emit(Event{Type: EventOrderRejected, Symbol: order.Symbol, OrderID: order.ID})
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestAsyncMatchingAcksBeforeFills(t *testing.T) {
+	me := NewMatchingEngine()
+
+	events := make(chan Event, 16)
+	me.OnEvent(func(e Event) {
+		events <- e
+	})
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+	me.SetAsyncMatching(true)
+
+	buyOrder := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	if trades := me.SubmitOrder(buyOrder); trades != nil {
+		t.Errorf("Expected no trades from an async submit, got %d", len(trades))
+	}
+
+	// Top of book updates interleave with order events, so skip them
+	next := func() Event {
+		for {
+			select {
+			case e := <-events:
+				if e.Type == EventBBOChanged {
+					continue
+				}
+				return e
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for an event")
+				return Event{}
+			}
+		}
+	}
+
+	ack := next()
+	if ack.Type != EventOrderAccepted || ack.OrderID != buyOrder.ID {
+		t.Fatalf("Expected the ack first, got %s for %s", ack.Type, ack.OrderID)
+	}
+
+	fill := next()
+	if fill.Type != EventTrade || fill.OrderID != buyOrder.ID {
+		t.Fatalf("Expected a trade after the ack, got %s", fill.Type)
+	}
+	if fill.Trade.Quantity != 100 || fill.Trade.Price != 150.0 {
+		t.Errorf("Expected 100 @ 150, got %v @ %v", fill.Trade.Quantity, fill.Trade.Price)
+	}
+
+	// Switching back waits for the queue, after which the order is settled
+	me.SetAsyncMatching(false)
+	if buyOrder.Status != models.OrderStatusFilled {
+		t.Errorf("Expected order to be filled, got %s", buyOrder.Status)
+	}
+
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0))
+	if trades == nil {
+		t.Error("Expected synchronous submit to return trades again")
+	}
+}
+
+func TestAsyncMatchingReportsRejections(t *testing.T) {
+	me := NewMatchingEngine()
+
+	events := make(chan Event, 16)
+	me.OnEvent(func(e Event) {
+		events <- e
+	})
+
+	me.SetAsyncMatching(true)
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1e18, 150.0)
+	me.SubmitOrder(order)
+	me.SetAsyncMatching(false)
+
+	if e := <-events; e.Type != EventOrderAccepted {
+		t.Errorf("Expected ack, got %s", e.Type)
+	}
+	if e := <-events; e.Type != EventOrderRejected || e.OrderID != order.ID {
+		t.Errorf("Expected rejection event, got %s", e.Type)
+	}
+}
+
+func TestAsyncHandlersMaySubmitOrders(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetAsyncMatching(true)
+
+	// Submitting from a handler used to deadlock on the queue lock
+	first := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 150.0)
+	second := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0)
+	me.OnEvent(func(e Event) {
+		if e.Type == EventOrderAccepted && e.OrderID == first.ID {
+			me.SubmitOrder(second)
+		}
+	})
+
+	submitted := make(chan struct{})
+	go func() {
+		me.SubmitOrder(first)
+		close(submitted)
+	}()
+	select {
+	case <-submitted:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out submitting from an event handler")
+	}
+
+	me.SetAsyncMatching(false)
+	if first.Status != models.OrderStatusFilled || second.Status != models.OrderStatusFilled {
+		t.Errorf("Expected both orders filled, got %s and %s", first.Status, second.Status)
+	}
+}
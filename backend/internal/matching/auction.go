@@ -0,0 +1,317 @@
+package matching
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// VolatilityAuctionConfig configures the short auctions that replace a halt
+// when continuous trading breaches a price band or the book becomes too
+// one-sided
+type VolatilityAuctionConfig struct {
+	Duration     time.Duration // How long orders are collected before uncrossing, 0 to disable
+	MaxImbalance float64       // Resting imbalance that triggers an auction, e.g. 0.8, 0 to ignore
+}
+
+// volatilityAuction is a running auction on one symbol
+type volatilityAuction struct {
+	reference float64 // Price the clearing band is measured from
+	endsAt    time.Time
+}
+
+// SetVolatilityAuctions enables volatility auctions. While enabled, a would-be
+// trade outside the price band moves the symbol into an auction instead of
+// halting it.
+func (me *MatchingEngine) SetVolatilityAuctions(config VolatilityAuctionConfig) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.auctionConfig = config
+}
+
+// InVolatilityAuction returns true if a symbol is collecting orders for a
+// volatility auction
+func (me *MatchingEngine) InVolatilityAuction(symbol string) bool {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	_, exists := me.auctions[symbol]
+	return exists
+}
+
+// RunDueAuctions uncrosses every volatility auction whose collection period
+// has ended and resumes continuous trading, returning the number run. Like
+// ExpireStaleOrders it is meant to be run periodically; an auction is also
+// run when the next order for its symbol arrives after it is due.
+func (me *MatchingEngine) RunDueAuctions() int {
+	me.mutex.RLock()
+	now := me.clock.Now()
+	due := make([]string, 0)
+	for symbol, auction := range me.auctions {
+		if !now.Before(auction.endsAt) {
+			due = append(due, symbol)
+		}
+	}
+	me.mutex.RUnlock()
+
+	for _, symbol := range due {
+		me.UncrossAuction(symbol)
+	}
+	return len(due)
+}
+
+// UncrossAuction ends a symbol's volatility auction now, executing crossing
+// orders at a single clearing price inside the band and resuming continuous
+// trading. It returns nil if the symbol is not in an auction.
+func (me *MatchingEngine) UncrossAuction(symbol string) []*models.Trade {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	auction, exists := me.auctions[symbol]
+	if exists {
+		delete(me.auctions, symbol)
+		me.sessions[symbol] = SessionContinuous
+	}
+	me.mutex.Unlock()
+
+	ob := me.GetOrderBook(symbol)
+	if !exists || ob == nil {
+		return nil
+	}
+
+	trades := me.uncross(ob, auction.reference)
+
+	me.mutex.Lock()
+	if len(trades) > 0 {
+		me.trades.add(trades...)
+		me.printTrades(trades)
+	}
+	me.mutex.Unlock()
+
+	me.bookChanged(symbol)
+	for _, trade := range trades {
+		me.emit(Event{Type: EventTrade, Symbol: symbol, Trade: trade})
+	}
+	me.establishReference(symbol, trades)
+	me.emit(Event{Type: EventAuctionEnded, Symbol: symbol})
+	me.checkBBO(symbol, BBOCauseTrade)
+	if len(trades) > 0 {
+		me.fireConditionals(symbol, ob.LastPrice)
+		me.fireStops(symbol, ob.LastPrice)
+	}
+	return trades
+}
+
+// runDueAuction uncrosses a symbol's auction if its collection period has
+// ended
+func (me *MatchingEngine) runDueAuction(symbol string) {
+	me.mutex.RLock()
+	auction, exists := me.auctions[symbol]
+	due := exists && !me.clock.Now().Before(auction.endsAt)
+	me.mutex.RUnlock()
+
+	if due {
+		me.UncrossAuction(symbol)
+	}
+}
+
+// startAuction moves a symbol into a volatility auction around reference,
+// returning false if volatility auctions are disabled
+func (me *MatchingEngine) startAuction(symbol string, reference float64) bool {
+	me.mutex.Lock()
+	if me.auctionConfig.Duration <= 0 {
+		me.mutex.Unlock()
+		return false
+	}
+	if _, exists := me.auctions[symbol]; exists {
+		me.mutex.Unlock()
+		return true
+	}
+	me.auctions[symbol] = &volatilityAuction{
+		reference: reference,
+		endsAt:    me.clock.Now().Add(me.auctionConfig.Duration),
+	}
+	me.sessions[symbol] = SessionAuction
+	me.mutex.Unlock()
+
+	me.emit(Event{Type: EventAuctionStarted, Symbol: symbol})
+	return true
+}
+
+// checkImbalance starts an auction if resting quantity is too heavily on one
+// side of the book
+func (me *MatchingEngine) checkImbalance(ob *orderbook.OrderBook) {
+	me.mutex.RLock()
+	maxImbalance := me.auctionConfig.MaxImbalance
+	me.mutex.RUnlock()
+
+	if maxImbalance <= 0 {
+		return
+	}
+
+	totals := ob.TotalResting()
+	if totals.BidQuantity <= 0 || totals.AskQuantity <= 0 {
+		return // A one-sided book has no imbalance to measure
+	}
+	imbalance := math.Abs(totals.BidQuantity-totals.AskQuantity) / (totals.BidQuantity + totals.AskQuantity)
+	if imbalance > maxImbalance {
+		me.startAuction(ob.Symbol, me.referencePrice(ob))
+	}
+}
+
+// collectAuctionOrder rests an order in an auction book without matching it.
+// Market orders have no price to collect at, and immediate-or-cancel orders
+// can't wait for the uncross, so both are cancelled.
+func (me *MatchingEngine) collectAuctionOrder(ob *orderbook.OrderBook, order *models.Order, trace *MatchTrace) {
+	if order.Type == models.OrderTypeMarket {
+		order.CancelRemainder(me.clock.Now(), "market orders cannot join a volatility auction")
+		return
+	}
+	if order.TimeInForce == models.TimeInForceIOC {
+		order.CancelRemainder(me.clock.Now(), "immediate-or-cancel orders cannot join a volatility auction")
+		return
+	}
+
+	order.Type = models.OrderTypeLimit
+	ob.AddOrder(order)
+	trace.record(TraceStep{Action: TraceRest, Price: order.Price, Quantity: order.RemainingQuantity(me.tolerance.Quantity)})
+}
+
+// uncross executes every crossing order in the book at the clearing price:
+// the price inside the band around reference that executes the most
+// quantity, then leaves the least imbalance, then is nearest the reference
+func (me *MatchingEngine) uncross(ob *orderbook.OrderBook, reference float64) []*models.Trade {
+	low, high := 0.0, math.Inf(1)
+	me.mutex.RLock()
+	band, banded := me.circuitBreaker.BandFor(reference)
+	me.mutex.RUnlock()
+	if banded && reference > 0 {
+		low, high = reference*(1-band), reference*(1+band)
+		// Clear at a band edge only on a tick inside the band
+		if tick := me.GetSymbolConfig(ob.Symbol).TickSize; tick > 0 {
+			low, high = snapToTick(low, tick, true), snapToTick(high, tick, false)
+		}
+	}
+
+	bids := sortedLevels(ob.Bids, func(a, b float64) bool { return a > b })
+	asks := sortedLevels(ob.Asks, func(a, b float64) bool { return a < b })
+
+	candidates := make([]float64, 0, len(bids)+len(asks)+2)
+	for _, level := range append(bids, asks...) {
+		candidates = append(candidates, level.Price)
+	}
+	if banded {
+		candidates = append(candidates, low, high)
+	}
+
+	price, volume := 0.0, 0.0
+	bestImbalance := 0.0
+	for _, candidate := range candidates {
+		if candidate < low-me.tolerance.Price || candidate > high+me.tolerance.Price {
+			continue
+		}
+		demand := auctionQuantity(bids, func(p float64) bool { return p >= candidate-me.tolerance.Price }, me.tolerance.Quantity)
+		supply := auctionQuantity(asks, func(p float64) bool { return p <= candidate+me.tolerance.Price }, me.tolerance.Quantity)
+		executable := min(demand, supply)
+		imbalance := math.Abs(demand - supply)
+
+		better := executable > volume+me.tolerance.Quantity
+		if !better && executable > me.tolerance.Quantity && math.Abs(executable-volume) <= me.tolerance.Quantity {
+			better = imbalance < bestImbalance ||
+				(imbalance == bestImbalance && math.Abs(candidate-reference) < math.Abs(price-reference))
+		}
+		if better {
+			price, volume, bestImbalance = candidate, executable, imbalance
+		}
+	}
+	if volume <= me.tolerance.Quantity {
+		return nil
+	}
+
+	buyers := auctionOrders(bids, func(p float64) bool { return p >= price-me.tolerance.Price }, me.tolerance.Quantity)
+	sellers := auctionOrders(asks, func(p float64) bool { return p <= price+me.tolerance.Price }, me.tolerance.Quantity)
+	oddLots := me.GetSymbolConfig(ob.Symbol).OddLots
+
+	trades := make([]*models.Trade, 0)
+	for i, j := 0, 0; i < len(buyers) && j < len(sellers) && volume > me.tolerance.Quantity; {
+		buy, sell := buyers[i], sellers[j]
+		quantity := min(volume, min(buy.RemainingQuantity(me.tolerance.Quantity), sell.RemainingQuantity(me.tolerance.Quantity)))
+
+		// The later of the two orders is treated as the taker
+		incoming, resting := buy, sell
+		if sell.SubmittedAt.After(buy.SubmittedAt) {
+			incoming, resting = sell, buy
+		}
+		trade := me.newTrade(incoming, resting, price, quantity)
+		buy.Fill(quantity, price, trade.Timestamp, me.tolerance.Quantity)
+		sell.Fill(quantity, price, trade.Timestamp, me.tolerance.Quantity)
+		for _, order := range []*models.Order{buy, sell} {
+			if order.Status == models.OrderStatusFilled {
+				me.recordLatency(order)
+			}
+		}
+		if !trade.OddLot || !oddLots.SuppressLastPrice {
+			ob.LastPrice = price
+			ob.LastTrade = trade
+		}
+		me.recordFill(trade)
+		trade.RecordFills(buy, sell, me.tolerance.Quantity)
+		trades = append(trades, trade)
+
+		volume -= quantity
+		if buy.RemainingQuantity(me.tolerance.Quantity) <= 0 {
+			i++
+		}
+		if sell.RemainingQuantity(me.tolerance.Quantity) <= 0 {
+			j++
+		}
+	}
+
+	// Consumed levels are all at the top of each side, so popping the
+	// emptied ones leaves both heaps valid
+	me.nextLevel(ob, ob.Bids, nil)
+	me.nextLevel(ob, ob.Asks, nil)
+	ob.MarkChanged(orderbook.ChangeTrade)
+	return trades
+}
+
+// sortedLevels returns a side's levels ordered best first
+func sortedLevels(h *orderbook.PriceLevelHeap, better func(a, b float64) bool) []*orderbook.PriceLevel {
+	levels := make([]*orderbook.PriceLevel, len(h.Levels))
+	copy(levels, h.Levels)
+	sort.Slice(levels, func(i, j int) bool { return better(levels[i].Price, levels[j].Price) })
+	return levels
+}
+
+// auctionQuantity sums the live quantity on levels whose price is eligible,
+// ignoring residue within epsilon
+func auctionQuantity(levels []*orderbook.PriceLevel, eligible func(float64) bool, epsilon float64) float64 {
+	total := 0.0
+	for _, order := range auctionOrders(levels, eligible, epsilon) {
+		total += order.RemainingQuantity(epsilon)
+	}
+	return total
+}
+
+// auctionOrders returns the live orders on eligible levels in price-time
+// priority, leaving out those with only residue within epsilon left
+func auctionOrders(levels []*orderbook.PriceLevel, eligible func(float64) bool, epsilon float64) []*models.Order {
+	orders := make([]*models.Order, 0)
+	for _, level := range levels {
+		if !eligible(level.Price) {
+			continue
+		}
+		for _, order := range level.Orders {
+			if order.IsActive() && order.RemainingQuantity(epsilon) > 0 {
+				orders = append(orders, order)
+			}
+		}
+	}
+	return orders
+}
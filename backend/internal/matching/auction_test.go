@@ -0,0 +1,111 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestBandBreachTriggersVolatilityAuction(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetCircuitBreaker(CircuitBreakerConfig{Tiers: []PriceBandTier{{BandPercent: 0.05}}})
+	me.SetVolatilityAuctions(VolatilityAuctionConfig{Duration: 30 * time.Second})
+	me.SetReferencePrice("AAPL", 100.0)
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 110.0))
+
+	aggressive := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 20, 112.0)
+	trades := me.SubmitOrder(aggressive)
+	if len(trades) != 1 || trades[0].Price != 100.0 {
+		t.Fatalf("Expected only the in-band level to print, got %d trades", len(trades))
+	}
+	if me.IsHalted("AAPL") {
+		t.Error("Expected an auction instead of a halt")
+	}
+	if !me.InVolatilityAuction("AAPL") || me.GetSessionPhase("AAPL") != SessionAuction {
+		t.Fatalf("Expected AAPL in a volatility auction, got phase %s", me.GetSessionPhase("AAPL"))
+	}
+	if !aggressive.IsActive() || aggressive.RemainingQuantity(models.DefaultQuantityEpsilon) != 10 {
+		t.Errorf("Expected the remainder of 10 to rest for the auction, got %g (%s)", aggressive.RemainingQuantity(models.DefaultQuantityEpsilon), aggressive.Status)
+	}
+
+	// A crossing seller joins the auction without printing
+	seller := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 104.0)
+	if trades := me.SubmitOrder(seller); len(trades) != 0 {
+		t.Errorf("Expected no trades while the auction collects orders, got %d", len(trades))
+	}
+
+	if n := me.RunDueAuctions(); n != 0 {
+		t.Errorf("Expected no auction due before the duration, got %d", n)
+	}
+
+	mock.Advance(30 * time.Second)
+	if n := me.RunDueAuctions(); n != 1 {
+		t.Fatalf("Expected one auction to run, got %d", n)
+	}
+
+	if me.InVolatilityAuction("AAPL") || me.GetSessionPhase("AAPL") != SessionContinuous {
+		t.Error("Expected continuous trading to resume after the auction")
+	}
+
+	recent := me.GetRecentTrades("AAPL", 10)
+	if len(recent) != 2 {
+		t.Fatalf("Expected 2 trades in total, got %d", len(recent))
+	}
+	auctionTrade := recent[0]
+	if auctionTrade.Price != 104.0 || auctionTrade.Quantity != 10 {
+		t.Errorf("Expected the auction to clear 10 at 104, got %g at %g", auctionTrade.Quantity, auctionTrade.Price)
+	}
+	if auctionTrade.Price > 105.0 {
+		t.Errorf("Expected the clearing price inside the band, got %g", auctionTrade.Price)
+	}
+	if aggressive.Status != models.OrderStatusFilled || seller.Status != models.OrderStatusFilled {
+		t.Errorf("Expected both auction orders filled, got %s and %s", aggressive.Status, seller.Status)
+	}
+
+	ob := me.GetOrderBook("AAPL")
+	if ob.GetBestAsk() != 110.0 || ob.GetBestBid() != 0 {
+		t.Errorf("Expected only the 110 ask left, got bid %g ask %g", ob.GetBestBid(), ob.GetBestAsk())
+	}
+}
+
+func TestBandBreachHaltsWithoutAuctions(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetCircuitBreaker(CircuitBreakerConfig{Tiers: []PriceBandTier{{BandPercent: 0.05}}})
+	me.SetReferencePrice("AAPL", 100.0)
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 110.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 112.0))
+
+	if !me.IsHalted("AAPL") || me.InVolatilityAuction("AAPL") {
+		t.Error("Expected a halt when volatility auctions are disabled")
+	}
+}
+
+func TestImbalanceTriggersVolatilityAuction(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetVolatilityAuctions(VolatilityAuctionConfig{Duration: time.Minute, MaxImbalance: 0.8})
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 20, 99.0))
+	if me.InVolatilityAuction("AAPL") {
+		t.Fatal("Expected no auction for a modest imbalance")
+	}
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 200, 98.0))
+	if !me.InVolatilityAuction("AAPL") {
+		t.Error("Expected a severely imbalanced book to start an auction")
+	}
+
+	if trades := me.UncrossAuction("AAPL"); len(trades) != 0 {
+		t.Errorf("Expected an uncrossed book to clear nothing, got %d trades", len(trades))
+	}
+	if me.InVolatilityAuction("AAPL") {
+		t.Error("Expected UncrossAuction to end the auction")
+	}
+}
@@ -0,0 +1,43 @@
+package matching
+
+import "github.com/acagliol/arbitrax/backend/internal/orderbook"
+
+// BBOCause is what moved the top of book
+type BBOCause string
+
+const (
+	BBOCauseNewOrder BBOCause = "new_order"
+	BBOCauseCancel   BBOCause = "cancel"
+	BBOCauseTrade    BBOCause = "trade"
+	BBOCauseAdjust   BBOCause = "adjustment"
+	BBOCauseImport   BBOCause = "import"
+	BBOCauseReplace  BBOCause = "replace"
+)
+
+// BBOChange describes a move in the best displayed bid or offer
+type BBOChange struct {
+	Cause BBOCause      `json:"cause"`
+	Old   orderbook.BBO `json:"old"`
+	New   orderbook.BBO `json:"new"`
+}
+
+// checkBBO emits EventBBOChanged if a symbol's top of book has moved since
+// it was last seen. It must be called without holding the mutex.
+func (me *MatchingEngine) checkBBO(symbol string, cause BBOCause) {
+	me.mutex.Lock()
+	ob := me.orderBooks[symbol]
+	if ob == nil {
+		me.mutex.Unlock()
+		return
+	}
+	current := ob.BBO()
+	previous := me.bbos[symbol]
+	me.bbos[symbol] = current
+	me.trackMakers(ob, current)
+	me.mutex.Unlock()
+
+	if current == previous {
+		return
+	}
+	me.emit(Event{Type: EventBBOChanged, Symbol: symbol, BBO: &BBOChange{Cause: cause, Old: previous, New: current}})
+}
@@ -0,0 +1,64 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestCancelBestBidEmitsBBOChange(t *testing.T) {
+	me := NewMatchingEngine()
+
+	best := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	me.SubmitOrder(best)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 50, 149.0))
+
+	changes := make([]*BBOChange, 0)
+	me.OnEvent(func(event Event) {
+		if event.Type == EventBBOChanged {
+			changes = append(changes, event.BBO)
+		}
+	})
+
+	me.CancelOrder("AAPL", best.ID)
+
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 BBO change, got %d", len(changes))
+	}
+	change := changes[0]
+	if change.Cause != BBOCauseCancel {
+		t.Errorf("Expected cause cancel, got %s", change.Cause)
+	}
+	if change.Old.BidPrice != 150.0 || change.Old.BidQuantity != 100 {
+		t.Errorf("Expected old bid 100 @ 150, got %f @ %f", change.Old.BidQuantity, change.Old.BidPrice)
+	}
+	if change.New.BidPrice != 149.0 || change.New.BidQuantity != 50 {
+		t.Errorf("Expected new bid 50 @ 149, got %f @ %f", change.New.BidQuantity, change.New.BidPrice)
+	}
+}
+
+func TestBBOChangeCauses(t *testing.T) {
+	me := NewMatchingEngine()
+
+	causes := make([]BBOCause, 0)
+	me.OnEvent(func(event Event) {
+		if event.Type == EventBBOChanged {
+			causes = append(causes, event.BBO.Cause)
+		}
+	})
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+	// Behind the touch, so the top of book doesn't move
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 151.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 40, 150.0))
+
+	expected := []BBOCause{BBOCauseNewOrder, BBOCauseTrade}
+	if len(causes) != len(expected) {
+		t.Fatalf("Expected causes %v, got %v", expected, causes)
+	}
+	for i := range expected {
+		if causes[i] != expected[i] {
+			t.Errorf("Expected cause %d to be %s, got %s", i, expected[i], causes[i])
+		}
+	}
+}
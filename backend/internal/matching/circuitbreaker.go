@@ -0,0 +1,227 @@
+package matching
+
+import (
+	"math"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// PriceBandTier sets the allowed price move for reference prices in
+// [MinPrice, MaxPrice)
+type PriceBandTier struct {
+	MinPrice    float64 // Inclusive lower bound of the reference price
+	MaxPrice    float64 // Exclusive upper bound, 0 for no upper bound
+	BandPercent float64 // Allowed move from the reference, e.g. 0.05 for 5%
+}
+
+// CircuitBreakerConfig configures trading halts on large price moves
+type CircuitBreakerConfig struct {
+	Tiers []PriceBandTier
+}
+
+// BandFor returns the allowed percentage move for a reference price
+func (c CircuitBreakerConfig) BandFor(reference float64) (float64, bool) {
+	for _, tier := range c.Tiers {
+		if reference >= tier.MinPrice && (tier.MaxPrice == 0 || reference < tier.MaxPrice) {
+			return tier.BandPercent, true
+		}
+	}
+	return 0, false
+}
+
+// Breaches reports whether a trade at price would move beyond the band
+// around the reference price
+func (c CircuitBreakerConfig) Breaches(reference, price float64) bool {
+	if reference <= 0 {
+		return false // No anchor to measure the move against
+	}
+
+	band, exists := c.BandFor(reference)
+	if !exists {
+		return false
+	}
+	return math.Abs(price-reference)/reference > band
+}
+
+// SetCircuitBreaker sets the price bands that halt trading when breached
+func (me *MatchingEngine) SetCircuitBreaker(config CircuitBreakerConfig) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.circuitBreaker = config
+}
+
+// IsHalted returns true if trading in a symbol is halted
+func (me *MatchingEngine) IsHalted(symbol string) bool {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	return me.halted[symbol]
+}
+
+// SetResumeRevalidation controls whether resuming a symbol cancels resting
+// orders that fall outside the price band around the last trade price
+func (me *MatchingEngine) SetResumeRevalidation(enabled bool) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.resumeCheck = enabled
+}
+
+// ResumeTrading lifts a halt on a symbol, first cancelling resting orders
+// that are now out of band if resume revalidation is enabled
+func (me *MatchingEngine) ResumeTrading(symbol string) {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	revalidate := me.resumeCheck
+	me.mutex.RUnlock()
+
+	if ob := me.GetOrderBook(symbol); ob != nil && revalidate {
+		me.cancelOutOfBand(ob)
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	delete(me.halted, symbol)
+}
+
+// cancelOutOfBand cancels every resting order priced outside the band
+// around the book's last trade price
+func (me *MatchingEngine) cancelOutOfBand(ob *orderbook.OrderBook) {
+	reference := ob.LastPrice
+
+	for _, orderID := range ob.OrderIDs() {
+		if order, exists := ob.GetOrder(orderID); exists && me.breachesBand(reference, order.Price) {
+			me.CancelOrder(ob.Symbol, orderID)
+		}
+	}
+}
+
+// haltSymbol halts trading in a symbol
+func (me *MatchingEngine) haltSymbol(symbol string) {
+	me.mutex.Lock()
+	me.halted[symbol] = true
+	me.mutex.Unlock()
+
+	me.emit(Event{Type: EventTradingHalted, Symbol: symbol})
+}
+
+// KillSwitch halts every symbol and cancels every resting order across all
+// books, returning the number of orders cancelled. Orders held outside the
+// books are cancelled too: dormant stops, conditional orders, orders parked
+// by the trade cap, waiting on the match rate or queued by a maintenance
+// pause, and orders not yet taken by the async matcher. Trading can be
+// restarted per symbol with ResumeTrading.
+func (me *MatchingEngine) KillSwitch() int {
+	me.mutex.RLock()
+	books := make([]*orderbook.OrderBook, 0, len(me.orderBooks))
+	for _, ob := range me.orderBooks {
+		books = append(books, ob)
+	}
+	me.mutex.RUnlock()
+
+	// Halt everything first so nothing new rests while books are cleared
+	for _, ob := range books {
+		me.haltSymbol(ob.Symbol)
+	}
+
+	cancelled := 0
+	for _, order := range me.drainQueue() {
+		if !order.IsActive() {
+			continue
+		}
+		order.Cancel(me.clock.Now())
+		me.recordLatency(order)
+		me.emit(Event{Type: EventOrderCancelled, Symbol: order.Symbol, OrderID: order.ID})
+		cancelled++
+	}
+	for _, order := range me.heldOrders() {
+		if me.CancelOrder(order.Symbol, order.ID) {
+			cancelled++
+		}
+	}
+	for _, ob := range books {
+		for _, orderID := range ob.OrderIDs() {
+			if me.CancelOrder(ob.Symbol, orderID) {
+				cancelled++
+			}
+		}
+	}
+
+	me.emit(Event{Type: EventKillSwitch})
+	return cancelled
+}
+
+// heldOrders returns the orders the engine holds outside the books: dormant
+// stops, conditional orders, and orders parked by the trade cap, waiting on
+// the match rate or queued by a maintenance pause
+func (me *MatchingEngine) heldOrders() []*models.Order {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	held := make([]*models.Order, 0)
+	for _, stops := range me.stopOrders {
+		held = append(held, stops...)
+	}
+	for _, pending := range me.conditionals {
+		for _, co := range pending {
+			held = append(held, co.Order)
+		}
+	}
+	for _, parked := range me.parked {
+		held = append(held, parked...)
+	}
+	if me.throttle != nil {
+		held = append(held, me.throttle.pending...)
+	}
+	for _, pause := range me.paused {
+		held = append(held, pause.queued...)
+	}
+	return held
+}
+
+// SetReferencePrice seeds a symbol's reference price, such as the previous
+// close, so price bands apply before the first trade sets a last price
+func (me *MatchingEngine) SetReferencePrice(symbol string, price float64) {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.references[symbol] = price
+	delete(me.established, symbol)
+}
+
+// referencePrice returns the price bands are measured from: the last trade
+// once the first trade has established a reference, or the seeded reference
+// until the book has traded, then the mid
+func (me *MatchingEngine) referencePrice(ob *orderbook.OrderBook) float64 {
+	me.mutex.RLock()
+	seeded := me.references[ob.Symbol]
+	established := me.established[ob.Symbol]
+	me.mutex.RUnlock()
+
+	if established {
+		if ob.LastPrice > 0 {
+			return ob.LastPrice
+		}
+		return seeded
+	}
+	if seeded > 0 && ob.LastPrice == 0 {
+		return seeded
+	}
+	return ob.GetMidPrice()
+}
+
+// breachesBand checks a would-be trade price against the circuit breaker
+func (me *MatchingEngine) breachesBand(reference, price float64) bool {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	return me.circuitBreaker.Breaches(reference, price)
+}
@@ -0,0 +1,285 @@
+package matching
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestTieredPriceBands(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetCircuitBreaker(CircuitBreakerConfig{
+		Tiers: []PriceBandTier{
+			{MinPrice: 0, MaxPrice: 50, BandPercent: 0.05},
+			{MinPrice: 50, BandPercent: 0.10},
+		},
+	})
+
+	// Both books are centred on their reference with an ask 8% away
+	me.SubmitOrder(models.NewOrder("LOW", models.OrderTypeLimit, models.OrderSideBuy, 10, 19.9))
+	me.SubmitOrder(models.NewOrder("LOW", models.OrderTypeLimit, models.OrderSideSell, 10, 20.1))
+	me.SubmitOrder(models.NewOrder("LOW", models.OrderTypeLimit, models.OrderSideSell, 10, 21.6))
+
+	me.SubmitOrder(models.NewOrder("HIGH", models.OrderTypeLimit, models.OrderSideBuy, 10, 199.0))
+	me.SubmitOrder(models.NewOrder("HIGH", models.OrderTypeLimit, models.OrderSideSell, 10, 201.0))
+	me.SubmitOrder(models.NewOrder("HIGH", models.OrderTypeLimit, models.OrderSideSell, 10, 216.0))
+
+	lowTrades := me.SubmitOrder(models.NewOrder("LOW", models.OrderTypeMarket, models.OrderSideBuy, 20, 0))
+	if len(lowTrades) != 1 {
+		t.Errorf("Expected the LOW sweep to stop after 1 trade, got %d", len(lowTrades))
+	}
+	if !me.IsHalted("LOW") {
+		t.Error("Expected an 8% move to halt LOW under the 5% tier")
+	}
+
+	highTrades := me.SubmitOrder(models.NewOrder("HIGH", models.OrderTypeMarket, models.OrderSideBuy, 20, 0))
+	if len(highTrades) != 2 {
+		t.Errorf("Expected the HIGH sweep to fill 2 trades, got %d", len(highTrades))
+	}
+	if me.IsHalted("HIGH") {
+		t.Error("Expected an 8% move not to halt HIGH under the 10% tier")
+	}
+}
+
+func TestHaltedSymbolRejectsOrders(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetCircuitBreaker(CircuitBreakerConfig{
+		Tiers: []PriceBandTier{{MinPrice: 0, BandPercent: 0.05}},
+	})
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 120.0))
+
+	// The limit buy's remainder is cancelled rather than left crossing the book
+	buy := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 20, 125.0)
+	me.SubmitOrder(buy)
+	if buy.Status != models.OrderStatusCancelled {
+		t.Errorf("Expected halted remainder to be cancelled, got %s", buy.Status)
+	}
+
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0)
+	me.SubmitOrder(order)
+	if order.Status != models.OrderStatusRejected {
+		t.Errorf("Expected order to be rejected while halted, got %s", order.Status)
+	}
+
+	me.ResumeTrading("AAPL")
+	order = models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0)
+	me.SubmitOrder(order)
+	if order.Status != models.OrderStatusPending {
+		t.Errorf("Expected order to be accepted after resume, got %s", order.Status)
+	}
+}
+
+func TestKillSwitch(t *testing.T) {
+	me := NewMatchingEngine()
+
+	events := make(map[EventType]int)
+	me.OnEvent(func(e Event) {
+		events[e.Type]++
+	})
+
+	for _, symbol := range []string{"AAPL", "MSFT", "GOOG"} {
+		me.SubmitOrder(models.NewOrder(symbol, models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+		me.SubmitOrder(models.NewOrder(symbol, models.OrderTypeLimit, models.OrderSideBuy, 10, 98.0))
+		me.SubmitOrder(models.NewOrder(symbol, models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	}
+
+	if cancelled := me.KillSwitch(); cancelled != 9 {
+		t.Errorf("Expected 9 orders cancelled, got %d", cancelled)
+	}
+
+	for _, symbol := range []string{"AAPL", "MSFT", "GOOG"} {
+		ob := me.GetOrderBook(symbol)
+		if ob.Bids.Len() != 0 || ob.Asks.Len() != 0 || ob.OrderCount() != 0 {
+			t.Errorf("Expected %s to be empty after the kill switch", symbol)
+		}
+		if !me.IsHalted(symbol) {
+			t.Errorf("Expected %s to be halted", symbol)
+		}
+	}
+
+	if events[EventOrderCancelled] != 9 || events[EventTradingHalted] != 3 || events[EventKillSwitch] != 1 {
+		t.Errorf("Unexpected events: %v", events)
+	}
+
+	if errs := me.ValidateState(); len(errs) != 0 {
+		t.Errorf("Expected a consistent state after the kill switch, got %v", errs)
+	}
+
+	// Trading restarts cleanly once resumed
+	me.ResumeTrading("AAPL")
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0)
+	me.SubmitOrder(order)
+	if order.Status != models.OrderStatusPending {
+		t.Errorf("Expected order to be accepted after resume, got %s", order.Status)
+	}
+}
+
+func TestKillSwitchCancelsHeldOrders(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetMaxTradesPerOrder(1)
+
+	cancelledEvents := 0
+	me.OnEvent(func(e Event) {
+		if e.Type == EventOrderCancelled {
+			cancelledEvents++
+		}
+	})
+
+	// A dormant sell stop, and a buy whose remainder the trade cap parks
+	stop := models.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideSell, 5, 90.0)
+	me.SubmitOrder(stop)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.5))
+	parked := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 30, 102.0)
+	me.SubmitOrder(parked)
+	if parked.FilledQuantity != 10 || len(me.GetStopOrders("AAPL")) != 1 {
+		t.Fatalf("Expected a parked buy and a dormant stop, got %g filled", parked.FilledQuantity)
+	}
+
+	// The resting ask, the stop and the parked buy
+	if cancelled := me.KillSwitch(); cancelled != 3 || cancelledEvents != 3 {
+		t.Errorf("Expected 3 orders cancelled with events, got %d and %d", cancelled, cancelledEvents)
+	}
+	for _, order := range []*models.Order{stop, parked} {
+		if order.Status != models.OrderStatusCancelled {
+			t.Errorf("Expected the held order cancelled, got %s", order.Status)
+		}
+	}
+
+	// Nothing held comes back to life on restart
+	me.ResumeTrading("AAPL")
+	if trades := me.ContinueParkedOrders(); len(trades) != 0 {
+		t.Errorf("Expected nothing parked to continue, got %d trades", len(trades))
+	}
+	printTrade(me, 89.0, 10)
+	if stop.FilledQuantity != 0 || parked.FilledQuantity != 10 {
+		t.Errorf("Expected neither order to trade again, got %g and %g filled", stop.FilledQuantity, parked.FilledQuantity)
+	}
+	if errs := me.ValidateState(); len(errs) != 0 {
+		t.Errorf("Expected a consistent state after the kill switch, got %v", errs)
+	}
+}
+
+func TestKillSwitchCancelsQueuedAsyncOrders(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetAsyncMatching(true)
+
+	// Hold the matcher inside the first order's BBO event so the next
+	// order waits in the queue
+	started, release := make(chan struct{}), make(chan struct{})
+	var held atomic.Bool
+	me.OnEvent(func(e Event) {
+		if e.Type == EventBBOChanged && held.CompareAndSwap(false, true) {
+			close(started)
+			<-release
+		}
+	})
+	first := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0)
+	me.SubmitOrder(first)
+	<-started
+
+	queued := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 98.0)
+	me.SubmitOrder(queued)
+
+	me.KillSwitch()
+	close(release)
+	me.SetAsyncMatching(false)
+
+	if queued.Status != models.OrderStatusCancelled {
+		t.Errorf("Expected the queued order cancelled, got %s", queued.Status)
+	}
+	if ob := me.GetOrderBook("AAPL"); ob != nil && ob.OrderCount() > 1 {
+		t.Errorf("Expected the queued order kept out of the book, got %d orders", ob.OrderCount())
+	}
+}
+
+func TestResumeRevalidationCancelsOutOfBandOrders(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetCircuitBreaker(CircuitBreakerConfig{
+		Tiers: []PriceBandTier{{MinPrice: 0, BandPercent: 0.05}},
+	})
+	me.SetResumeRevalidation(true)
+
+	inBand := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0)
+	outOfBand := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 120.0)
+	me.SubmitOrder(inBand)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	me.SubmitOrder(outOfBand)
+
+	// Trades at 101, then halts before reaching 120
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 20, 125.0))
+	if !me.IsHalted("AAPL") {
+		t.Fatal("Expected AAPL to be halted")
+	}
+
+	me.ResumeTrading("AAPL")
+
+	if me.IsHalted("AAPL") {
+		t.Error("Expected AAPL to resume")
+	}
+	if outOfBand.Status != models.OrderStatusCancelled {
+		t.Errorf("Expected out-of-band order to be cancelled on resume, got %s", outOfBand.Status)
+	}
+	if inBand.Status != models.OrderStatusPending {
+		t.Errorf("Expected in-band order to persist, got %s", inBand.Status)
+	}
+	if _, resting := me.GetOrderBook("AAPL").GetOrder(inBand.ID); !resting {
+		t.Error("Expected in-band order to still rest in the book")
+	}
+}
+
+func TestCollaredMarketOrderWarns(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetCircuitBreaker(CircuitBreakerConfig{
+		Tiers: []PriceBandTier{{MinPrice: 0, BandPercent: 0.05}},
+	})
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 120.0))
+
+	order := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 20, 0)
+	trades := me.SubmitOrder(order)
+
+	if len(trades) != 1 {
+		t.Fatalf("Expected 1 trade before the collar, got %d", len(trades))
+	}
+	if order.Status == models.OrderStatusRejected {
+		t.Errorf("Expected the order not to be rejected, got %s", order.RejectReason)
+	}
+	if len(order.Warnings) != 1 || !strings.Contains(order.Warnings[0], "collared") {
+		t.Errorf("Expected a collar warning, got %v", order.Warnings)
+	}
+}
+
+func TestSeededReferencePriceEnforcesBands(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetCircuitBreaker(CircuitBreakerConfig{
+		Tiers: []PriceBandTier{{MinPrice: 0, BandPercent: 0.05}},
+	})
+	me.SetReferencePrice("AAPL", 100.0)
+
+	// A one-sided fresh book has no mid or last price of its own
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 120.0))
+
+	order := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	trades := me.SubmitOrder(order)
+
+	if len(trades) != 0 {
+		t.Errorf("Expected no trades 20%% from the seeded reference, got %d", len(trades))
+	}
+	if !me.IsHalted("AAPL") {
+		t.Error("Expected the seeded reference to trigger the band")
+	}
+
+	// Without a seed the same book trades freely
+	me.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideSell, 10, 120.0))
+	if trades := me.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)); len(trades) != 1 {
+		t.Errorf("Expected an unseeded book to trade, got %d trades", len(trades))
+	}
+}
@@ -0,0 +1,291 @@
+package matching
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// TriggerCondition is the direction a reference price must move to fire a
+// conditional order
+type TriggerCondition string
+
+const (
+	// TriggerAtOrAbove fires once the reference last price reaches the
+	// trigger price or higher
+	TriggerAtOrAbove TriggerCondition = "at_or_above"
+	// TriggerAtOrBelow fires once the reference last price reaches the
+	// trigger price or lower
+	TriggerAtOrBelow TriggerCondition = "at_or_below"
+)
+
+// ConditionalOrder is an order held back until another symbol's last price
+// meets its trigger
+type ConditionalOrder struct {
+	Order           *models.Order    `json:"order"`
+	ReferenceSymbol string           `json:"reference_symbol"`
+	Condition       TriggerCondition `json:"condition"`
+	TriggerPrice    float64          `json:"trigger_price"`
+}
+
+// triggered reports whether a reference last price fires the order
+func (co *ConditionalOrder) triggered(lastPrice float64) bool {
+	if lastPrice <= 0 {
+		return false
+	}
+	if co.Condition == TriggerAtOrAbove {
+		return lastPrice >= co.TriggerPrice
+	}
+	return lastPrice <= co.TriggerPrice
+}
+
+// SubmitConditional holds an order until a trade in referenceSymbol moves
+// its last price across triggerPrice, then submits it as normal. The order
+// is rejected if the reference symbol has no book.
+func (me *MatchingEngine) SubmitConditional(order *models.Order, referenceSymbol string, condition TriggerCondition, triggerPrice float64) error {
+	switch condition {
+	case TriggerAtOrAbove, TriggerAtOrBelow:
+	default:
+		return fmt.Errorf("unknown trigger condition %q", condition)
+	}
+	if triggerPrice <= 0 {
+		return fmt.Errorf("trigger price must be positive, got %g", triggerPrice)
+	}
+
+	referenceSymbol = me.NormalizeSymbol(referenceSymbol)
+	if me.GetOrderBook(referenceSymbol) == nil {
+		reason := fmt.Sprintf("no order book for reference symbol %s", referenceSymbol)
+		order.Reject(reason)
+		return errors.New(reason)
+	}
+	order.Symbol = me.NormalizeSymbol(order.Symbol)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.conditionals[referenceSymbol] = append(me.conditionals[referenceSymbol], &ConditionalOrder{
+		Order:           order,
+		ReferenceSymbol: referenceSymbol,
+		Condition:       condition,
+		TriggerPrice:    triggerPrice,
+	})
+	return nil
+}
+
+// GetConditionalOrders returns the orders still waiting on a reference symbol
+func (me *MatchingEngine) GetConditionalOrders(referenceSymbol string) []*ConditionalOrder {
+	referenceSymbol = me.NormalizeSymbol(referenceSymbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	pending := me.conditionals[referenceSymbol]
+	result := make([]*ConditionalOrder, len(pending))
+	copy(result, pending)
+	return result
+}
+
+// GetPendingConditionals returns copies of a symbol's orders waiting on a
+// trigger: its dormant stops, then the cross-symbol conditional orders that
+// will trade it. None of them show in the book until they fire. The copies
+// are taken under the engine lock, so they don't change as triggers fire or
+// are amended.
+func (me *MatchingEngine) GetPendingConditionals(symbol string) []*models.Order {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	result := make([]*models.Order, 0, len(me.stopOrders[symbol]))
+	for _, order := range me.stopOrders[symbol] {
+		snapshot := *order
+		result = append(result, &snapshot)
+	}
+	for _, pending := range me.conditionals {
+		for _, co := range pending {
+			if co.Order.Symbol == symbol {
+				snapshot := *co.Order
+				result = append(result, &snapshot)
+			}
+		}
+	}
+	return result
+}
+
+// AmendConditional moves the trigger of a symbol's pending stop or
+// conditional order, then checks it against the current last price, so the
+// order may fire at once. The new trigger is snapped to the tick size of the
+// symbol it watches and held to the same price caps as a new order. An
+// order that has already triggered can't be amended.
+func (me *MatchingEngine) AmendConditional(symbol string, orderID uuid.UUID, newTrigger float64) error {
+	symbol = me.NormalizeSymbol(symbol)
+
+	reference, stop := me.pendingTrigger(symbol, orderID)
+	if reference == "" {
+		return fmt.Errorf("order %s has no pending trigger", orderID)
+	}
+	newTrigger, err := me.checkTrigger(reference, newTrigger)
+	if err != nil {
+		return err
+	}
+
+	me.mutex.Lock()
+	amended := false
+	if stop {
+		for _, order := range me.stopOrders[symbol] {
+			if order.ID == orderID {
+				if order.Type == models.OrderTypeStopLimit {
+					order.StopPrice = newTrigger
+				} else {
+					order.Price = newTrigger
+				}
+				amended = true
+				break
+			}
+		}
+	} else {
+		for _, co := range me.conditionals[reference] {
+			if co.Order.ID == orderID && co.Order.Symbol == symbol {
+				co.TriggerPrice = newTrigger
+				amended = true
+				break
+			}
+		}
+	}
+	me.mutex.Unlock()
+
+	// The order may have fired while the trigger was being checked
+	if !amended {
+		return fmt.Errorf("order %s has no pending trigger", orderID)
+	}
+
+	ob := me.GetOrderBook(reference)
+	if ob == nil {
+		return nil
+	}
+	if stop {
+		me.fireStops(reference, ob.LastPrice)
+	} else {
+		me.fireConditionals(reference, ob.LastPrice)
+	}
+	return nil
+}
+
+// pendingTrigger finds a symbol's order still waiting on a trigger,
+// returning the symbol whose price it watches and whether it is a stop, or
+// "" if there is no such order
+func (me *MatchingEngine) pendingTrigger(symbol string, orderID uuid.UUID) (reference string, stop bool) {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	for _, order := range me.stopOrders[symbol] {
+		if order.ID == orderID {
+			return symbol, true
+		}
+	}
+	for ref, pending := range me.conditionals {
+		for _, co := range pending {
+			if co.Order.ID == orderID && co.Order.Symbol == symbol {
+				return ref, false
+			}
+		}
+	}
+	return "", false
+}
+
+// checkTrigger validates a trigger price on the symbol it watches the way
+// checkSanity and submission validate an order price, returning it snapped
+// to the symbol's tick size. It must be called without holding the mutex.
+func (me *MatchingEngine) checkTrigger(symbol string, trigger float64) (float64, error) {
+	if math.IsNaN(trigger) || math.IsInf(trigger, 0) || trigger <= 0 {
+		return 0, fmt.Errorf("trigger price must be a positive finite number, got %g", trigger)
+	}
+
+	config := me.GetSymbolConfig(symbol)
+	if config.TickSize > 0 {
+		trigger = roundToTick(trigger, config.TickSize)
+		if trigger <= 0 {
+			return 0, fmt.Errorf("trigger price is below the tick size of %g", config.TickSize)
+		}
+	}
+
+	me.mutex.RLock()
+	limits := me.sanity
+	me.mutex.RUnlock()
+
+	if trigger > PriceCeiling {
+		return 0, fmt.Errorf("trigger price %g exceeds the ceiling of %g", trigger, float64(PriceCeiling))
+	}
+	if limits.MaxPrice > 0 && trigger > limits.MaxPrice {
+		return 0, fmt.Errorf("trigger price %g exceeds the sanity cap of %g", trigger, limits.MaxPrice)
+	}
+	if config.MaxPrice > 0 && trigger > config.MaxPrice {
+		return 0, fmt.Errorf("trigger price %g exceeds the maximum of %g for %s", trigger, config.MaxPrice, symbol)
+	}
+	return trigger, nil
+}
+
+// fireConditionals submits the conditional orders a symbol's new last price
+// triggers. It must be called without holding the mutex.
+func (me *MatchingEngine) fireConditionals(symbol string, lastPrice float64) {
+	me.mutex.Lock()
+	pending := me.conditionals[symbol]
+	fired := make([]*ConditionalOrder, 0)
+	waiting := pending[:0]
+	for _, co := range pending {
+		if co.triggered(lastPrice) {
+			fired = append(fired, co)
+			continue
+		}
+		waiting = append(waiting, co)
+	}
+	for i := len(waiting); i < len(pending); i++ {
+		pending[i] = nil
+	}
+	if len(waiting) == 0 {
+		delete(me.conditionals, symbol)
+	} else {
+		me.conditionals[symbol] = waiting
+	}
+	me.mutex.Unlock()
+
+	for _, co := range fired {
+		me.submitOrder(co.Order, nil)
+	}
+}
+
+// cancelConditional cancels a symbol's order still waiting on its reference
+// symbol, reporting whether there was one
+func (me *MatchingEngine) cancelConditional(symbol string, orderID uuid.UUID) bool {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	var order *models.Order
+	for reference, pending := range me.conditionals {
+		for i, co := range pending {
+			if co.Order.ID == orderID && co.Order.Symbol == symbol {
+				order = co.Order
+				if len(pending) == 1 {
+					delete(me.conditionals, reference)
+				} else {
+					me.conditionals[reference] = append(pending[:i:i], pending[i+1:]...)
+				}
+				break
+			}
+		}
+		if order != nil {
+			break
+		}
+	}
+	me.mutex.Unlock()
+
+	if order == nil || !order.IsActive() {
+		return false
+	}
+	order.Cancel(me.clock.Now())
+	me.emit(Event{Type: EventOrderCancelled, Symbol: symbol, OrderID: orderID})
+	return true
+}
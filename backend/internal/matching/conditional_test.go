@@ -0,0 +1,177 @@
+package matching
+
+import (
+	"math"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestConditionalOrderTriggersOffReferenceSymbol(t *testing.T) {
+	me := NewMatchingEngine()
+
+	// SPY trades at 450 and AAPL has an offer waiting
+	me.SubmitOrder(models.NewOrder("SPY", models.OrderTypeLimit, models.OrderSideSell, 10, 450.0))
+	me.SubmitOrder(models.NewOrder("SPY", models.OrderTypeLimit, models.OrderSideBuy, 10, 450.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+
+	// Buy AAPL at market when SPY falls to 440 or below
+	buy := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 100, 0)
+	if err := me.SubmitConditional(buy, "spy", TriggerAtOrBelow, 440.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A trade above the trigger leaves it waiting
+	me.SubmitOrder(models.NewOrder("SPY", models.OrderTypeLimit, models.OrderSideSell, 10, 445.0))
+	me.SubmitOrder(models.NewOrder("SPY", models.OrderTypeLimit, models.OrderSideBuy, 10, 445.0))
+	if buy.Status != models.OrderStatusPending || len(me.GetConditionalOrders("SPY")) != 1 {
+		t.Fatalf("Expected the conditional order to keep waiting, got %s", buy.Status)
+	}
+
+	me.SubmitOrder(models.NewOrder("SPY", models.OrderTypeLimit, models.OrderSideSell, 10, 439.0))
+	me.SubmitOrder(models.NewOrder("SPY", models.OrderTypeLimit, models.OrderSideBuy, 10, 439.0))
+
+	if buy.Status != models.OrderStatusFilled || buy.FilledPrice != 150.0 {
+		t.Errorf("Expected the AAPL buy to fill at 150, got %s at %f", buy.Status, buy.FilledPrice)
+	}
+	if len(me.GetConditionalOrders("SPY")) != 0 {
+		t.Errorf("Expected no conditional orders left, got %d", len(me.GetConditionalOrders("SPY")))
+	}
+}
+
+func TestConditionalOrderRequiresReferenceBook(t *testing.T) {
+	me := NewMatchingEngine()
+
+	buy := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 100, 0)
+	if err := me.SubmitConditional(buy, "SPY", TriggerAtOrBelow, 440.0); err == nil {
+		t.Error("Expected an error for a reference symbol without a book")
+	}
+	if buy.Status != models.OrderStatusRejected {
+		t.Errorf("Expected the order to be rejected, got %s", buy.Status)
+	}
+	if err := me.SubmitConditional(buy, "AAPL", "sideways", 440.0); err == nil {
+		t.Error("Expected an error for an unknown trigger condition")
+	}
+}
+
+func TestCancelConditionalOrder(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(models.NewOrder("SPY", models.OrderTypeLimit, models.OrderSideBuy, 10, 450.0))
+
+	buy := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 100, 0)
+	if err := me.SubmitConditional(buy, "SPY", TriggerAtOrBelow, 440.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if me.CancelOrder("SPY", buy.ID) {
+		t.Error("Expected the cancel to need the order's own symbol")
+	}
+	if !me.CancelOrder("aapl", buy.ID) || buy.Status != models.OrderStatusCancelled {
+		t.Fatalf("Expected the waiting conditional order cancelled, got %s", buy.Status)
+	}
+	if len(me.GetConditionalOrders("SPY")) != 0 {
+		t.Error("Expected the cancelled order no longer waiting")
+	}
+	if me.CancelOrder("AAPL", buy.ID) {
+		t.Error("Expected a second cancel to fail")
+	}
+}
+
+func TestPendingConditionalsStayOutOfTheBook(t *testing.T) {
+	me := NewMatchingEngine()
+	printTrade(me, 100.0, 10)
+	me.GetOrCreateOrderBook("SPY")
+
+	stop := me.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideSell, 10, 95.0)
+	me.SubmitOrder(stop)
+	conditional := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0)
+	if err := me.SubmitConditional(conditional, "SPY", TriggerAtOrAbove, 460.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pending := me.GetPendingConditionals("aapl")
+	if len(pending) != 2 || pending[0].ID != stop.ID || pending[1].ID != conditional.ID {
+		t.Fatalf("Expected the stop then the conditional order pending, got %d", len(pending))
+	}
+	if pending[0] == stop || pending[1] == conditional {
+		t.Error("Expected copies of the pending orders, not the live ones")
+	}
+	if snapshot := me.GetOrderBook("AAPL").Snapshot(); len(snapshot.Bids) != 0 || len(snapshot.Asks) != 0 {
+		t.Errorf("Expected neither in the book, got %d bids and %d asks", len(snapshot.Bids), len(snapshot.Asks))
+	}
+	if other := me.GetPendingConditionals("SPY"); len(other) != 0 {
+		t.Errorf("Expected nothing pending on SPY itself, got %d", len(other))
+	}
+}
+
+func TestAmendConditionalTriggersAtOnce(t *testing.T) {
+	me := NewMatchingEngine()
+	printTrade(me, 100.0, 10)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+
+	stop := me.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideBuy, 10, 105.0)
+	me.SubmitOrder(stop)
+	if stop.Status != models.OrderStatusPending {
+		t.Fatalf("Expected the stop to wait above the market, got %s", stop.Status)
+	}
+
+	// Lowering the trigger below the last price fires it on the amend
+	if err := me.AmendConditional("aapl", stop.ID, 99.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stop.Status != models.OrderStatusFilled || stop.FilledPrice != 101.0 {
+		t.Errorf("Expected the stop filled at 101, got %s at %g", stop.Status, stop.FilledPrice)
+	}
+	if pending := me.GetPendingConditionals("AAPL"); len(pending) != 0 {
+		t.Errorf("Expected nothing left pending, got %d", len(pending))
+	}
+
+	if err := me.AmendConditional("AAPL", stop.ID, 98.0); err == nil {
+		t.Error("Expected amending a triggered stop to fail")
+	}
+}
+
+func TestAmendConditionalKeepsWaiting(t *testing.T) {
+	me := NewMatchingEngine()
+	printTrade(me, 100.0, 10)
+	me.SubmitOrder(models.NewOrder("SPY", models.OrderTypeLimit, models.OrderSideSell, 10, 450.0))
+	me.SubmitOrder(models.NewOrder("SPY", models.OrderTypeLimit, models.OrderSideBuy, 10, 450.0))
+
+	buy := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	if err := me.SubmitConditional(buy, "SPY", TriggerAtOrBelow, 440.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := me.AmendConditional("AAPL", buy.ID, 445.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pending := me.GetConditionalOrders("SPY")
+	if len(pending) != 1 || pending[0].TriggerPrice != 445.0 || buy.Status != models.OrderStatusPending {
+		t.Errorf("Expected the order waiting at 445, got %+v", pending)
+	}
+}
+
+func TestAmendConditionalValidatesTrigger(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: 0.05, MaxPrice: 200.0})
+	printTrade(me, 100.0, 10)
+
+	stop := me.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideBuy, 10, 105.0)
+	me.SubmitOrder(stop)
+
+	for _, trigger := range []float64{math.NaN(), math.Inf(1), -1, 250.0, 2e9} {
+		if err := me.AmendConditional("AAPL", stop.ID, trigger); err == nil {
+			t.Errorf("Expected a trigger of %g to be rejected", trigger)
+		}
+	}
+	if stop.Price != 105.0 || stop.Status != models.OrderStatusPending {
+		t.Errorf("Expected the stop left waiting at 105, got %s at %g", stop.Status, stop.Price)
+	}
+
+	// An off-tick trigger is snapped to the tick size
+	if err := me.AmendConditional("AAPL", stop.ID, 110.02); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pending := me.GetPendingConditionals("AAPL"); len(pending) != 1 || pending[0].Price != 110.0 {
+		t.Errorf("Expected the trigger snapped to 110, got %+v", pending)
+	}
+}
@@ -0,0 +1,120 @@
+package matching
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// BookSubscription delivers conflated order book snapshots for one symbol.
+// However often the book changes, at most one snapshot is sent per interval
+// and it always reflects the latest state.
+type BookSubscription struct {
+	C <-chan *orderbook.OrderBookSnapshot
+
+	symbol  string
+	engine  *MatchingEngine
+	updates chan *orderbook.OrderBookSnapshot
+	dirty   atomic.Bool
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// bookListener is told when a symbol's book changes, so it can send an
+// update on its next flush
+type bookListener interface {
+	markDirty()
+}
+
+// SubscribeBook starts delivering conflated snapshots of a symbol's book on
+// the returned subscription's channel. Call Close when done.
+func (me *MatchingEngine) SubscribeBook(symbol string, interval time.Duration) *BookSubscription {
+	symbol = me.NormalizeSymbol(symbol)
+
+	updates := make(chan *orderbook.OrderBookSnapshot, 1)
+	sub := &BookSubscription{
+		C:       updates,
+		symbol:  symbol,
+		engine:  me,
+		updates: updates,
+		stop:    make(chan struct{}),
+	}
+
+	me.mutex.Lock()
+	me.subscribers[symbol] = append(me.subscribers[symbol], sub)
+	me.mutex.Unlock()
+
+	go sub.run(interval)
+	return sub
+}
+
+// Close stops the subscription. The channel is not closed, so a pending
+// receive simply never completes.
+func (sub *BookSubscription) Close() {
+	sub.once.Do(func() {
+		close(sub.stop)
+
+		me := sub.engine
+		me.mutex.Lock()
+		me.subscribers[sub.symbol] = slices.DeleteFunc(me.subscribers[sub.symbol], func(s bookListener) bool {
+			return s == sub
+		})
+		me.mutex.Unlock()
+	})
+}
+
+func (sub *BookSubscription) markDirty() {
+	sub.dirty.Store(true)
+}
+
+func (sub *BookSubscription) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sub.flush()
+		case <-sub.stop:
+			return
+		}
+	}
+}
+
+// flush sends a snapshot if the book changed since the last one, replacing
+// any snapshot the subscriber has not read yet
+func (sub *BookSubscription) flush() {
+	if !sub.dirty.Swap(false) {
+		return
+	}
+
+	ob := sub.engine.GetOrderBook(sub.symbol)
+	if ob == nil {
+		return
+	}
+	snapshot := ob.Snapshot()
+
+	select {
+	case <-sub.updates:
+	default:
+	}
+	sub.updates <- snapshot
+}
+
+// bookChanged records the symbol's spread and delayed history and marks
+// every subscriber to its book as having an update due. It must be called
+// without holding the engine mutex.
+func (me *MatchingEngine) bookChanged(symbol string) {
+	me.recordSpread(symbol)
+	me.recordDelayed(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	for _, sub := range me.subscribers[symbol] {
+		sub.markDirty()
+	}
+}
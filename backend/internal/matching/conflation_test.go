@@ -0,0 +1,74 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestBookSubscriptionConflatesUpdates(t *testing.T) {
+	me := NewMatchingEngine()
+
+	// A long interval keeps the ticker out of the way; the test flushes by hand
+	sub := me.SubscribeBook("AAPL", time.Hour)
+	defer sub.Close()
+
+	for i := 0; i < 10; i++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0+float64(i)))
+	}
+	sub.flush()
+
+	select {
+	case snapshot := <-sub.C:
+		if len(snapshot.Bids) != 10 {
+			t.Errorf("Expected snapshot with all 10 bid levels, got %d", len(snapshot.Bids))
+		}
+		if snapshot.Bids[0].Price != 109.0 {
+			t.Errorf("Expected best bid 109, got %v", snapshot.Bids[0].Price)
+		}
+	default:
+		t.Fatal("Expected a conflated snapshot")
+	}
+
+	// Nothing changed since, so the next interval sends nothing
+	sub.flush()
+	select {
+	case <-sub.C:
+		t.Error("Expected no snapshot without book changes")
+	default:
+	}
+
+	// An unread snapshot is replaced by the newer one
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 120.0))
+	sub.flush()
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 121.0))
+	sub.flush()
+
+	snapshot := <-sub.C
+	if len(snapshot.Asks) != 2 {
+		t.Errorf("Expected latest snapshot with 2 asks, got %d", len(snapshot.Asks))
+	}
+	select {
+	case <-sub.C:
+		t.Error("Expected stale snapshot to be dropped")
+	default:
+	}
+}
+
+func TestBookSubscriptionTicker(t *testing.T) {
+	me := NewMatchingEngine()
+	sub := me.SubscribeBook("aapl", 10*time.Millisecond)
+	defer sub.Close()
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+
+	select {
+	case snapshot := <-sub.C:
+		if snapshot.Symbol != "AAPL" {
+			t.Errorf("Expected AAPL snapshot, got %s", snapshot.Symbol)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a snapshot within the interval")
+	}
+}
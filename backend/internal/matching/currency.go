@@ -0,0 +1,87 @@
+package matching
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// FXRateSource supplies the rate that converts a price in one currency to
+// another, e.g. Rate("EUR", "USD") of 1.1 turns 100 EUR into 110 USD
+type FXRateSource interface {
+	Rate(from, to string) (float64, bool)
+}
+
+// StaticFXRates is a fixed table of rates keyed by "FROM/TO". The inverse of
+// a listed pair is used when only the other direction is present.
+type StaticFXRates map[string]float64
+
+// Rate returns the rate for converting from one currency to another
+func (r StaticFXRates) Rate(from, to string) (float64, bool) {
+	if rate, exists := r[from+"/"+to]; exists && rate > 0 {
+		return rate, true
+	}
+	if rate, exists := r[to+"/"+from]; exists && rate > 0 {
+		return 1 / rate, true
+	}
+	return 0, false
+}
+
+// SetFXRates sets where conversion rates for orders priced in another
+// currency come from. nil, the default, rejects any order that needs one.
+func (me *MatchingEngine) SetFXRates(source FXRateSource) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.fxRates = source
+}
+
+// convertCurrency converts an order priced in a currency other than its
+// symbol's into the symbol's currency, returning a reject reason if it
+// can't. The limit and stop prices are both converted and rounded to the
+// symbol's tick size.
+func (me *MatchingEngine) convertCurrency(order *models.Order) string {
+	from := strings.ToUpper(order.Currency)
+	if from == "" {
+		return ""
+	}
+
+	config := me.GetSymbolConfig(order.Symbol)
+	to := strings.ToUpper(config.Currency)
+	if to == "" {
+		return fmt.Sprintf("%s has no currency to convert %s prices into", order.Symbol, from)
+	}
+	if from == to || order.Type == models.OrderTypeMarket {
+		order.Currency = to
+		return ""
+	}
+
+	me.mutex.RLock()
+	source := me.fxRates
+	me.mutex.RUnlock()
+
+	rate, ok := 0.0, false
+	if source != nil {
+		rate, ok = source.Rate(from, to)
+	}
+	if !ok || rate <= 0 {
+		return fmt.Sprintf("no FX rate available from %s to %s", from, to)
+	}
+
+	convert := func(name string, price *float64) {
+		if *price == 0 {
+			return
+		}
+		converted := *price * rate
+		if config.TickSize > 0 {
+			converted = roundToTick(converted, config.TickSize)
+		}
+		order.Warn(fmt.Sprintf("%s %g %s converted to %g %s at %g", name, *price, from, converted, to, rate))
+		*price = converted
+	}
+	convert("price", &order.Price)
+	convert("stop price", &order.StopPrice)
+	order.Currency = to
+	return ""
+}
@@ -0,0 +1,74 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestOrderPricedInForeignCurrencyIsConverted(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: 0.01, Currency: "USD"})
+	me.SetFXRates(StaticFXRates{"EUR/USD": 1.1})
+
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 136.37)
+	order.Currency = "eur"
+	me.SubmitOrder(order)
+
+	if order.Status != models.OrderStatusPending {
+		t.Fatalf("Expected the order to rest, got %s (%s)", order.Status, order.RejectReason)
+	}
+	if order.Price != 150.01 || order.Currency != "USD" {
+		t.Errorf("Expected 150.01 USD, got %v %s", order.Price, order.Currency)
+	}
+	if bid := me.GetOrderBook("AAPL").GetBestBid(); bid != 150.01 {
+		t.Errorf("Expected best bid 150.01, got %v", bid)
+	}
+
+	// The inverse of a listed pair works too
+	me.SetSymbolConfig("SAP", SymbolConfig{Currency: "EUR"})
+	sell := models.NewOrder("SAP", models.OrderTypeLimit, models.OrderSideSell, 100, 165.0)
+	sell.Currency = "USD"
+	me.SubmitOrder(sell)
+	if sell.Price != 150.0 {
+		t.Errorf("Expected 165 USD to convert to 150 EUR, got %v", sell.Price)
+	}
+}
+
+func TestOrderRejectedWithoutFXRate(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{Currency: "USD"})
+
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 100.0)
+	order.Currency = "GBP"
+	me.SubmitOrder(order)
+
+	if order.Status != models.OrderStatusRejected {
+		t.Errorf("Expected the order to be rejected, got %s", order.Status)
+	}
+	if order.RejectReason != "no FX rate available from GBP to USD" {
+		t.Errorf("Expected a missing rate reason, got %q", order.RejectReason)
+	}
+}
+
+func TestStopPriceIsConverted(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: 0.01, Currency: "USD"})
+	me.SetFXRates(StaticFXRates{"EUR/USD": 1.1})
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0))
+
+	stop := models.NewOrder("AAPL", models.OrderTypeStopLimit, models.OrderSideSell, 10, 100.0)
+	stop.StopPrice = 125.0
+	stop.Currency = "EUR"
+	me.SubmitOrder(stop)
+
+	if !models.DefaultTolerance.PricesEqual(stop.StopPrice, 137.5) || !models.DefaultTolerance.PricesEqual(stop.Price, 110.0) {
+		t.Fatalf("Expected the stop at 137.5 / 110 USD, got %g / %g", stop.StopPrice, stop.Price)
+	}
+
+	// A trade at 140 USD is above the converted trigger, so the stop waits
+	printTrade(me, 140.0, 1)
+	if stop.FilledQuantity != 0 || len(me.GetStopOrders("AAPL")) != 1 {
+		t.Errorf("Expected the stop still dormant above 137.5, got %g filled", stop.FilledQuantity)
+	}
+}
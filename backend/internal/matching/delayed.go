@@ -0,0 +1,158 @@
+package matching
+
+import (
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// delayedResolution is the finest spacing of a symbol's delayed recordings.
+// A change within it of the last recording replaces that recording, so the
+// history holds at most one delay over the resolution of them.
+const delayedResolution = 100 * time.Millisecond
+
+// delayedSnapshot is a book's state recorded when it changed
+type delayedSnapshot struct {
+	at       time.Time
+	snapshot *orderbook.OrderBookSnapshot
+}
+
+// SetMarketDataDelay sets how far behind the delayed market-data tier runs.
+// While it is non-zero book changes are recorded, at most one per
+// delayedResolution, and history older than the delay is discarded once a
+// newer recording covers it. 0, the default, turns the tier off.
+func (me *MatchingEngine) SetMarketDataDelay(delay time.Duration) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.dataDelay = delay
+	if delay <= 0 {
+		me.delayed = make(map[string][]delayedSnapshot)
+	}
+}
+
+// GetMarketDataDelay returns how far behind the delayed tier runs
+func (me *MatchingEngine) GetMarketDataDelay() time.Duration {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	return me.dataDelay
+}
+
+// DelayedSnapshot returns a symbol's book as it was one market-data delay
+// ago, sorted best first. It returns false if the book did not exist then
+// or the tier is off.
+func (me *MatchingEngine) DelayedSnapshot(symbol string) (*orderbook.OrderBookSnapshot, bool) {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	if me.dataDelay <= 0 {
+		return nil, false
+	}
+	cutoff := me.clock.Now().Add(-me.dataDelay)
+
+	history := me.delayed[symbol]
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].at.After(cutoff) {
+			// A copy, so callers can adjust it for display
+			snapshot := *history[i].snapshot
+			return &snapshot, true
+		}
+	}
+	return nil, false
+}
+
+// recordDelayed keeps the symbol's current book for the delayed tier. It
+// must be called without holding the mutex.
+func (me *MatchingEngine) recordDelayed(symbol string) {
+	if me.GetMarketDataDelay() <= 0 {
+		return
+	}
+	ob := me.GetOrderBook(symbol)
+	if ob == nil {
+		return
+	}
+	snapshot := ob.Depth(0, 0)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	now := me.clock.Now()
+	history := me.delayed[symbol]
+	recording := delayedSnapshot{at: now, snapshot: snapshot}
+
+	// A change close behind the last recording replaces it. The recording
+	// takes the later time, so the delayed tier never sees a state early.
+	if n := len(history); n > 0 && now.Sub(history[n-1].at) < delayedResolution {
+		history[n-1] = recording
+	} else {
+		history = append(history, recording)
+	}
+	me.delayed[symbol] = trimDelayed(history, now.Add(-me.dataDelay))
+}
+
+// trimDelayed drops recordings no reader can need: everything before the
+// newest one at or before the cutoff
+func trimDelayed(history []delayedSnapshot, cutoff time.Time) []delayedSnapshot {
+	keep := 0
+	for i := range history {
+		if history[i].at.After(cutoff) {
+			break
+		}
+		keep = i
+	}
+	if keep == 0 {
+		return history
+	}
+	// Copy down so the dropped recordings can be garbage collected
+	n := copy(history, history[keep:])
+	clear(history[n:])
+	return history[:n]
+}
+
+// PruneDelayed trims every symbol's delayed history to what the tier can
+// still serve. Recording trims as it goes; this is meant to be run
+// periodically so books that stop changing don't hold history either.
+func (me *MatchingEngine) PruneDelayed() {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	if me.dataDelay <= 0 {
+		return
+	}
+	cutoff := me.clock.Now().Add(-me.dataDelay)
+	for symbol, history := range me.delayed {
+		me.delayed[symbol] = trimDelayed(history, cutoff)
+	}
+}
+
+// DelayedCutoff returns the latest time the delayed tier may see, one
+// market-data delay ago
+func (me *MatchingEngine) DelayedCutoff() time.Time {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	return me.clock.Now().Add(-me.dataDelay)
+}
+
+// DelayedTrades returns up to limit of a symbol's public trades executed at
+// least one market-data delay ago, newest first
+func (me *MatchingEngine) DelayedTrades(symbol string, limit int) []*models.Trade {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	now := me.clock.Now()
+	me.publishTrades(me.tape.release(now))
+	return me.tape.recent(symbol, limit, now.Add(-me.dataDelay))
+}
+
+// DelayedPriceHistory returns up to limit last-trade prices for a symbol, as
+// GetPriceHistory, from trades at least one market-data delay old
+func (me *MatchingEngine) DelayedPriceHistory(symbol string, limit int) []PricePoint {
+	return pricePoints(me.DelayedTrades(symbol, limit))
+}
@@ -0,0 +1,127 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestDelayedSnapshotLagsTheBook(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetMarketDataDelay(15 * time.Minute)
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0))
+	if _, ok := me.DelayedSnapshot("AAPL"); ok {
+		t.Error("Expected no delayed snapshot before the book is 15 minutes old")
+	}
+
+	mock.Advance(20 * time.Minute)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 151.0))
+
+	delayed, ok := me.DelayedSnapshot("AAPL")
+	if !ok {
+		t.Fatal("Expected a delayed snapshot")
+	}
+	if len(delayed.Bids) != 1 || len(delayed.Asks) != 0 {
+		t.Errorf("Expected the delayed book to hold only the bid, got %d bids and %d asks", len(delayed.Bids), len(delayed.Asks))
+	}
+	if current := me.GetOrderBook("AAPL").Snapshot(); len(current.Asks) != 1 {
+		t.Errorf("Expected the real-time book to hold the ask, got %d asks", len(current.Asks))
+	}
+
+	// Once the delay has passed the ask shows up
+	mock.Advance(15 * time.Minute)
+	delayed, _ = me.DelayedSnapshot("AAPL")
+	if len(delayed.Asks) != 1 {
+		t.Errorf("Expected the delayed book to catch up, got %d asks", len(delayed.Asks))
+	}
+}
+
+func TestDelayedHistoryIsTrimmed(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetMarketDataDelay(time.Minute)
+
+	for i := 0; i < 10; i++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0+float64(i)))
+		mock.Advance(30 * time.Second)
+	}
+
+	me.mutex.RLock()
+	recorded := len(me.delayed["AAPL"])
+	me.mutex.RUnlock()
+	if recorded > 3 {
+		t.Errorf("Expected at most 3 recordings kept for a 1 minute delay, got %d", recorded)
+	}
+}
+
+func TestDelayedRecordingsAreConflated(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetMarketDataDelay(time.Minute)
+
+	// A burst well inside the resolution leaves one recording, of its end
+	for i := 0; i < 100; i++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 100.0-float64(i)*0.01))
+	}
+	if n := len(me.delayed["AAPL"]); n != 1 {
+		t.Fatalf("Expected the burst conflated into 1 recording, got %d", n)
+	}
+
+	mock.Advance(time.Minute)
+	if delayed, ok := me.DelayedSnapshot("AAPL"); !ok || len(delayed.Bids) != 100 {
+		t.Errorf("Expected the recording to hold the burst's end state")
+	}
+}
+
+func TestPruneDelayedTrimsQuietBooks(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetMarketDataDelay(time.Minute)
+
+	for i := 0; i < 10; i++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 100.0-float64(i)))
+		mock.Advance(time.Second)
+	}
+	mock.Advance(time.Hour)
+
+	me.PruneDelayed()
+	if n := len(me.delayed["AAPL"]); n != 1 {
+		t.Errorf("Expected only the latest recording kept, got %d", n)
+	}
+	if delayed, ok := me.DelayedSnapshot("AAPL"); !ok || len(delayed.Bids) != 10 {
+		t.Error("Expected the kept recording to still be served")
+	}
+}
+
+func TestDelayedTrades(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetMarketDataDelay(15 * time.Minute)
+
+	printTrade(me, 100.0, 1)
+	mock.Advance(10 * time.Minute)
+	printTrade(me, 101.0, 1)
+	mock.Advance(10 * time.Minute)
+
+	trades := me.DelayedTrades("AAPL", 10)
+	if len(trades) != 1 || trades[0].Price != 100.0 {
+		t.Fatalf("Expected only the trade older than the delay, got %d", len(trades))
+	}
+	if points := me.DelayedPriceHistory("AAPL", 10); len(points) != 1 || points[0].Price != 100.0 {
+		t.Errorf("Expected the delayed price history to match, got %v", points)
+	}
+}
@@ -0,0 +1,168 @@
+package matching
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// BookDelta is the displayed state of one price level after a change. A
+// Quantity of 0 means the level is gone.
+type BookDelta struct {
+	Side     models.OrderSide `json:"side"`
+	Price    float64          `json:"price"`
+	Quantity float64          `json:"quantity"`
+	Orders   int              `json:"orders"`
+}
+
+// deltaKey identifies a price level on one side of the book
+type deltaKey struct {
+	side  models.OrderSide
+	price float64
+}
+
+// DeltaSubscription delivers level-by-level book changes for one symbol,
+// coalesced by price level: however often a level changes within an
+// interval, one delta carries its net state on the next flush
+type DeltaSubscription struct {
+	C <-chan []BookDelta
+
+	symbol  string
+	engine  *MatchingEngine
+	updates chan []BookDelta
+	levels  map[deltaKey]BookDelta // Levels as of the last flush
+	dirty   atomic.Bool
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// SubscribeDeltas starts delivering coalesced level deltas of a symbol's
+// book on the returned subscription's channel. The first batch describes
+// every level, so a subscriber can build the book from nothing. Call Close
+// when done.
+func (me *MatchingEngine) SubscribeDeltas(symbol string, interval time.Duration) *DeltaSubscription {
+	symbol = me.NormalizeSymbol(symbol)
+
+	updates := make(chan []BookDelta, 1)
+	sub := &DeltaSubscription{
+		C:       updates,
+		symbol:  symbol,
+		engine:  me,
+		updates: updates,
+		levels:  make(map[deltaKey]BookDelta),
+		stop:    make(chan struct{}),
+	}
+	sub.dirty.Store(true)
+
+	me.mutex.Lock()
+	me.subscribers[symbol] = append(me.subscribers[symbol], sub)
+	me.mutex.Unlock()
+
+	go sub.run(interval)
+	return sub
+}
+
+// Close stops the subscription. The channel is not closed, so a pending
+// receive simply never completes.
+func (sub *DeltaSubscription) Close() {
+	sub.once.Do(func() {
+		close(sub.stop)
+
+		me := sub.engine
+		me.mutex.Lock()
+		me.subscribers[sub.symbol] = slices.DeleteFunc(me.subscribers[sub.symbol], func(s bookListener) bool {
+			return s == sub
+		})
+		me.mutex.Unlock()
+	})
+}
+
+func (sub *DeltaSubscription) markDirty() {
+	sub.dirty.Store(true)
+}
+
+func (sub *DeltaSubscription) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sub.flush()
+		case <-sub.stop:
+			return
+		}
+	}
+}
+
+// flush sends a delta for every level whose displayed state differs from
+// the last flush. A batch the subscriber has not read yet is merged into the
+// new one, later state winning, so no change is lost.
+func (sub *DeltaSubscription) flush() {
+	if !sub.dirty.Swap(false) {
+		return
+	}
+
+	ob := sub.engine.GetOrderBook(sub.symbol)
+	if ob == nil {
+		return
+	}
+	snapshot := ob.Depth(0, 0)
+
+	current := make(map[deltaKey]BookDelta)
+	record := func(side models.OrderSide, levels []orderbook.PriceLevelSnapshot) {
+		for _, level := range levels {
+			current[deltaKey{side, level.Price}] = BookDelta{Side: side, Price: level.Price, Quantity: level.Quantity, Orders: level.Orders}
+		}
+	}
+	record(models.OrderSideBuy, snapshot.Bids)
+	record(models.OrderSideSell, snapshot.Asks)
+
+	changed := make(map[deltaKey]BookDelta)
+	for key, delta := range current {
+		if sub.levels[key] != delta {
+			changed[key] = delta
+		}
+	}
+	for key := range sub.levels {
+		if _, exists := current[key]; !exists {
+			changed[key] = BookDelta{Side: key.side, Price: key.price}
+		}
+	}
+	sub.levels = current
+
+	select {
+	case pending := <-sub.updates:
+		for _, delta := range pending {
+			key := deltaKey{delta.Side, delta.Price}
+			if _, exists := changed[key]; !exists {
+				changed[key] = delta
+			}
+		}
+	default:
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	deltas := make([]BookDelta, 0, len(changed))
+	for _, delta := range changed {
+		deltas = append(deltas, delta)
+	}
+	// Bids best first, then asks best first
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].Side != deltas[j].Side {
+			return deltas[i].Side == models.OrderSideBuy
+		}
+		if deltas[i].Side == models.OrderSideBuy {
+			return deltas[i].Price > deltas[j].Price
+		}
+		return deltas[i].Price < deltas[j].Price
+	})
+	sub.updates <- deltas
+}
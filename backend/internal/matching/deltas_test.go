@@ -0,0 +1,64 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestDeltaSubscriptionCoalescesByLevel(t *testing.T) {
+	me := NewMatchingEngine()
+	resting := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	me.SubmitOrder(resting)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 155.0))
+
+	// A long interval keeps the ticker out of the way; the test flushes by hand
+	sub := me.SubscribeDeltas("AAPL", time.Hour)
+	defer sub.Close()
+
+	sub.flush()
+	initial := <-sub.C
+	if len(initial) != 2 || initial[0].Price != 150.0 || initial[1].Price != 155.0 {
+		t.Fatalf("Expected the initial batch to describe both levels, got %v", initial)
+	}
+
+	// Three size changes at 150 within one interval
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 50, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 25, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 60, 150.0))
+	sub.flush()
+
+	deltas := <-sub.C
+	if len(deltas) != 1 {
+		t.Fatalf("Expected a single net delta, got %v", deltas)
+	}
+	if delta := deltas[0]; delta.Side != models.OrderSideBuy || delta.Price != 150.0 || delta.Quantity != 115 || delta.Orders != 3 {
+		t.Errorf("Expected 115 across 3 orders bid at 150, got %+v", delta)
+	}
+
+	// A removed level is sent with zero quantity, and an unread batch is
+	// merged rather than lost
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 155.0))
+	sub.flush()
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 149.0))
+	sub.flush()
+
+	deltas = <-sub.C
+	if len(deltas) != 2 {
+		t.Fatalf("Expected the merged batch to hold 2 deltas, got %v", deltas)
+	}
+	if deltas[0].Price != 149.0 || deltas[0].Quantity != 5 {
+		t.Errorf("Expected a new bid of 5 at 149, got %+v", deltas[0])
+	}
+	if deltas[1].Side != models.OrderSideSell || deltas[1].Price != 155.0 || deltas[1].Quantity != 0 {
+		t.Errorf("Expected the 155 offer to be removed, got %+v", deltas[1])
+	}
+
+	sub.flush()
+	select {
+	case deltas := <-sub.C:
+		t.Errorf("Expected nothing without book changes, got %v", deltas)
+	default:
+	}
+}
@@ -0,0 +1,56 @@
+package matching
+
+import "math"
+
+// DisplayPrice rounds a derived price to the symbol's tick size when the
+// symbol is configured to round prices, and returns it unchanged otherwise.
+// Internal state always keeps full precision; this is for outward-facing
+// values only.
+func (me *MatchingEngine) DisplayPrice(symbol string, price float64) float64 {
+	config := me.GetSymbolConfig(symbol)
+	if !config.RoundPrices || config.TickSize <= 0 {
+		return price
+	}
+	return roundToTick(price, config.TickSize)
+}
+
+// roundToTick rounds price to the nearest multiple of tick. A price already
+// on a tick comes back exactly as it went in, so float error in the
+// multiplication can never move a resting price.
+func roundToTick(price, tick float64) float64 {
+	ticks := math.Round(price / tick)
+	if math.Abs(price-ticks*tick) < tick*1e-9 {
+		return price
+	}
+
+	return tickMultiple(ticks, tick)
+}
+
+// tickMultiple returns ticks*tick with the float noise of the multiplication
+// trimmed back to the tick's own precision
+func tickMultiple(ticks, tick float64) float64 {
+	scale := 1.0
+	for i := 0; i < 12 && math.Abs(tick*scale-math.Round(tick*scale)) > 1e-9; i++ {
+		scale *= 10
+	}
+	return math.Round(ticks*tick*scale) / scale
+}
+
+// DisplaySummary returns a copy of a fill summary with its prices rounded
+// for display
+func (me *MatchingEngine) DisplaySummary(symbol string, summary *FillSummary) *FillSummary {
+	if summary == nil {
+		return nil
+	}
+
+	rounded := *summary
+	rounded.AveragePrice = me.DisplayPrice(symbol, summary.AveragePrice)
+	rounded.Levels = make([]LevelFill, len(summary.Levels))
+	for i, level := range summary.Levels {
+		rounded.Levels[i] = LevelFill{
+			Price:    me.DisplayPrice(symbol, level.Price),
+			Quantity: level.Quantity,
+		}
+	}
+	return &rounded
+}
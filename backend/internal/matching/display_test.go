@@ -0,0 +1,27 @@
+package matching
+
+import (
+	"testing"
+)
+
+func TestDisplayPrice(t *testing.T) {
+	me := NewMatchingEngine()
+
+	if got := me.DisplayPrice("AAPL", 100.0333); got != 100.0333 {
+		t.Errorf("Expected unrounded price without config, got %v", got)
+	}
+
+	me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: 0.05, RoundPrices: true})
+
+	if got := me.DisplayPrice("AAPL", 100.0333); got != 100.05 {
+		t.Errorf("Expected 100.05, got %v", got)
+	}
+
+	// On-tick prices come back bit-for-bit, even where tick multiplication
+	// would introduce float error
+	for _, price := range []float64{150.05, 0.15, 99.95, 1234.55} {
+		if got := me.DisplayPrice("AAPL", price); got != price {
+			t.Errorf("Expected on-tick price %v to be unchanged, got %v", price, got)
+		}
+	}
+}
@@ -2,29 +2,277 @@ package matching
 
 import (
 	"container/heap"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/acagliol/arbitrax/backend/internal/clock"
 	"github.com/acagliol/arbitrax/backend/internal/models"
 	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/google/uuid"
 )
 
+// SymbolConfig holds per-symbol trading parameters
+type SymbolConfig struct {
+	TickSize float64 // Minimum price increment, 0 if unrestricted
+	MaxPrice float64 // Highest accepted order price, 0 if only the global caps apply
+
+	// Currency is what the book's prices are in. Orders priced in another
+	// currency are converted using the engine's FX rates.
+	Currency string
+
+	// MaxSlippage stops a market order sweeping past this fraction from the
+	// best price it first meets, e.g. 0.01 for 1%. 0 for no cap.
+	MaxSlippage float64
+
+	// MaxSweep stops a marketable limit order sweeping past this fraction
+	// from the best price it first meets, e.g. 0.02 for 2%, cancelling the
+	// remainder rather than resting it through the book. 0 for no cap.
+	MaxSweep float64
+
+	// ConfirmMarketable rejects a limit order that would trade on arrival
+	// unless it sets ConfirmRest, so a remainder never rests at a limit far
+	// through the market by accident. Immediate orders never rest and need
+	// no confirmation.
+	ConfirmMarketable bool
+
+	// MinSpread is the narrowest spread a post-only order may leave, 0 to
+	// only stop post-only orders from taking liquidity
+	MinSpread float64
+
+	// OddLots sets how trades smaller than a round lot are reported
+	OddLots OddLotRule
+
+	// RoundPrices rounds derived prices (mid, VWAP) to TickSize for display
+	RoundPrices bool
+
+	// SessionOrderTypes lists the order types accepted in each phase, nil
+	// for DefaultSessionOrderTypes
+	SessionOrderTypes map[SessionPhase][]models.OrderType
+}
+
 // MatchingEngine handles order matching across multiple order books
 type MatchingEngine struct {
-	orderBooks map[string]*orderbook.OrderBook
-	trades     []*models.Trade
-	mutex      sync.RWMutex
+	orderBooks     map[string]*orderbook.OrderBook
+	symbolConfigs  map[string]SymbolConfig
+	symbolAliases  map[string]string
+	accountOrders  map[string]map[uuid.UUID]*models.Order // Resting orders by account
+	orderIndex     map[uuid.UUID]*models.Order            // Accepted orders by ID until pruned
+	positions      map[string]map[string]*Position        // Positions by account and symbol
+	trades         tradeLog
+	spreads        map[string][]SpreadPoint // Bounded BBO history by symbol, oldest first
+	bbos           map[string]orderbook.BBO // Last top of book reported by symbol
+	makers         map[string]*makerSymbol  // Quoting metrics by symbol, nil when not tracking
+	levelChanges   map[string][]levelChange // Recent displayed level adds, trades and cancels by symbol
+	circuitBreaker CircuitBreakerConfig
+	auctionConfig  VolatilityAuctionConfig
+	icebergConfig  IcebergDetectionConfig
+	halted         map[string]bool
+	pegged         map[string]map[uuid.UUID]*models.Order
+	paused         map[string]*matchingPause
+	auctions       map[string]*volatilityAuction
+	references     map[string]float64   // Seeded reference prices by symbol
+	established    map[string]bool      // Symbols whose reference was set by their first trade
+	activity       map[string]*activity // Recent order and trade times by symbol
+	latencies      map[string][]time.Duration
+	delayed        map[string][]delayedSnapshot
+	rateWindow     time.Duration
+	maxLifetime    time.Duration // Oldest a resting order may get, 0 for no limit
+	retention      time.Duration // How long terminal orders stay indexed, 0 for no limit
+	dataDelay      time.Duration // How far the delayed market-data tier runs behind
+	costHorizon    time.Duration // How long after a trade its realized spread is measured
+	resumeCheck    bool          // Cancel out-of-band resting orders on resume
+	firstTradeRef  bool          // Take the first trade's price as an unseeded reference
+	sessions       map[string]SessionPhase
+	conditionals   map[string][]*ConditionalOrder // Pending cross-symbol orders by reference symbol
+	tape           tape
+	fees           FeeSchedule
+	fxRates        FXRateSource
+	matchingMode   MatchingMode
+	stpMode        STPMode
+	settlement     SettlementPolicy
+	funds          FundsChecker
+	hiddenPriority HiddenPriority
+	eventHandlers  []func(Event)
+	queue          *asyncQueue // Orders awaiting async matching, nil when synchronous
+	queueMutex     sync.Mutex
+	throttle       *matchThrottle // Match rate limiter, nil when unlimited
+	replaceMutex   sync.Mutex
+	subscribers    map[string][]bookListener // Book update subscribers by symbol
+	tradeSubs      map[string][]*TradeSubscription
+	sequentialRefs bool
+	refCounters    map[string]uint64
+	tradeCap       int                        // Most trades one submission may make, 0 for no cap
+	parked         map[string][]*models.Order // Limit remainders stopped by the trade cap, by symbol
+	stopOrders     map[string][]*models.Order // Dormant stop orders by symbol
+	loadShedding   LoadSheddingConfig
+	sanity         SanityLimits
+	ids            models.IDGenerator
+	tolerance      models.Tolerance // Fixed at creation, as books key their levels by it
+	clock          clock.Clock
+	mutex          sync.RWMutex
 }
 
-// NewMatchingEngine creates a new matching engine
+// NewMatchingEngine creates a new matching engine using the default
+// tolerance
 func NewMatchingEngine() *MatchingEngine {
+	return NewMatchingEngineWithTolerance(models.DefaultTolerance)
+}
+
+// NewMatchingEngineWithTolerance creates a new matching engine that treats
+// quantities and prices within tolerance as equal, in its books and when
+// matching. A margin that isn't positive takes its default.
+func NewMatchingEngineWithTolerance(tolerance models.Tolerance) *MatchingEngine {
 	return &MatchingEngine{
-		orderBooks: make(map[string]*orderbook.OrderBook),
-		trades:     make([]*models.Trade, 0),
+		orderBooks:    make(map[string]*orderbook.OrderBook),
+		symbolConfigs: make(map[string]SymbolConfig),
+		symbolAliases: make(map[string]string),
+		accountOrders: make(map[string]map[uuid.UUID]*models.Order),
+		orderIndex:    make(map[uuid.UUID]*models.Order),
+		positions:     make(map[string]map[string]*Position),
+		trades:        newTradeLog(DefaultTradeHistory),
+		tape:          newTape(DefaultTradeHistory),
+		spreads:       make(map[string][]SpreadPoint),
+		bbos:          make(map[string]orderbook.BBO),
+		levelChanges:  make(map[string][]levelChange),
+		icebergConfig: DefaultIcebergDetection,
+		delayed:       make(map[string][]delayedSnapshot),
+		halted:        make(map[string]bool),
+		pegged:        make(map[string]map[uuid.UUID]*models.Order),
+		paused:        make(map[string]*matchingPause),
+		auctions:      make(map[string]*volatilityAuction),
+		conditionals:  make(map[string][]*ConditionalOrder),
+		references:    make(map[string]float64),
+		established:   make(map[string]bool),
+		sessions:      make(map[string]SessionPhase),
+		activity:      make(map[string]*activity),
+		latencies:     make(map[string][]time.Duration),
+		rateWindow:    DefaultRateWindow,
+		costHorizon:   DefaultRealizedSpreadHorizon,
+		retention:     DefaultOrderRetention,
+		refCounters:   make(map[string]uint64),
+		parked:        make(map[string][]*models.Order),
+		stopOrders:    make(map[string][]*models.Order),
+		subscribers:   make(map[string][]bookListener),
+		tradeSubs:     make(map[string][]*TradeSubscription),
+		sanity:        DefaultSanityLimits,
+		matchingMode:  MatchingModeFIFO,
+		stpMode:       STPCancelResting,
+		settlement:    SettlementPreReserve,
+		ids:           models.UUIDGenerator{},
+		tolerance:     tolerance.OrDefault(),
+		clock:         clock.Real{},
 	}
 }
 
+// Tolerance returns the margins within which the engine treats quantities
+// and prices as equal
+func (me *MatchingEngine) Tolerance() models.Tolerance {
+	return me.tolerance
+}
+
+// SetIDGenerator replaces the generator used for order and trade IDs
+func (me *MatchingEngine) SetIDGenerator(ids models.IDGenerator) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.ids = ids
+}
+
+// NewOrder creates an order with an ID from the engine's generator
+func (me *MatchingEngine) NewOrder(symbol string, orderType models.OrderType, side models.OrderSide, quantity, price float64) *models.Order {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	order := models.NewOrder(symbol, orderType, side, quantity, price)
+	order.ID = me.ids.NewID()
+	order.SubmittedAt = me.clock.Now()
+	return order
+}
+
+// SetSequentialRefs enables human-readable per-symbol references such as
+// AAPL-000123 on orders and trades, alongside their UUIDs
+func (me *MatchingEngine) SetSequentialRefs(enabled bool) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.sequentialRefs = enabled
+}
+
+// nextRef returns the next reference in a symbol's sequence, or "" when
+// sequential references are disabled. Orders and trades share a sequence so
+// a reference is unique within its symbol. The caller must hold the engine
+// mutex.
+func (me *MatchingEngine) nextRef(symbol string) string {
+	if !me.sequentialRefs {
+		return ""
+	}
+
+	me.refCounters[symbol]++
+	return fmt.Sprintf("%s-%06d", symbol, me.refCounters[symbol])
+}
+
+// newTrade creates a trade between an incoming order and a resting order
+// with an ID and timestamp from the engine
+func (me *MatchingEngine) newTrade(incoming, resting *models.Order, price, quantity float64) *models.Trade {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	buyOrder, sellOrder := incoming, resting
+	if incoming.Side == models.OrderSideSell {
+		buyOrder, sellOrder = resting, incoming
+	}
+
+	trade := models.NewTrade(incoming.Symbol, buyOrder.ID, sellOrder.ID, price, quantity)
+	trade.ID = me.ids.NewID()
+	trade.Timestamp = me.clock.Now()
+	trade.BuyAccountID, trade.SellAccountID = buyOrder.AccountID, sellOrder.AccountID
+	trade.Hidden = buyOrder.Hidden || sellOrder.Hidden
+	if roundLot := me.symbolConfigs[trade.Symbol].OddLots.RoundLot; roundLot > 0 && quantity < roundLot {
+		trade.OddLot = true
+	}
+	trade.TakerSide = incoming.Side
+	trade.MakerFee, trade.TakerFee = me.fees.fees(price * quantity)
+	trade.Ref = me.nextRef(trade.Symbol)
+	return trade
+}
+
+// SetClock replaces the clock used by the engine and all of its order books
+func (me *MatchingEngine) SetClock(c clock.Clock) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.clock = c
+	for _, ob := range me.orderBooks {
+		ob.SetClock(c)
+	}
+}
+
+// SetSymbolConfig sets the trading parameters for a symbol
+func (me *MatchingEngine) SetSymbolConfig(symbol string, config SymbolConfig) {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.symbolConfigs[symbol] = config
+}
+
+// GetSymbolConfig returns the trading parameters for a symbol
+func (me *MatchingEngine) GetSymbolConfig(symbol string) SymbolConfig {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	return me.symbolConfigs[symbol]
+}
+
 // GetOrCreateOrderBook gets or creates an order book for a symbol
 func (me *MatchingEngine) GetOrCreateOrderBook(symbol string) *orderbook.OrderBook {
+	symbol = me.NormalizeSymbol(symbol)
+
 	me.mutex.Lock()
 	defer me.mutex.Unlock()
 
@@ -32,13 +280,16 @@ func (me *MatchingEngine) GetOrCreateOrderBook(symbol string) *orderbook.OrderBo
 		return ob
 	}
 
-	ob := orderbook.NewOrderBook(symbol)
+	ob := orderbook.NewOrderBookWithTolerance(symbol, me.tolerance)
+	ob.SetClock(me.clock)
 	me.orderBooks[symbol] = ob
 	return ob
 }
 
 // GetOrderBook retrieves an order book for a symbol
 func (me *MatchingEngine) GetOrderBook(symbol string) *orderbook.OrderBook {
+	symbol = me.NormalizeSymbol(symbol)
+
 	me.mutex.RLock()
 	defer me.mutex.RUnlock()
 
@@ -47,35 +298,244 @@ func (me *MatchingEngine) GetOrderBook(symbol string) *orderbook.OrderBook {
 
 // SubmitOrder submits an order to the matching engine
 func (me *MatchingEngine) SubmitOrder(order *models.Order) []*models.Trade {
+	if me.enqueue(order) || me.throttled(order) {
+		return nil
+	}
+	return me.submitOrder(order, nil)
+}
+
+// submitOrder matches an order, recording each decision in trace if non-nil
+func (me *MatchingEngine) submitOrder(order *models.Order, trace *MatchTrace) []*models.Trade {
+	// Reject nonsensical values before anything else looks at the order
+	if reason := me.checkSanity(order); reason != "" {
+		order.Reject(reason)
+		return nil
+	}
+
+	order.Symbol = me.NormalizeSymbol(order.Symbol)
+	me.runDueAuction(order.Symbol)
+
+	if me.IsHalted(order.Symbol) {
+		order.Reject("trading is halted")
+		return nil
+	}
+
+	if reason := me.checkSession(order); reason != "" {
+		order.Reject(reason)
+		return nil
+	}
+
+	if reason := me.convertCurrency(order); reason != "" {
+		order.Reject(reason)
+		return nil
+	}
+
+	if order.Peg != "" {
+		if reason := me.pricePeg(order); reason != "" {
+			order.Reject(reason)
+			return nil
+		}
+	}
+
+	if maxPrice := me.GetSymbolConfig(order.Symbol).MaxPrice; maxPrice > 0 && order.Price > maxPrice {
+		order.Reject(fmt.Sprintf("price %g exceeds the maximum of %g for %s", order.Price, maxPrice, order.Symbol))
+		return nil
+	}
+
+	// Stop orders wait out of the book until the market reaches them
+	if isStop(order) && me.holdStop(order) {
+		return nil
+	}
+
+	// A maintenance pause holds back orders that would trade
+	if me.holdPaused(order) {
+		return nil
+	}
+
+	// Orders parked by the trade cap carry on ahead of anything newer
+	me.continueParked(order.Symbol)
+
+	me.mutex.Lock()
+	order.Ref = me.nextRef(order.Symbol)
+	me.orderIndex[order.ID] = order
+	me.mutex.Unlock()
+
 	ob := me.GetOrCreateOrderBook(order.Symbol)
 
-	var trades []*models.Trade
+	if me.shedOrder(ob, order) {
+		order.Reject("order book is under load and the order is too far from the touch")
+		return nil
+	}
+
+	// Check the minimum fill up front so nothing prints if it can't be met
+	if order.MinFillQuantity > 0 {
+		limitPrice := 0.0
+		if order.Type != models.OrderTypeMarket {
+			limitPrice = order.Price
+		}
+		if ob.AvailableQuantity(order.Side, limitPrice) < order.MinFillQuantity {
+			order.Cancel(me.clock.Now())
+			me.recordLatency(order)
+			return nil
+		}
+	}
 
-	// Handle different order types
-	switch order.Type {
-	case models.OrderTypeMarket:
-		trades = me.matchMarketOrder(ob, order)
-	case models.OrderTypeLimit:
-		trades = me.matchLimitOrder(ob, order)
-	case models.OrderTypeStopLoss:
-		// Stop-loss orders become market orders when triggered
-		// For now, we'll treat them as limit orders at the stop price
-		order.Type = models.OrderTypeLimit
-		trades = me.matchLimitOrder(ob, order)
+	if order.PostOnly {
+		if reason := me.checkPostOnly(ob, order); reason != "" {
+			order.Reject(reason)
+			return nil
+		}
+	}
+
+	if reason := me.checkMarketable(order); reason != "" {
+		order.Reject(reason)
+		return nil
 	}
 
+	return me.execute(ob, order, trace, false)
+}
+
+// execute matches an accepted order against its book and records the
+// outcome: stored trades, indices, events and everything driven by them. A
+// resumed order was already counted as an order when it was submitted, so
+// only its new trades are counted.
+func (me *MatchingEngine) execute(ob *orderbook.OrderBook, order *models.Order, trace *MatchTrace, resumed bool) []*models.Trade {
+	var trades []*models.Trade
+	mode := me.GetMatchingMode()
+
+	// Handle different order types. A book in a volatility auction only
+	// collects orders until it uncrosses.
+	switch {
+	case me.InVolatilityAuction(order.Symbol):
+		me.collectAuctionOrder(ob, order, trace)
+	case order.Type == models.OrderTypeMarket:
+		trades = me.matchMarketOrder(ob, order, mode, trace)
+	case order.Type == models.OrderTypeLimit:
+		trades = me.matchLimitOrder(ob, order, mode, trace)
+	}
+
+	me.applyDepthSurcharge(trades)
+
+	me.mutex.Lock()
 	// Store trades
 	if len(trades) > 0 {
-		me.mutex.Lock()
-		me.trades = append(me.trades, trades...)
-		me.mutex.Unlock()
+		me.trades.add(trades...)
+		me.printTrades(trades)
+	}
+	if resumed {
+		me.recordTradeActivity(order.Symbol, trades)
+	} else {
+		me.recordActivity(order, trades)
 	}
+	me.recordSubmission(ob, order, trades)
 
+	// Index resting orders by account
+	if order.AccountID != "" && order.IsActive() {
+		if _, resting := ob.GetOrder(order.ID); resting {
+			if me.accountOrders[order.AccountID] == nil {
+				me.accountOrders[order.AccountID] = make(map[uuid.UUID]*models.Order)
+			}
+			me.accountOrders[order.AccountID][order.ID] = order
+		}
+	}
+	me.mutex.Unlock()
+
+	if !order.IsActive() {
+		me.recordLatency(order)
+	}
+	// An auction book may stay crossed until it uncrosses
+	if !me.InVolatilityAuction(order.Symbol) {
+		me.checkSpread(ob)
+		me.checkImbalance(ob)
+	}
+	me.repricePegs(ob, order)
+	me.bookChanged(order.Symbol)
+	for _, trade := range trades {
+		me.emit(Event{Type: EventTrade, Symbol: trade.Symbol, OrderID: order.ID, Trade: trade})
+	}
+	me.establishReference(order.Symbol, trades)
+	if len(trades) > 0 {
+		me.checkBBO(order.Symbol, BBOCauseTrade)
+	} else {
+		me.checkBBO(order.Symbol, BBOCauseNewOrder)
+	}
+	if len(trades) > 0 {
+		me.fireConditionals(order.Symbol, ob.LastPrice)
+		me.fireStops(order.Symbol, ob.LastPrice)
+	}
 	return trades
 }
 
+// CancelOrder cancels a resting order, or one still waiting out of the book:
+// parked by the trade cap, a dormant stop, a conditional order, or one held
+// by the match rate or a maintenance pause. It returns false if there is no
+// such live order.
+func (me *MatchingEngine) CancelOrder(symbol string, orderID uuid.UUID) bool {
+	if me.cancelParked(symbol, orderID) || me.cancelStop(symbol, orderID) || me.cancelConditional(symbol, orderID) ||
+		me.cancelThrottled(symbol, orderID) || me.cancelQueued(symbol, orderID) {
+		return true
+	}
+
+	ob := me.GetOrderBook(symbol)
+	if ob == nil {
+		return false
+	}
+
+	order, exists := ob.GetOrder(orderID)
+	if !exists || !order.IsActive() {
+		return false
+	}
+
+	if !ob.RemoveOrder(orderID) {
+		return false
+	}
+	order.Cancel(me.clock.Now())
+	me.recordLatency(order)
+
+	me.mutex.Lock()
+	if orders, exists := me.accountOrders[order.AccountID]; exists {
+		delete(orders, orderID)
+	}
+	if !order.Hidden {
+		me.recordLevelChange(order.Symbol, order.Side, order.Price, orderbook.ChangeCancel)
+	}
+	me.mutex.Unlock()
+
+	me.repricePegs(ob, nil)
+	me.bookChanged(order.Symbol)
+	me.emit(Event{Type: EventOrderCancelled, Symbol: order.Symbol, OrderID: orderID})
+	me.checkBBO(order.Symbol, BBOCauseCancel)
+	return true
+}
+
+// CancelByClientIDPrefix cancels all of an account's resting orders whose
+// client order ID starts with prefix, returning the number cancelled
+func (me *MatchingEngine) CancelByClientIDPrefix(accountID, prefix string) int {
+	me.mutex.Lock()
+	matches := make([]*models.Order, 0)
+	for id, order := range me.accountOrders[accountID] {
+		// Drop orders that have since been filled
+		if !order.IsActive() {
+			delete(me.accountOrders[accountID], id)
+			continue
+		}
+		if strings.HasPrefix(order.ClientOrderID, prefix) {
+			matches = append(matches, order)
+		}
+	}
+	me.mutex.Unlock()
+
+	cancelled := 0
+	for _, order := range matches {
+		if me.CancelOrder(order.Symbol, order.ID) {
+			cancelled++
+		}
+	}
+	return cancelled
+}
+
 // matchMarketOrder matches a market order immediately at best available prices
-func (me *MatchingEngine) matchMarketOrder(ob *orderbook.OrderBook, order *models.Order) []*models.Trade {
+func (me *MatchingEngine) matchMarketOrder(ob *orderbook.OrderBook, order *models.Order, mode MatchingMode, trace *MatchTrace) []*models.Trade {
 	trades := make([]*models.Trade, 0)
 
 	var oppositeHeap *orderbook.PriceLevelHeap
@@ -85,65 +545,73 @@ func (me *MatchingEngine) matchMarketOrder(ob *orderbook.OrderBook, order *model
 		oppositeHeap = ob.Bids
 	}
 
+	reference := me.referencePrice(ob)
+	maxSlippage := me.GetSymbolConfig(ob.Symbol).MaxSlippage
+	slippageLimit := 0.0
+	maxTrades := me.GetMaxTradesPerOrder()
+	unsettled := make(map[uuid.UUID]bool)
+
 	// Match against all available opposite orders until filled
-	for order.RemainingQuantity() > 0 && oppositeHeap.Len() > 0 {
-		bestLevel := oppositeHeap.Peek()
+	for order.RemainingQuantity(me.tolerance.Quantity) > 0 && order.IsActive() {
+		bestLevel := me.nextLevel(ob, oppositeHeap, trace)
 		if bestLevel == nil {
 			break
 		}
-		if len(bestLevel.Orders) == 0 {
-			heap.Pop(oppositeHeap)
-			continue
-		}
 
-		// Match with orders at this price level (FIFO - time priority)
-		for len(bestLevel.Orders) > 0 && order.RemainingQuantity() > 0 {
-			oppositeOrder := bestLevel.Orders[0]
-
-			// Calculate trade quantity
-			tradeQty := min(order.RemainingQuantity(), oppositeOrder.RemainingQuantity())
-			tradePrice := oppositeOrder.Price
-
-			// Create trade
-			var trade *models.Trade
-			if order.Side == models.OrderSideBuy {
-				trade = models.NewTrade(order.Symbol, order.ID, oppositeOrder.ID, tradePrice, tradeQty)
-			} else {
-				trade = models.NewTrade(order.Symbol, oppositeOrder.ID, order.ID, tradePrice, tradeQty)
+		// Halt or auction instead of printing outside the price band
+		if me.breachesBand(reference, bestLevel.Price) {
+			trace.record(TraceStep{Action: TraceHalt, Price: bestLevel.Price})
+			if me.startAuction(ob.Symbol, reference) {
+				order.Warn(fmt.Sprintf("volatility auction started before %g with %g unfilled", bestLevel.Price, order.RemainingQuantity(me.tolerance.Quantity)))
+				order.CancelRemainder(me.clock.Now(), "market orders cannot join a volatility auction")
+				return trades
 			}
+			me.haltSymbol(ob.Symbol)
+			order.Warn(fmt.Sprintf("collared at the price band before %g; trading halted with %g unfilled", bestLevel.Price, order.RemainingQuantity(me.tolerance.Quantity)))
+			order.CancelRemainder(me.clock.Now(), "collared at the price band")
+			return trades
+		}
 
-			// Fill both orders
-			order.Fill(tradeQty, tradePrice)
-			oppositeOrder.Fill(tradeQty, tradePrice)
-
-			// Update last price
-			ob.LastPrice = tradePrice
-			ob.LastTrade = trade
-
-			trades = append(trades, trade)
-
-			// If opposite order is filled, remove it from the book
-			if oppositeOrder.IsFilled() {
-				bestLevel.Orders = bestLevel.Orders[1:]
+		// Stop sweeping once the price has slipped too far from the touch
+		if maxSlippage > 0 {
+			if slippageLimit == 0 {
+				slippageLimit = sweepLimit(order.Side, bestLevel.Price, maxSlippage)
 			}
-
-			// If incoming order is filled, stop matching at this level
-			if order.IsFilled() {
-				break
+			if beyondSweep(order.Side, bestLevel.Price, slippageLimit) {
+				order.CancelRemainder(me.clock.Now(), fmt.Sprintf("slippage cap of %g reached at %g", slippageLimit, bestLevel.Price))
+				return trades
 			}
 		}
 
-		// If price level is empty, remove it
-		if len(bestLevel.Orders) == 0 {
-			heap.Pop(oppositeHeap)
+		if capReached(maxTrades, trades) {
+			order.Warn(fmt.Sprintf("trade cap of %d reached with %g unfilled", maxTrades, order.RemainingQuantity(me.tolerance.Quantity)))
+			order.CancelRemainder(me.clock.Now(), "trade cap reached")
+			return trades
+		}
+
+		if unsettledLevel(bestLevel, unsettled) {
+			order.Warn(fmt.Sprintf("resting orders at %g could not settle; %g unfilled was cancelled", bestLevel.Price, order.RemainingQuantity(me.tolerance.Quantity)))
+			order.CancelRemainder(me.clock.Now(), unbackedReason)
+			return trades
 		}
+
+		// Match with orders at this price level
+		before := order.RemainingQuantity(me.tolerance.Quantity)
+		trades = append(trades, me.matchLevel(ob, order, bestLevel, mode, tradesLeft(maxTrades, trades), unsettled)...)
+		trace.record(TraceStep{Action: TraceMatch, Price: bestLevel.Price, Quantity: before - order.RemainingQuantity(me.tolerance.Quantity)})
+
+		me.dropIfEmpty(oppositeHeap, bestLevel, trace)
 	}
 
+	if order.RemainingQuantity(me.tolerance.Quantity) > 0 && order.IsActive() {
+		order.Warn(fmt.Sprintf("book exhausted with %g unfilled", order.RemainingQuantity(me.tolerance.Quantity)))
+		order.CancelRemainder(me.clock.Now(), "no liquidity")
+	}
 	return trades
 }
 
 // matchLimitOrder matches a limit order, adding remainder to order book if not fully filled
-func (me *MatchingEngine) matchLimitOrder(ob *orderbook.OrderBook, order *models.Order) []*models.Trade {
+func (me *MatchingEngine) matchLimitOrder(ob *orderbook.OrderBook, order *models.Order, mode MatchingMode, trace *MatchTrace) []*models.Trade {
 	trades := make([]*models.Trade, 0)
 
 	var oppositeHeap *orderbook.PriceLevelHeap
@@ -153,84 +621,163 @@ func (me *MatchingEngine) matchLimitOrder(ob *orderbook.OrderBook, order *models
 		oppositeHeap = ob.Bids
 	}
 
+	reference := me.referencePrice(ob)
+	maxTrades := me.GetMaxTradesPerOrder()
+	maxSweep := me.GetSymbolConfig(ob.Symbol).MaxSweep
+	limit, swept := 0.0, 0.0
+	halted, capped, blocked := false, false, false
+	unsettled := make(map[uuid.UUID]bool)
+
 	// Match against opposite orders while price is acceptable
-	for order.RemainingQuantity() > 0 && oppositeHeap.Len() > 0 {
-		bestLevel := oppositeHeap.Peek()
-		if bestLevel == nil || len(bestLevel.Orders) == 0 {
+	for order.RemainingQuantity(me.tolerance.Quantity) > 0 && order.IsActive() {
+		bestLevel := me.nextLevel(ob, oppositeHeap, trace)
+		if bestLevel == nil {
 			break
 		}
 
 		// Check if price is acceptable
-		if order.Side == models.OrderSideBuy && bestLevel.Price > order.Price {
-			break // Ask price too high
+		acceptable := true
+		if order.Side == models.OrderSideBuy && bestLevel.Price > order.Price && !me.tolerance.PricesEqual(bestLevel.Price, order.Price) {
+			acceptable = false // Ask price too high
 		}
-		if order.Side == models.OrderSideSell && bestLevel.Price < order.Price {
-			break // Bid price too low
+		if order.Side == models.OrderSideSell && bestLevel.Price < order.Price && !me.tolerance.PricesEqual(bestLevel.Price, order.Price) {
+			acceptable = false // Bid price too low
+		}
+		trace.record(TraceStep{Action: TracePriceCheck, Price: bestLevel.Price, Accepted: acceptable})
+		if !acceptable {
+			break
 		}
 
-		// Match with orders at this price level (FIFO - time priority)
-		for len(bestLevel.Orders) > 0 && order.RemainingQuantity() > 0 {
-			oppositeOrder := bestLevel.Orders[0]
-
-			// Calculate trade quantity
-			tradeQty := min(order.RemainingQuantity(), oppositeOrder.RemainingQuantity())
-			tradePrice := oppositeOrder.Price
+		// Halt or auction instead of printing outside the price band. In an
+		// auction the remainder rests and joins the uncross.
+		if me.breachesBand(reference, bestLevel.Price) {
+			trace.record(TraceStep{Action: TraceHalt, Price: bestLevel.Price})
+			if me.startAuction(ob.Symbol, reference) {
+				order.Warn(fmt.Sprintf("volatility auction started before %g; %g unfilled joins the auction", bestLevel.Price, order.RemainingQuantity(me.tolerance.Quantity)))
+				break
+			}
+			me.haltSymbol(ob.Symbol)
+			halted = true
+			break
+		}
 
-			// Create trade
-			var trade *models.Trade
-			if order.Side == models.OrderSideBuy {
-				trade = models.NewTrade(order.Symbol, order.ID, oppositeOrder.ID, tradePrice, tradeQty)
-			} else {
-				trade = models.NewTrade(order.Symbol, oppositeOrder.ID, order.ID, tradePrice, tradeQty)
+		// Stop sweeping once the price has moved too far from the touch
+		if maxSweep > 0 {
+			if limit == 0 {
+				limit = sweepLimit(order.Side, bestLevel.Price, maxSweep)
+			}
+			if beyondSweep(order.Side, bestLevel.Price, limit) {
+				swept = bestLevel.Price
+				break
 			}
+		}
+
+		if capReached(maxTrades, trades) {
+			capped = true
+			break
+		}
 
-			// Fill both orders
-			order.Fill(tradeQty, tradePrice)
-			oppositeOrder.Fill(tradeQty, tradePrice)
+		if unsettledLevel(bestLevel, unsettled) {
+			blocked = true
+			break
+		}
 
-			// Update last price
-			ob.LastPrice = tradePrice
-			ob.LastTrade = trade
+		// Match with orders at this price level
+		before := order.RemainingQuantity(me.tolerance.Quantity)
+		trades = append(trades, me.matchLevel(ob, order, bestLevel, mode, tradesLeft(maxTrades, trades), unsettled)...)
+		trace.record(TraceStep{Action: TraceMatch, Price: bestLevel.Price, Quantity: before - order.RemainingQuantity(me.tolerance.Quantity)})
 
-			trades = append(trades, trade)
+		me.dropIfEmpty(oppositeHeap, bestLevel, trace)
+	}
 
-			// If opposite order is filled, remove it
-			if oppositeOrder.IsFilled() {
-				bestLevel.Orders = bestLevel.Orders[1:]
-			}
+	// If order is not fully filled, add remainder to order book. A remainder
+	// that tripped the circuit breaker is cancelled so the book is not left
+	// crossed while halted, and one stopped by the trade cap is parked for
+	// the same reason, as is one held up by resting orders that could not
+	// settle. One stopped by the sweep cap is cancelled, as it would rest
+	// through the book. One cancelled by self-trade prevention never rests,
+	// and nor does an immediate-or-cancel remainder.
+	if order.RemainingQuantity(me.tolerance.Quantity) > 0 && order.IsActive() {
+		switch {
+		case halted:
+			order.CancelRemainder(me.clock.Now(), "collared at the price band")
+			order.Warn(fmt.Sprintf("collared at the price band; trading halted and %g unfilled was cancelled", order.RemainingQuantity(me.tolerance.Quantity)))
+		case swept > 0:
+			order.Warn(fmt.Sprintf("sweep cap of %g reached before %g; %g unfilled was cancelled", limit, swept, order.RemainingQuantity(me.tolerance.Quantity)))
+			order.CancelRemainder(me.clock.Now(), fmt.Sprintf("sweep cap of %g reached at %g", limit, swept))
+		case order.TimeInForce == models.TimeInForceIOC:
+			order.CancelRemainder(me.clock.Now(), "immediate-or-cancel remainder")
+		case capped:
+			order.Warn(fmt.Sprintf("trade cap of %d reached; %g unfilled continues on the next submission", maxTrades, order.RemainingQuantity(me.tolerance.Quantity)))
+			me.park(order)
+		case blocked:
+			order.Warn(fmt.Sprintf("resting orders could not settle; %g unfilled continues on the next submission", order.RemainingQuantity(me.tolerance.Quantity)))
+			me.park(order)
+		default:
+			ob.AddOrder(order)
+			trace.record(TraceStep{Action: TraceRest, Price: order.Price, Quantity: order.RemainingQuantity(me.tolerance.Quantity)})
 		}
+	}
 
-		// If price level is empty, remove it
-		if len(bestLevel.Orders) == 0 {
-			heap.Pop(oppositeHeap)
+	return trades
+}
+
+// nextLevel returns the best level on a side that still has live orders,
+// popping any exhausted levels in front of it, or nil if the side is empty
+func (me *MatchingEngine) nextLevel(ob *orderbook.OrderBook, h *orderbook.PriceLevelHeap, trace *MatchTrace) *orderbook.PriceLevel {
+	for h.Len() > 0 {
+		level := h.Peek()
+		trace.record(TraceStep{Action: TracePeek, Price: level.Price})
+		if !ob.PruneLevel(level) {
+			return level
 		}
+		heap.Pop(h)
+		trace.record(TraceStep{Action: TracePop, Price: level.Price})
 	}
+	return nil
+}
 
-	// If order is not fully filled, add remainder to order book
-	if order.RemainingQuantity() > 0 {
-		ob.AddOrder(order)
+// dropIfEmpty pops a level that matching has just emptied. The level must
+// still be at the top of the heap.
+func (me *MatchingEngine) dropIfEmpty(h *orderbook.PriceLevelHeap, level *orderbook.PriceLevel, trace *MatchTrace) {
+	if len(level.Orders) == 0 && h.Peek() == level {
+		heap.Pop(h)
+		trace.record(TraceStep{Action: TracePop, Price: level.Price})
 	}
-
-	return trades
 }
 
-// GetRecentTrades returns recent trades for a symbol
-func (me *MatchingEngine) GetRecentTrades(symbol string, limit int) []*models.Trade {
+// ValidateState checks every order book's invariants and that no order ID
+// is shared between books, e.g. after restoring or replaying state
+func (me *MatchingEngine) ValidateState() []error {
 	me.mutex.RLock()
 	defer me.mutex.RUnlock()
 
-	result := make([]*models.Trade, 0)
-	count := 0
+	errs := make([]error, 0)
+	owners := make(map[uuid.UUID]string)
+
+	for symbol, ob := range me.orderBooks {
+		errs = append(errs, ob.Validate()...)
 
-	// Iterate from most recent
-	for i := len(me.trades) - 1; i >= 0 && count < limit; i-- {
-		if me.trades[i].Symbol == symbol {
-			result = append(result, me.trades[i])
-			count++
+		for _, id := range ob.OrderIDs() {
+			if other, exists := owners[id]; exists {
+				errs = append(errs, fmt.Errorf("order %s is present in both %s and %s", id, other, symbol))
+				continue
+			}
+			owners[id] = symbol
 		}
 	}
 
-	return result
+	return errs
+}
+
+// GetRecentTrades returns recent trades for a symbol
+func (me *MatchingEngine) GetRecentTrades(symbol string, limit int) []*models.Trade {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	return me.trades.recent(symbol, limit)
 }
 
 // Helper function to get minimum of two floats
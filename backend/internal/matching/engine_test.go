@@ -1,9 +1,13 @@
 package matching
 
 import (
+	"encoding/binary"
+	"strings"
 	"testing"
 
 	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/google/uuid"
 )
 
 func TestNewMatchingEngine(t *testing.T) {
@@ -66,11 +70,11 @@ func TestMatchLimitOrders(t *testing.T) {
 	}
 
 	// Check that both orders are filled
-	if !buyOrder.IsFilled() {
+	if !buyOrder.IsFilled(models.DefaultQuantityEpsilon) {
 		t.Error("Buy order should be filled")
 	}
 
-	if !sellOrder.IsFilled() {
+	if !sellOrder.IsFilled(models.DefaultQuantityEpsilon) {
 		t.Error("Sell order should be filled")
 	}
 }
@@ -94,16 +98,16 @@ func TestPartialFill(t *testing.T) {
 		t.Errorf("Expected trade quantity 50, got %f", trades[0].Quantity)
 	}
 
-	if !buyOrder.IsFilled() {
+	if !buyOrder.IsFilled(models.DefaultQuantityEpsilon) {
 		t.Error("Buy order should be fully filled")
 	}
 
-	if sellOrder.IsFilled() {
+	if sellOrder.IsFilled(models.DefaultQuantityEpsilon) {
 		t.Error("Sell order should be partially filled")
 	}
 
-	if sellOrder.RemainingQuantity() != 50 {
-		t.Errorf("Expected remaining quantity 50, got %f", sellOrder.RemainingQuantity())
+	if sellOrder.RemainingQuantity(models.DefaultQuantityEpsilon) != 50 {
+		t.Errorf("Expected remaining quantity 50, got %f", sellOrder.RemainingQuantity(models.DefaultQuantityEpsilon))
 	}
 }
 
@@ -132,7 +136,7 @@ func TestMarketOrder(t *testing.T) {
 		t.Errorf("Expected second trade at 151.0, got %f", trades[1].Price)
 	}
 
-	if !marketOrder.IsFilled() {
+	if !marketOrder.IsFilled(models.DefaultQuantityEpsilon) {
 		t.Error("Market order should be fully filled")
 	}
 }
@@ -156,12 +160,12 @@ func TestPriceTimePriority(t *testing.T) {
 	}
 
 	// First order should be filled
-	if !sellOrder1.IsFilled() {
+	if !sellOrder1.IsFilled(models.DefaultQuantityEpsilon) {
 		t.Error("First sell order should be filled (time priority)")
 	}
 
 	// Second order should not be filled
-	if sellOrder2.IsFilled() {
+	if sellOrder2.IsFilled(models.DefaultQuantityEpsilon) {
 		t.Error("Second sell order should not be filled")
 	}
 }
@@ -220,3 +224,501 @@ func TestEmptyOrderBook(t *testing.T) {
 		t.Error("Expected nil for non-existent order book")
 	}
 }
+
+func TestCancelByClientIDPrefix(t *testing.T) {
+	me := NewMatchingEngine()
+
+	newOrder := func(accountID, clientOrderID string, price float64) *models.Order {
+		order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, price)
+		order.AccountID = accountID
+		order.ClientOrderID = clientOrderID
+		me.SubmitOrder(order)
+		return order
+	}
+
+	strat7a := newOrder("acct1", "strat7-001", 150.0)
+	strat7b := newOrder("acct1", "strat7-002", 149.0)
+	strat8 := newOrder("acct1", "strat8-001", 148.0)
+	otherAccount := newOrder("acct2", "strat7-001", 147.0)
+
+	cancelled := me.CancelByClientIDPrefix("acct1", "strat7-")
+	if cancelled != 2 {
+		t.Fatalf("Expected 2 orders cancelled, got %d", cancelled)
+	}
+
+	for _, order := range []*models.Order{strat7a, strat7b} {
+		if order.Status != models.OrderStatusCancelled {
+			t.Errorf("Expected order %s to be cancelled, got %s", order.ClientOrderID, order.Status)
+		}
+	}
+
+	ob := me.GetOrderBook("AAPL")
+	for _, order := range []*models.Order{strat8, otherAccount} {
+		if order.Status != models.OrderStatusPending {
+			t.Errorf("Expected order %s/%s to stay pending, got %s", order.AccountID, order.ClientOrderID, order.Status)
+		}
+		if _, resting := ob.GetOrder(order.ID); !resting {
+			t.Errorf("Expected order %s/%s to still rest on the book", order.AccountID, order.ClientOrderID)
+		}
+	}
+
+	if ob.GetBestBid() != 148.0 {
+		t.Errorf("Expected best bid 148.0 after cancels, got %f", ob.GetBestBid())
+	}
+}
+
+func TestExactFillsLeaveNoPhantomOrders(t *testing.T) {
+	me := NewMatchingEngine()
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 30, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 30, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 40, 151.0))
+
+	// Consume the book exactly in several pieces
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 20, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 30, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 40, 0))
+
+	ob := me.GetOrderBook("AAPL")
+	if ob.Asks.Len() != 0 || ob.Bids.Len() != 0 {
+		t.Errorf("Expected empty book, got %d bid and %d ask levels", ob.Bids.Len(), ob.Asks.Len())
+	}
+
+	if ob.OrderCount() != 0 {
+		t.Errorf("Expected no tracked orders, got %d", ob.OrderCount())
+	}
+
+	snapshot := ob.Snapshot()
+	if len(snapshot.Asks) != 0 {
+		t.Errorf("Expected no ask levels in snapshot, got %d", len(snapshot.Asks))
+	}
+}
+
+// sequentialIDs hands out predictable IDs for tests
+type sequentialIDs struct {
+	next uint32
+}
+
+func (s *sequentialIDs) NewID() uuid.UUID {
+	s.next++
+	var id uuid.UUID
+	binary.BigEndian.PutUint32(id[12:], s.next)
+	return id
+}
+
+func TestDeterministicIDs(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetIDGenerator(&sequentialIDs{})
+
+	sellOrder := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0)
+	buyOrder := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+
+	me.SubmitOrder(sellOrder)
+	trades := me.SubmitOrder(buyOrder)
+
+	if len(trades) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(trades))
+	}
+
+	expected := map[string]uuid.UUID{
+		"sell order": uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		"buy order":  uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+		"trade":      uuid.MustParse("00000000-0000-0000-0000-000000000003"),
+	}
+	actual := map[string]uuid.UUID{
+		"sell order": sellOrder.ID,
+		"buy order":  buyOrder.ID,
+		"trade":      trades[0].ID,
+	}
+
+	for name, id := range expected {
+		if actual[name] != id {
+			t.Errorf("Expected %s ID %s, got %s", name, id, actual[name])
+		}
+	}
+
+	if trades[0].BuyOrderID != buyOrder.ID || trades[0].SellOrderID != sellOrder.ID {
+		t.Error("Trade should reference the generated order IDs")
+	}
+}
+
+func TestMinFillQuantity(t *testing.T) {
+	me := NewMatchingEngine()
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 30, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 30, 151.0))
+
+	// Only 30 is available at or below 150, short of the 50 minimum
+	buyOrder := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	buyOrder.MinFillQuantity = 50
+	trades := me.SubmitOrder(buyOrder)
+
+	if len(trades) != 0 {
+		t.Errorf("Expected no trades, got %d", len(trades))
+	}
+
+	if buyOrder.Status != models.OrderStatusCancelled {
+		t.Errorf("Expected order to be cancelled, got %s", buyOrder.Status)
+	}
+
+	ob := me.GetOrderBook("AAPL")
+	if ob.Bids.Len() != 0 {
+		t.Error("Cancelled order should not rest on the book")
+	}
+
+	// A minimum that can be met executes normally
+	buyOrder = models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 151.0)
+	buyOrder.MinFillQuantity = 50
+	trades = me.SubmitOrder(buyOrder)
+
+	if len(trades) != 2 {
+		t.Errorf("Expected 2 trades, got %d", len(trades))
+	}
+}
+
+func TestValidateStateReportsCorruption(t *testing.T) {
+	me := NewMatchingEngine()
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 152.0))
+
+	if errs := me.ValidateState(); len(errs) != 0 {
+		t.Fatalf("Expected a clean book to validate, got %v", errs)
+	}
+
+	// Restore a corrupt state: a bid pushed straight onto the heap without
+	// being indexed crosses the book, and an order shared between two books
+	aapl := me.GetOrderBook("AAPL")
+	aapl.Bids.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 153.0))
+
+	shared := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 160.0)
+	aapl.AddOrder(shared)
+	me.GetOrCreateOrderBook("MSFT").AddOrder(shared)
+
+	errs := me.ValidateState()
+	expected := []string{
+		"is missing from the order index",
+		"book is crossed with bid 153 at or above ask 152",
+		"is present in both",
+	}
+
+	for _, want := range expected {
+		found := false
+		for _, err := range errs {
+			if strings.Contains(err.Error(), want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected a validation error containing %q, got %v", want, errs)
+		}
+	}
+}
+
+func TestSequentialRefs(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSequentialRefs(true)
+
+	aaplSell := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0)
+	msftSell := models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideSell, 100, 300.0)
+	aaplBuy := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+
+	me.SubmitOrder(aaplSell)
+	me.SubmitOrder(msftSell)
+	trades := me.SubmitOrder(aaplBuy)
+
+	if len(trades) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(trades))
+	}
+
+	expected := map[string]string{
+		"AAPL sell":  "AAPL-000001",
+		"AAPL buy":   "AAPL-000002",
+		"AAPL trade": "AAPL-000003",
+		"MSFT sell":  "MSFT-000001",
+	}
+	actual := map[string]string{
+		"AAPL sell":  aaplSell.Ref,
+		"AAPL buy":   aaplBuy.Ref,
+		"AAPL trade": trades[0].Ref,
+		"MSFT sell":  msftSell.Ref,
+	}
+
+	for name, ref := range expected {
+		if actual[name] != ref {
+			t.Errorf("Expected %s ref %s, got %s", name, ref, actual[name])
+		}
+	}
+}
+
+// exhaustedBook rests asks at 100 to 104 where the 100 and 103 levels hold
+// only consumed orders and 101 holds one consumed and one live order
+func exhaustedBook(me *MatchingEngine) {
+	ob := me.GetOrCreateOrderBook("AAPL")
+	add := func(price float64, consumed bool) {
+		order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, price)
+		ob.AddOrder(order)
+		if consumed {
+			order.FilledQuantity = order.Quantity
+		}
+	}
+
+	add(100.0, true)
+	add(101.0, true)
+	add(101.0, false)
+	add(102.0, false)
+	add(103.0, true)
+	add(104.0, false)
+}
+
+func TestMarketOrderSkipsExhaustedLevels(t *testing.T) {
+	me := NewMatchingEngine()
+	exhaustedBook(me)
+
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 25, 0))
+
+	expected := []struct{ price, quantity float64 }{{101.0, 10}, {102.0, 10}, {104.0, 5}}
+	if len(trades) != len(expected) {
+		t.Fatalf("Expected %d trades, got %d", len(expected), len(trades))
+	}
+	for i, trade := range trades {
+		if trade.Price != expected[i].price || trade.Quantity != expected[i].quantity {
+			t.Errorf("Trade %d: expected %v @ %v, got %v @ %v", i, expected[i].quantity, expected[i].price, trade.Quantity, trade.Price)
+		}
+	}
+
+	ob := me.GetOrderBook("AAPL")
+	if ob.Asks.Len() != 1 || ob.GetBestAsk() != 104.0 || ob.OrderCount() != 1 {
+		t.Errorf("Expected only the partially filled 104 level to remain, got %d levels", ob.Asks.Len())
+	}
+	if errs := me.ValidateState(); len(errs) > 0 {
+		t.Errorf("Expected valid state, got %v", errs)
+	}
+}
+
+func TestLimitOrderSkipsExhaustedLevels(t *testing.T) {
+	me := NewMatchingEngine()
+	exhaustedBook(me)
+
+	buyOrder := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 25, 103.5)
+	trades := me.SubmitOrder(buyOrder)
+
+	if len(trades) != 2 || trades[0].Price != 101.0 || trades[1].Price != 102.0 {
+		t.Fatalf("Expected trades at 101 and 102, got %d trades", len(trades))
+	}
+
+	ob := me.GetOrderBook("AAPL")
+	if ob.GetBestBid() != 103.5 || buyOrder.RemainingQuantity(models.DefaultQuantityEpsilon) != 5 {
+		t.Errorf("Expected 5 to rest at 103.5, got best bid %v with %v remaining", ob.GetBestBid(), buyOrder.RemainingQuantity(models.DefaultQuantityEpsilon))
+	}
+	if ob.Asks.Len() != 1 || ob.GetBestAsk() != 104.0 {
+		t.Errorf("Expected the exhausted 103 level to be dropped, got best ask %v across %d levels", ob.GetBestAsk(), ob.Asks.Len())
+	}
+	if errs := me.ValidateState(); len(errs) > 0 {
+		t.Errorf("Expected valid state, got %v", errs)
+	}
+}
+
+func TestMarketOrderRemainderCancelledWithoutLiquidity(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 30, 150.0))
+
+	order := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 100, 0)
+	trades := me.SubmitOrder(order)
+
+	if len(trades) != 1 || order.FilledQuantity != 30 {
+		t.Fatalf("Expected 30 filled in 1 trade, got %v in %d", order.FilledQuantity, len(trades))
+	}
+	if order.Status != models.OrderStatusCancelled || order.IsActive() {
+		t.Errorf("Expected the remainder to be cancelled, got %s", order.Status)
+	}
+	if order.CancelledQuantity != 70 {
+		t.Errorf("Expected 70 cancelled, got %v", order.CancelledQuantity)
+	}
+	if order.CancelReason != "no liquidity" {
+		t.Errorf("Expected no liquidity reason, got %q", order.CancelReason)
+	}
+}
+
+func TestMarketOrderSlippageCap(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{MaxSlippage: 0.01})
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.5))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 102.0))
+
+	order := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 30, 0)
+	trades := me.SubmitOrder(order)
+
+	if len(trades) != 2 || order.FilledQuantity != 20 {
+		t.Fatalf("Expected 20 filled within 1%% of the touch, got %v in %d trades", order.FilledQuantity, len(trades))
+	}
+	if order.Status != models.OrderStatusCancelled || order.CancelledQuantity != 10 {
+		t.Errorf("Expected 10 cancelled, got %s with %v", order.Status, order.CancelledQuantity)
+	}
+	if !strings.Contains(order.CancelReason, "slippage") {
+		t.Errorf("Expected slippage reason, got %q", order.CancelReason)
+	}
+	if ask := me.GetOrderBook("AAPL").GetBestAsk(); ask != 102.0 {
+		t.Errorf("Expected the 102 level untouched, got best ask %v", ask)
+	}
+}
+
+func TestFloatResidueFillsCleanly(t *testing.T) {
+	me := NewMatchingEngine()
+
+	first := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 0.1, 150.0)
+	second := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 0.2, 150.0+1e-12)
+	me.SubmitOrder(first)
+	me.SubmitOrder(second)
+
+	ob := me.GetOrderBook("AAPL")
+	if ob.Asks.Len() != 1 {
+		t.Fatalf("Expected near-equal prices to share a level, got %d levels", ob.Asks.Len())
+	}
+
+	// 0.3 - 0.1 leaves 0.19999999999999998, short of the second order's 0.2
+	buy := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 0.3, 150.0)
+	me.SubmitOrder(buy)
+
+	for _, order := range []*models.Order{first, second, buy} {
+		if order.Status != models.OrderStatusFilled || order.RemainingQuantity(models.DefaultQuantityEpsilon) != 0 {
+			t.Errorf("Expected %s %v to be fully filled, got %s with %g left", order.Side, order.Quantity, order.Status, order.RemainingQuantity(models.DefaultQuantityEpsilon))
+		}
+	}
+	if ob.OrderCount() != 0 || ob.Asks.Len() != 0 {
+		t.Errorf("Expected an empty book, got %d orders on %d ask levels", ob.OrderCount(), ob.Asks.Len())
+	}
+}
+
+func TestConfiguredToleranceFillsCleanly(t *testing.T) {
+	tolerance := models.Tolerance{Quantity: 1e-4, Price: 1e-4}
+	me := NewMatchingEngineWithTolerance(tolerance)
+
+	first := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 150.0)
+	second := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 150.00005)
+	me.SubmitOrder(first)
+	me.SubmitOrder(second)
+
+	ob := me.GetOrderBook("AAPL")
+	if ob.Tolerance() != tolerance || ob.Asks.Len() != 1 {
+		t.Fatalf("Expected prices within the tolerance to share a level, got %d levels", ob.Asks.Len())
+	}
+
+	// Leaves 0.00005 of the second order, within the quantity tolerance
+	buy := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1.99995, 150.0001)
+	me.SubmitOrder(buy)
+	for _, order := range []*models.Order{first, second, buy} {
+		if order.Status != models.OrderStatusFilled {
+			t.Errorf("Expected %s %v to be fully filled, got %s", order.Side, order.Quantity, order.Status)
+		}
+	}
+	if ob.OrderCount() != 0 {
+		t.Errorf("Expected an empty book, got %d orders", ob.OrderCount())
+	}
+
+	// The default tolerance keeps the same prices apart
+	strict := NewMatchingEngine()
+	strict.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 150.0))
+	strict.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 150.00005))
+	if levels := strict.GetOrderBook("AAPL").Asks.Len(); levels != 2 {
+		t.Errorf("Expected 2 levels under the default tolerance, got %d", levels)
+	}
+}
+
+func TestTradeRecordsCounterpartyAccounts(t *testing.T) {
+	me := NewMatchingEngine()
+
+	sell := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0)
+	sell.AccountID = "seller"
+	me.SubmitOrder(sell)
+
+	buy := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 100, 0)
+	buy.AccountID = "buyer"
+	trades := me.SubmitOrder(buy)
+
+	if len(trades) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(trades))
+	}
+	if trades[0].BuyAccountID != "buyer" {
+		t.Errorf("Expected buy account buyer, got %q", trades[0].BuyAccountID)
+	}
+	if trades[0].SellAccountID != "seller" {
+		t.Errorf("Expected sell account seller, got %q", trades[0].SellAccountID)
+	}
+}
+
+func TestSnapshotReasonAfterMatching(t *testing.T) {
+	me := NewMatchingEngine()
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 40, 0))
+
+	if reason := me.GetOrderBook("AAPL").Snapshot().LastChangeReason; reason != orderbook.ChangeTrade {
+		t.Errorf("Expected the last change to be a trade, got %q", reason)
+	}
+
+	// A partial fill that rests its remainder ends with the add
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0))
+	if reason := me.GetOrderBook("AAPL").Snapshot().LastChangeReason; reason != orderbook.ChangeAdd {
+		t.Errorf("Expected the last change to be an add, got %q", reason)
+	}
+}
+
+func TestIOCRemainderDoesNotRest(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 3, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 2, 150.5))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 152.0))
+
+	// The thin book only has 5 within the limit
+	buy := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 151.0)
+	buy.TimeInForce = models.TimeInForceIOC
+	trades := me.SubmitOrder(buy)
+
+	if len(trades) != 2 || buy.FilledQuantity != 5 {
+		t.Fatalf("Expected 5 filled in 2 trades, got %g in %d", buy.FilledQuantity, len(trades))
+	}
+	if buy.Status != models.OrderStatusCancelled || buy.CancelledQuantity != 5 {
+		t.Errorf("Expected the remaining 5 cancelled, got %s with %g cancelled", buy.Status, buy.CancelledQuantity)
+	}
+
+	ob := me.GetOrderBook("AAPL")
+	if _, resting := ob.GetOrder(buy.ID); resting || ob.GetBestBid() != 0 {
+		t.Error("Expected no resting bid after the IOC order")
+	}
+	if ob.OrderCount() != 1 || ob.GetBestAsk() != 152.0 {
+		t.Errorf("Expected only the 152 offer left, got %d orders", ob.OrderCount())
+	}
+
+	// One that can't trade at all is cancelled outright
+	miss := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 140.0)
+	miss.TimeInForce = models.TimeInForceIOC
+	if trades := me.SubmitOrder(miss); len(trades) != 0 || miss.Status != models.OrderStatusCancelled {
+		t.Errorf("Expected an unmatched IOC order cancelled, got %s", miss.Status)
+	}
+	if ob.GetBestBid() != 0 {
+		t.Error("Expected the unmatched IOC order not to rest")
+	}
+}
+
+func TestIOCFullFillIsFilled(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 150.0))
+
+	buy := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0)
+	buy.TimeInForce = models.TimeInForceIOC
+	me.SubmitOrder(buy)
+	if buy.Status != models.OrderStatusFilled || buy.CancelledQuantity != 0 {
+		t.Errorf("Expected a fully filled IOC order to be filled, got %s", buy.Status)
+	}
+
+	unknown := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0)
+	unknown.TimeInForce = "FOK"
+	if me.SubmitOrder(unknown); unknown.Status != models.OrderStatusRejected {
+		t.Errorf("Expected an unknown time in force rejected, got %s", unknown.Status)
+	}
+}
@@ -0,0 +1,56 @@
+package matching
+
+import (
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// EventType identifies an engine event
+type EventType string
+
+const (
+	EventOrderCancelled EventType = "order_cancelled"
+	EventTradingHalted  EventType = "trading_halted"
+	EventKillSwitch     EventType = "kill_switch"
+	EventOrderAccepted  EventType = "order_accepted"
+	EventOrderRejected  EventType = "order_rejected"
+	EventTrade          EventType = "trade"
+	EventBBOChanged     EventType = "bbo_changed"
+	EventAuctionStarted EventType = "auction_started"
+	EventAuctionEnded   EventType = "auction_ended"
+	EventReferenceSet   EventType = "reference_established"
+)
+
+// Event is a notable change in engine state
+type Event struct {
+	Type      EventType     `json:"type"`
+	Symbol    string        `json:"symbol,omitempty"`
+	OrderID   uuid.UUID     `json:"order_id,omitempty"`
+	Trade     *models.Trade `json:"trade,omitempty"`
+	BBO       *BBOChange    `json:"bbo,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// OnEvent registers a handler called for every engine event. Handlers run
+// synchronously on the goroutine that caused the event and must not block.
+func (me *MatchingEngine) OnEvent(handler func(Event)) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.eventHandlers = append(me.eventHandlers, handler)
+}
+
+// emit sends an event to every handler. It must be called without holding
+// the engine mutex so handlers can query the engine.
+func (me *MatchingEngine) emit(event Event) {
+	me.mutex.RLock()
+	handlers := me.eventHandlers
+	event.Timestamp = me.clock.Now()
+	me.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
@@ -0,0 +1,123 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// FeeSchedule sets maker and taker fees as a fraction of trade notional. A
+// negative MakerRate is a rebate paid to the liquidity provider.
+type FeeSchedule struct {
+	MakerRate    float64
+	TakerRate    float64
+	MaxNetRebate float64 // Largest net rate the exchange may pay out per trade
+
+	// DepthSurcharge adds to the taker rate the deeper an order sweeps, by
+	// the average depth it filled at. Nil for no surcharge.
+	DepthSurcharge []DepthSurchargeTier
+}
+
+// DepthSurchargeTier is a step of the depth surcharge curve. Depth is the
+// fill-weighted average number of levels beyond the touch an order took
+// liquidity at: 0 for an order filled entirely at the touch, 1 for one
+// split evenly over three levels.
+type DepthSurchargeTier struct {
+	MinDepth float64 // Average depth from which the tier applies
+	Rate     float64 // Extra taker rate, e.g. 0.0001 for 1bp
+}
+
+// Validate checks that the schedule can't pay out more than it collects
+// beyond the configured bound
+func (f FeeSchedule) Validate() error {
+	if f.TakerRate < 0 {
+		return fmt.Errorf("taker fee rate cannot be negative, got %g", f.TakerRate)
+	}
+	if f.MaxNetRebate < 0 {
+		return fmt.Errorf("max net rebate cannot be negative, got %g", f.MaxNetRebate)
+	}
+	for _, tier := range f.DepthSurcharge {
+		if tier.Rate < 0 || tier.MinDepth < 0 {
+			return fmt.Errorf("depth surcharge tiers cannot be negative, got %g from depth %g", tier.Rate, tier.MinDepth)
+		}
+	}
+	if net := f.MakerRate + f.TakerRate; net < -f.MaxNetRebate {
+		return fmt.Errorf("maker rate %g and taker rate %g pay out a net %g, beyond the bound of %g", f.MakerRate, f.TakerRate, -net, f.MaxNetRebate)
+	}
+	return nil
+}
+
+// fees returns the maker and taker fees for a trade's notional. Negative
+// fees are credits.
+func (f FeeSchedule) fees(notional float64) (makerFee, takerFee float64) {
+	return notional * f.MakerRate, notional * f.TakerRate
+}
+
+// SetFeeSchedule sets the fees charged on subsequent trades
+func (me *MatchingEngine) SetFeeSchedule(schedule FeeSchedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.fees = schedule
+	return nil
+}
+
+// depthSurcharge returns the extra taker rate for an average sweep depth:
+// the rate of the deepest tier the depth reaches, 0 if it reaches none
+func (f FeeSchedule) depthSurcharge(depth float64) float64 {
+	rate, from := 0.0, -1.0
+	for _, tier := range f.DepthSurcharge {
+		if depth >= tier.MinDepth && tier.MinDepth > from {
+			rate, from = tier.Rate, tier.MinDepth
+		}
+	}
+	return rate
+}
+
+// sweepDepth returns the fill-weighted average number of levels beyond the
+// first that an order's trades were made at
+func sweepDepth(trades []*models.Trade) float64 {
+	summary := SummarizeFills(trades)
+	if summary.FilledQuantity <= 0 {
+		return 0
+	}
+
+	weighted := 0.0
+	for depth, level := range summary.Levels {
+		weighted += float64(depth) * level.Quantity
+	}
+	return weighted / summary.FilledQuantity
+}
+
+// applyDepthSurcharge adds the depth surcharge to the taker fee of each of
+// an incoming order's trades, once its sweep is complete
+func (me *MatchingEngine) applyDepthSurcharge(trades []*models.Trade) {
+	me.mutex.RLock()
+	schedule := me.fees
+	me.mutex.RUnlock()
+
+	if len(schedule.DepthSurcharge) == 0 || len(trades) == 0 {
+		return
+	}
+
+	rate := schedule.depthSurcharge(sweepDepth(trades))
+	if rate == 0 {
+		return
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	// The fills are already recorded, so the taker's position is charged too
+	for _, trade := range trades {
+		surcharge := trade.Price * trade.Quantity * rate
+		trade.TakerFee += surcharge
+		if account := takerAccount(trade); account != "" {
+			me.position(account, trade.Symbol).RealizedPnL -= surcharge
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package matching
+
+import (
+	"math"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestMakerRebate(t *testing.T) {
+	me := NewMatchingEngine()
+	err := me.SetFeeSchedule(FeeSchedule{MakerRate: -0.0002, TakerRate: 0.0005})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0))
+
+	if len(trades) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(trades))
+	}
+
+	trade := trades[0]
+	if trade.TakerSide != models.OrderSideBuy {
+		t.Errorf("Expected buy side to be the taker, got %s", trade.TakerSide)
+	}
+
+	// Notional is 15,000: the maker is credited 3 and the taker pays 7.50
+	if math.Abs(trade.MakerFee-(-3.0)) > 1e-9 {
+		t.Errorf("Expected maker credit of -3.0, got %f", trade.MakerFee)
+	}
+	if math.Abs(trade.TakerFee-7.5) > 1e-9 {
+		t.Errorf("Expected taker fee of 7.5, got %f", trade.TakerFee)
+	}
+}
+
+func TestFeeScheduleValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule FeeSchedule
+		valid    bool
+	}{
+		{"rebate covered by taker fee", FeeSchedule{MakerRate: -0.0002, TakerRate: 0.0005}, true},
+		{"net payout within bound", FeeSchedule{MakerRate: -0.0006, TakerRate: 0.0005, MaxNetRebate: 0.0002}, true},
+		{"net payout beyond bound", FeeSchedule{MakerRate: -0.0010, TakerRate: 0.0005, MaxNetRebate: 0.0002}, false},
+		{"negative taker fee", FeeSchedule{MakerRate: 0.0001, TakerRate: -0.0001}, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.schedule.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected validation error", tt.name)
+		}
+	}
+}
+
+func TestDepthSurchargeOnDeepSweep(t *testing.T) {
+	schedule := FeeSchedule{
+		TakerRate: 0.0005,
+		DepthSurcharge: []DepthSurchargeTier{
+			{MinDepth: 0.5, Rate: 0.0002},
+			{MinDepth: 1, Rate: 0.0005},
+		},
+	}
+
+	// effectiveRate returns the taker fee paid per unit of notional
+	effectiveRate := func(trades []*models.Trade) float64 {
+		fee, notional := 0.0, 0.0
+		for _, trade := range trades {
+			fee += trade.TakerFee
+			notional += trade.Price * trade.Quantity
+		}
+		return fee / notional
+	}
+
+	me := NewMatchingEngine()
+	if err := me.SetFeeSchedule(schedule); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, price := range []float64{100.0, 100.5, 101.0} {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, price))
+	}
+
+	touch := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 5, 0))
+	if rate := effectiveRate(touch); math.Abs(rate-0.0005) > 1e-12 {
+		t.Errorf("Expected no surcharge at the touch, got an effective rate of %g", rate)
+	}
+
+	// The sweep fills 5, 10 and 10 across three levels, an average depth
+	// of (0*5 + 1*10 + 2*10) / 25 = 1.2
+	sweeper := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 25, 0)
+	sweeper.AccountID = "sweeper"
+	sweep := me.SubmitOrder(sweeper)
+	if len(sweep) != 3 {
+		t.Fatalf("Expected the sweep to take three levels, got %d trades", len(sweep))
+	}
+	if rate := effectiveRate(sweep); math.Abs(rate-0.001) > 1e-12 {
+		t.Errorf("Expected the deepest tier to apply for an effective rate of 0.001, got %g", rate)
+	}
+	if effectiveRate(sweep) <= effectiveRate(touch) {
+		t.Error("Expected the sweep to pay a higher effective taker rate than the touch")
+	}
+
+	// The surcharge reaches the taker's realized PnL along with the base fee
+	paid := 0.0
+	for _, trade := range sweep {
+		paid += trade.TakerFee
+	}
+	if pnl := me.GetPosition("sweeper", "AAPL").RealizedPnL; math.Abs(pnl+paid) > 1e-9 {
+		t.Errorf("Expected the sweeper's realized PnL to be -%g, got %g", paid, pnl)
+	}
+	for _, trade := range sweep {
+		if trade.MakerFee != 0 {
+			t.Errorf("Expected makers unaffected, got %g", trade.MakerFee)
+		}
+	}
+
+	if err := me.SetFeeSchedule(FeeSchedule{DepthSurcharge: []DepthSurchargeTier{{Rate: -0.1}}}); err == nil {
+		t.Error("Expected a negative surcharge to be rejected")
+	}
+}
@@ -0,0 +1,57 @@
+package matching
+
+import "github.com/acagliol/arbitrax/backend/internal/models"
+
+// LevelFill is the quantity an order filled at a single price
+type LevelFill struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// FillSummary breaks an order's executions down by price level and, for a
+// marketable limit order, reports how much rested after the sweep
+type FillSummary struct {
+	FilledQuantity float64     `json:"filled_quantity"`
+	AveragePrice   float64     `json:"average_price"`
+	RestedQuantity float64     `json:"rested_quantity"`
+	Levels         []LevelFill `json:"levels"`
+}
+
+// SummarizeFills builds a FillSummary from an order's trades, keeping the
+// levels in the order they were swept
+func SummarizeFills(trades []*models.Trade) *FillSummary {
+	summary := &FillSummary{
+		Levels: make([]LevelFill, 0),
+	}
+
+	notional := 0.0
+	for _, trade := range trades {
+		summary.FilledQuantity += trade.Quantity
+		notional += trade.Price * trade.Quantity
+
+		last := len(summary.Levels) - 1
+		if last >= 0 && summary.Levels[last].Price == trade.Price {
+			summary.Levels[last].Quantity += trade.Quantity
+			continue
+		}
+		summary.Levels = append(summary.Levels, LevelFill{
+			Price:    trade.Price,
+			Quantity: trade.Quantity,
+		})
+	}
+
+	if summary.FilledQuantity > 0 {
+		summary.AveragePrice = notional / summary.FilledQuantity
+	}
+	return summary
+}
+
+// SummarizeOrder builds a FillSummary for an order after submission,
+// including any remainder left resting at its limit price
+func (me *MatchingEngine) SummarizeOrder(order *models.Order, trades []*models.Trade) *FillSummary {
+	summary := SummarizeFills(trades)
+	if order.Type == models.OrderTypeLimit && order.IsActive() {
+		summary.RestedQuantity = order.RemainingQuantity(me.tolerance.Quantity)
+	}
+	return summary
+}
@@ -0,0 +1,79 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestSummarizeFillsByLevel(t *testing.T) {
+	me := NewMatchingEngine()
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 30, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 20, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 40, 151.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 152.0))
+
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 120, 0))
+	summary := SummarizeFills(trades)
+
+	expected := []LevelFill{
+		{Price: 150.0, Quantity: 50},
+		{Price: 151.0, Quantity: 40},
+		{Price: 152.0, Quantity: 30},
+	}
+
+	if len(summary.Levels) != len(expected) {
+		t.Fatalf("Expected %d levels, got %d", len(expected), len(summary.Levels))
+	}
+
+	for i, level := range summary.Levels {
+		if level != expected[i] {
+			t.Errorf("Expected level %d to be %+v, got %+v", i, expected[i], level)
+		}
+	}
+
+	if summary.FilledQuantity != 120 {
+		t.Errorf("Expected filled quantity 120, got %f", summary.FilledQuantity)
+	}
+
+	expectedAvg := (150.0*50 + 151.0*40 + 152.0*30) / 120
+	if summary.AveragePrice != expectedAvg {
+		t.Errorf("Expected average price %f, got %f", expectedAvg, summary.AveragePrice)
+	}
+}
+
+func TestMarketableLimitSweepsAndRests(t *testing.T) {
+	me := NewMatchingEngine()
+
+	// Asks from 150 to 158, 10 each
+	for price := 150.0; price <= 158.0; price++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, price))
+	}
+
+	buyOrder := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 120, 160.0)
+	trades := me.SubmitOrder(buyOrder)
+
+	if len(trades) != 9 {
+		t.Fatalf("Expected the buy to sweep 9 levels, got %d trades", len(trades))
+	}
+
+	summary := me.SummarizeOrder(buyOrder, trades)
+	if summary.FilledQuantity != 90 {
+		t.Errorf("Expected 90 executed, got %f", summary.FilledQuantity)
+	}
+	if summary.RestedQuantity != 30 {
+		t.Errorf("Expected 30 rested, got %f", summary.RestedQuantity)
+	}
+	if len(summary.Levels) != 9 || summary.Levels[8].Price != 158.0 {
+		t.Errorf("Expected the sweep to end at 158, got %v", summary.Levels)
+	}
+
+	ob := me.GetOrderBook("AAPL")
+	if ob.GetBestBid() != 160.0 {
+		t.Errorf("Expected remainder to rest at 160, got best bid %f", ob.GetBestBid())
+	}
+	if ob.Asks.Len() != 0 {
+		t.Errorf("Expected all asks consumed, got %d levels", ob.Asks.Len())
+	}
+}
@@ -0,0 +1,93 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// HiddenPriority decides how hidden orders queue against displayed orders
+type HiddenPriority string
+
+const (
+	// HiddenPriorityPrice applies plain price-time priority: a hidden order
+	// priced better than a displayed one fills first, and at the same price
+	// the earlier order fills first whether or not it is displayed
+	HiddenPriorityPrice HiddenPriority = "price"
+	// HiddenPriorityDisplay keeps price priority across levels but fills
+	// displayed orders before hidden ones at the same price
+	HiddenPriorityDisplay HiddenPriority = "display"
+	// HiddenPriorityProtectTouch protects displayed liquidity: at the same
+	// price, hidden and pegged orders only fill once every displayed order
+	// is exhausted, whatever the matching mode
+	HiddenPriorityProtectTouch HiddenPriority = "protect_touch"
+)
+
+// SetHiddenPriority chooses how hidden orders queue against displayed orders.
+// Price priority always wins across levels; the policy only decides the
+// queue within a level.
+func (me *MatchingEngine) SetHiddenPriority(priority HiddenPriority) error {
+	switch priority {
+	case HiddenPriorityPrice, HiddenPriorityDisplay, HiddenPriorityProtectTouch:
+	default:
+		return fmt.Errorf("unknown hidden priority %q", priority)
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.hiddenPriority = priority
+	return nil
+}
+
+// GetHiddenPriority returns the current hidden order priority, price
+// priority unless set otherwise
+func (me *MatchingEngine) GetHiddenPriority() HiddenPriority {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	if me.hiddenPriority == "" {
+		return HiddenPriorityPrice
+	}
+	return me.hiddenPriority
+}
+
+// levelQueues returns a level's orders as the groups they should fill in,
+// each in time priority. Display priority puts displayed orders ahead of
+// hidden ones in a single queue; protect the touch splits displayed orders
+// from undisplayed ones so the matching mode shares a fill among the
+// displayed orders before the rest see any of it.
+func (me *MatchingEngine) levelQueues(orders []*models.Order) [][]*models.Order {
+	switch me.GetHiddenPriority() {
+	case HiddenPriorityDisplay:
+		displayed, hidden := splitDisplayed(orders, func(order *models.Order) bool { return order.Hidden })
+		return [][]*models.Order{append(displayed, hidden...)}
+	case HiddenPriorityProtectTouch:
+		displayed, undisplayed := splitDisplayed(orders, undisplayed)
+		return [][]*models.Order{displayed, undisplayed}
+	default:
+		return [][]*models.Order{orders}
+	}
+}
+
+// undisplayed reports whether an order rests without showing its size at its
+// own price in the book: hidden orders, and pegs whose price moves with the
+// book
+func undisplayed(order *models.Order) bool {
+	return order.Hidden || order.Peg != ""
+}
+
+// splitDisplayed separates orders that hide reports as hidden from the rest,
+// keeping time priority within each
+func splitDisplayed(orders []*models.Order, hide func(*models.Order) bool) (displayed, hidden []*models.Order) {
+	displayed = make([]*models.Order, 0, len(orders))
+	hidden = make([]*models.Order, 0)
+	for _, order := range orders {
+		if hide(order) {
+			hidden = append(hidden, order)
+		} else {
+			displayed = append(displayed, order)
+		}
+	}
+	return displayed, hidden
+}
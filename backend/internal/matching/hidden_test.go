@@ -0,0 +1,138 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestBetterPricedHiddenOrderFillsFirst(t *testing.T) {
+	for _, priority := range []HiddenPriority{HiddenPriorityPrice, HiddenPriorityDisplay, HiddenPriorityProtectTouch} {
+		me := NewMatchingEngine()
+		if err := me.SetHiddenPriority(priority); err != nil {
+			t.Fatalf("Expected %s to be accepted, got %v", priority, err)
+		}
+
+		displayed := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0)
+		me.SubmitOrder(displayed)
+		hidden := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+		hidden.Hidden = true
+		me.SubmitOrder(hidden)
+
+		trades := me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 101.0))
+		if len(trades) != 1 || trades[0].SellOrderID != hidden.ID || trades[0].Price != 100.0 {
+			t.Errorf("Expected the better-priced hidden order to fill first under %s priority", priority)
+		}
+		if displayed.FilledQuantity != 0 {
+			t.Errorf("Expected the displayed order untouched under %s priority, got %g filled", priority, displayed.FilledQuantity)
+		}
+	}
+}
+
+func TestHiddenPriorityAtSamePrice(t *testing.T) {
+	tests := []struct {
+		priority   HiddenPriority
+		wantHidden bool
+	}{
+		{HiddenPriorityPrice, true},
+		{HiddenPriorityDisplay, false},
+		{HiddenPriorityProtectTouch, false},
+	}
+
+	for _, tt := range tests {
+		me := NewMatchingEngine()
+		me.SetHiddenPriority(tt.priority)
+
+		// The hidden order arrives first, so time priority favours it
+		hidden := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+		hidden.Hidden = true
+		me.SubmitOrder(hidden)
+		displayed := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+		me.SubmitOrder(displayed)
+
+		trades := me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+		if len(trades) != 1 {
+			t.Fatalf("Expected 1 trade under %s priority, got %d", tt.priority, len(trades))
+		}
+
+		want := displayed.ID
+		if tt.wantHidden {
+			want = hidden.ID
+		}
+		if trades[0].SellOrderID != want {
+			t.Errorf("Expected hidden=%v to fill first under %s priority", tt.wantHidden, tt.priority)
+		}
+	}
+}
+
+func TestSetHiddenPriorityRejectsUnknown(t *testing.T) {
+	me := NewMatchingEngine()
+	if err := me.SetHiddenPriority("random"); err == nil {
+		t.Error("Expected an unknown hidden priority to be rejected")
+	}
+	if me.GetHiddenPriority() != HiddenPriorityPrice {
+		t.Errorf("Expected price priority by default, got %s", me.GetHiddenPriority())
+	}
+}
+
+func TestProtectTouchAcrossMatchingModes(t *testing.T) {
+	for _, mode := range []MatchingMode{MatchingModeFIFO, MatchingModeProRata, MatchingModeSizePriority} {
+		me := NewMatchingEngine()
+		me.SetMatchingMode(mode)
+		me.SetHiddenPriority(HiddenPriorityProtectTouch)
+
+		// The hidden order is earlier and larger, so every mode would
+		// otherwise give it some or all of the fill
+		hidden := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 30, 100.0)
+		hidden.Hidden = true
+		me.SubmitOrder(hidden)
+		displayed := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+		me.SubmitOrder(displayed)
+
+		trades := me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+		if len(trades) != 1 || trades[0].SellOrderID != displayed.ID || hidden.FilledQuantity != 0 {
+			t.Errorf("Expected only the displayed order to fill under %s, got %d trades and %g hidden", mode, len(trades), hidden.FilledQuantity)
+		}
+
+		// Once the displayed size is exhausted the hidden order trades
+		trades = me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 100.0))
+		if len(trades) != 1 || trades[0].SellOrderID != hidden.ID {
+			t.Errorf("Expected the hidden order to fill after the displayed one under %s", mode)
+		}
+	}
+}
+
+func TestProtectTouchTakesDisplayedBeforePegged(t *testing.T) {
+	for _, tt := range []struct {
+		priority   HiddenPriority
+		peggedFill float64
+	}{
+		{HiddenPriorityPrice, 10},
+		{HiddenPriorityProtectTouch, 0},
+	} {
+		me := NewMatchingEngine()
+		me.SetHiddenPriority(tt.priority)
+
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+		first := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 100.0)
+		me.SubmitOrder(first)
+
+		// A primary peg joins the offer ahead of the next displayed order
+		pegged := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 0)
+		pegged.Peg = models.PegPrimary
+		me.SubmitOrder(pegged)
+		if pegged.Price != 100.0 {
+			t.Fatalf("Expected the peg at 100, got %g", pegged.Price)
+		}
+		second := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+		me.SubmitOrder(second)
+
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 15, 100.0))
+		if pegged.FilledQuantity != tt.peggedFill {
+			t.Errorf("Expected the peg to fill %g under %s, got %g", tt.peggedFill, tt.priority, pegged.FilledQuantity)
+		}
+		if first.FilledQuantity != 5 || second.FilledQuantity != 10-tt.peggedFill {
+			t.Errorf("Expected displayed fills of 5 and %g under %s, got %g and %g", 10-tt.peggedFill, tt.priority, first.FilledQuantity, second.FilledQuantity)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package matching
+
+import (
+	"sort"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// PricePoint is a single last-trade price observation
+type PricePoint struct {
+	Price     float64   `json:"price"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// GetPriceHistory returns up to limit last-trade prices for a symbol, newest
+// first. Prices come from the public tape, so suppressed odd lots are left
+// out and hidden-order prints appear only once released.
+func (me *MatchingEngine) GetPriceHistory(symbol string, limit int) []PricePoint {
+	return pricePoints(me.GetPublicTrades(symbol, limit))
+}
+
+// pricePoints returns the price and time of each trade
+func pricePoints(trades []*models.Trade) []PricePoint {
+	points := make([]PricePoint, len(trades))
+	for i, trade := range trades {
+		points[i] = PricePoint{
+			Price:     trade.Price,
+			Timestamp: trade.Timestamp,
+		}
+	}
+	return points
+}
+
+// GetTradesInRange returns every trade across all symbols executed in
+// [from, to), oldest first. Only trades within the trade history limit are
+// held, so a range reaching back before TradeHistoryStart comes back short.
+func (me *MatchingEngine) GetTradesInRange(from, to time.Time) []*models.Trade {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	result := make([]*models.Trade, 0)
+	for trade := range me.trades.all() {
+		if !trade.Timestamp.Before(from) && trade.Timestamp.Before(to) {
+			result = append(result, trade)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+	return result
+}
+
+// TradesBetween returns every trade in which the two accounts were
+// counterparties, in either direction, oldest first. Trades evicted by the
+// trade history limit are not included.
+func (me *MatchingEngine) TradesBetween(accountA, accountB string) []*models.Trade {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	result := make([]*models.Trade, 0)
+	if accountA == "" || accountB == "" {
+		return result
+	}
+	for trade := range me.trades.all() {
+		if (trade.BuyAccountID == accountA && trade.SellAccountID == accountB) ||
+			(trade.BuyAccountID == accountB && trade.SellAccountID == accountA) {
+			result = append(result, trade)
+		}
+	}
+	return result
+}
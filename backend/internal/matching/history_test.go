@@ -0,0 +1,127 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestGetPriceHistory(t *testing.T) {
+	me := NewMatchingEngine()
+
+	prices := []float64{150.0, 151.5, 149.0, 152.0}
+	for _, price := range prices {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, price))
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, price))
+	}
+
+	// Trades in another symbol must not leak into the series
+	me.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideSell, 10, 300.0))
+	me.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideBuy, 10, 300.0))
+
+	history := me.GetPriceHistory("AAPL", 10)
+	if len(history) != len(prices) {
+		t.Fatalf("Expected %d price points, got %d", len(prices), len(history))
+	}
+
+	// Newest first
+	for i, point := range history {
+		expected := prices[len(prices)-1-i]
+		if point.Price != expected {
+			t.Errorf("Expected point %d at %f, got %f", i, expected, point.Price)
+		}
+	}
+
+	if limited := me.GetPriceHistory("AAPL", 2); len(limited) != 2 || limited[0].Price != 152.0 {
+		t.Errorf("Expected the 2 newest points, got %v", limited)
+	}
+}
+
+func TestPriceHistoryFollowsTheTape(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetHiddenPrintDelay(time.Minute)
+	me.SetSymbolConfig("AAPL", SymbolConfig{OddLots: OddLotRule{RoundLot: 100, SuppressPrint: true}})
+
+	printTrade(me, 150.0, 100)
+	printTrade(me, 151.0, 10) // Odd lot, kept off the tape
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 152.0)
+	hidden.Hidden = true
+	me.SubmitOrder(hidden)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 152.0))
+
+	if history := me.GetPriceHistory("AAPL", 10); len(history) != 1 || history[0].Price != 150.0 {
+		t.Fatalf("Expected only the round-lot lit print, got %v", history)
+	}
+
+	mock.Advance(time.Minute)
+	if history := me.GetPriceHistory("AAPL", 10); len(history) != 2 || history[0].Price != 152.0 {
+		t.Errorf("Expected the hidden print once released, got %v", history)
+	}
+}
+
+func TestGetTradesInRange(t *testing.T) {
+	start := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	mock := clock.NewMock(start)
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+
+	// One trade a minute, alternating symbols
+	symbols := []string{"AAPL", "MSFT", "AAPL", "GOOG", "MSFT"}
+	for _, symbol := range symbols {
+		me.SubmitOrder(models.NewOrder(symbol, models.OrderTypeLimit, models.OrderSideSell, 10, 100.0))
+		me.SubmitOrder(models.NewOrder(symbol, models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+		mock.Advance(time.Minute)
+	}
+
+	trades := me.GetTradesInRange(start.Add(time.Minute), start.Add(4*time.Minute))
+	if len(trades) != 3 {
+		t.Fatalf("Expected 3 trades in the window, got %d", len(trades))
+	}
+
+	for i, trade := range trades {
+		expectedSymbol := symbols[i+1]
+		expectedTime := start.Add(time.Duration(i+1) * time.Minute)
+		if trade.Symbol != expectedSymbol || !trade.Timestamp.Equal(expectedTime) {
+			t.Errorf("Expected trade %d to be %s at %v, got %s at %v", i, expectedSymbol, expectedTime, trade.Symbol, trade.Timestamp)
+		}
+	}
+}
+
+func TestTradesBetween(t *testing.T) {
+	me := NewMatchingEngine()
+
+	trade := func(seller, buyer string, price float64) {
+		sell := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, price)
+		sell.AccountID = seller
+		me.SubmitOrder(sell)
+		buy := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, price)
+		buy.AccountID = buyer
+		me.SubmitOrder(buy)
+	}
+
+	trade("alice", "bob", 150.0)
+	trade("alice", "carol", 151.0)
+	trade("bob", "alice", 152.0)
+	trade("carol", "bob", 153.0)
+
+	trades := me.TradesBetween("bob", "alice")
+	if len(trades) != 2 {
+		t.Fatalf("Expected 2 trades between alice and bob, got %d", len(trades))
+	}
+	if trades[0].Price != 150.0 || trades[1].Price != 152.0 {
+		t.Errorf("Expected trades at 150 then 152, got %f and %f", trades[0].Price, trades[1].Price)
+	}
+	if trades[0].BuyAccountID != "bob" || trades[0].SellAccountID != "alice" {
+		t.Errorf("Expected bob buying from alice, got %s from %s", trades[0].BuyAccountID, trades[0].SellAccountID)
+	}
+
+	if trades := me.TradesBetween("alice", "dave"); len(trades) != 0 {
+		t.Errorf("Expected no trades with an unknown account, got %d", len(trades))
+	}
+}
@@ -0,0 +1,127 @@
+package matching
+
+import (
+	"sort"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// maxLevelChanges bounds the level changes kept per symbol
+const maxLevelChanges = 1000
+
+// IcebergDetectionConfig tunes the iceberg heuristic: a level is suspect once
+// it has been traded into and then refilled MinReplenishments times within
+// Window
+type IcebergDetectionConfig struct {
+	Window            time.Duration
+	MinReplenishments int
+}
+
+// DefaultIcebergDetection is used until SetIcebergDetection is called
+var DefaultIcebergDetection = IcebergDetectionConfig{
+	Window:            time.Minute,
+	MinReplenishments: 3,
+}
+
+// levelChange is one add, trade or cancel at a displayed price level
+type levelChange struct {
+	side   models.OrderSide
+	price  float64
+	reason orderbook.ChangeReason
+	at     time.Time
+}
+
+// levelKey identifies a price level on one side of a book
+type levelKey struct {
+	side  models.OrderSide
+	price float64
+}
+
+// SetIcebergDetection replaces the iceberg heuristic's settings
+func (me *MatchingEngine) SetIcebergDetection(config IcebergDetectionConfig) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.icebergConfig = config
+}
+
+// SuspectedIcebergs returns the prices, lowest first, of a symbol's levels
+// that were repeatedly traded into and refilled within the detection window,
+// the pattern a reserve order replenishing its displayed size leaves behind
+func (me *MatchingEngine) SuspectedIcebergs(symbol string) []float64 {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	config := me.icebergConfig
+	since := me.clock.Now().Add(-config.Window)
+
+	traded := make(map[levelKey]bool)
+	replenished := make(map[levelKey]int)
+	for _, change := range me.levelChanges[symbol] {
+		if change.at.Before(since) {
+			continue
+		}
+
+		key := levelKey{side: change.side, price: change.price}
+		switch change.reason {
+		case orderbook.ChangeTrade:
+			traded[key] = true
+		case orderbook.ChangeAdd:
+			// Only a refill after the level was hit counts
+			if traded[key] {
+				replenished[key]++
+				traded[key] = false
+			}
+		}
+	}
+
+	prices := make([]float64, 0)
+	for key, count := range replenished {
+		if count >= config.MinReplenishments && !containsPrice(prices, key.price, me.tolerance) {
+			prices = append(prices, key.price)
+		}
+	}
+	sort.Float64s(prices)
+	return prices
+}
+
+// containsPrice reports whether prices already holds price, within
+// tolerance
+func containsPrice(prices []float64, price float64, tolerance models.Tolerance) bool {
+	for _, p := range prices {
+		if tolerance.PricesEqual(p, price) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordSubmission records the levels an order traded into and the level it
+// came to rest at, if displayed. The caller must hold the engine mutex.
+func (me *MatchingEngine) recordSubmission(ob *orderbook.OrderBook, order *models.Order, trades []*models.Trade) {
+	restingSide := models.OrderSideSell
+	if order.Side == models.OrderSideSell {
+		restingSide = models.OrderSideBuy
+	}
+	for _, trade := range trades {
+		me.recordLevelChange(order.Symbol, restingSide, trade.Price, orderbook.ChangeTrade)
+	}
+
+	if _, resting := ob.GetOrder(order.ID); resting && !order.Hidden {
+		me.recordLevelChange(order.Symbol, order.Side, order.Price, orderbook.ChangeAdd)
+	}
+}
+
+// recordLevelChange appends to a symbol's level-change history. The caller
+// must hold the engine mutex.
+func (me *MatchingEngine) recordLevelChange(symbol string, side models.OrderSide, price float64, reason orderbook.ChangeReason) {
+	history := me.levelChanges[symbol]
+	if len(history) >= maxLevelChanges {
+		history = history[1:]
+	}
+	me.levelChanges[symbol] = append(history, levelChange{side: side, price: price, reason: reason, at: me.clock.Now()})
+}
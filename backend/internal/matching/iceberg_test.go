@@ -0,0 +1,41 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestSuspectedIcebergs(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetIcebergDetection(IcebergDetectionConfig{Window: time.Minute, MinReplenishments: 3})
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 102.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+
+	// 101 is hit and refilled three times; 102 never changes
+	for i := 0; i < 3; i++ {
+		mock.Advance(5 * time.Second)
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 101.0))
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	}
+
+	// 99 is hit and refilled only once
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideSell, 10, 0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+
+	suspects := me.SuspectedIcebergs("AAPL")
+	if len(suspects) != 1 || suspects[0] != 101.0 {
+		t.Errorf("Expected only 101 flagged, got %v", suspects)
+	}
+
+	mock.Advance(2 * time.Minute)
+	if suspects := me.SuspectedIcebergs("AAPL"); len(suspects) != 0 {
+		t.Errorf("Expected nothing flagged once the window passed, got %v", suspects)
+	}
+}
@@ -0,0 +1,57 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ImportOrders rests many historical orders in a symbol's book without
+// matching them, to bootstrap the book from an external source. The book's
+// levels are built in bulk and heapified once, which is much faster than
+// submitting each order. Every order must be a live limit order for the
+// symbol, and the book must not be left crossed; otherwise nothing is
+// imported.
+func (me *MatchingEngine) ImportOrders(symbol string, orders []*models.Order) error {
+	symbol = me.NormalizeSymbol(symbol)
+
+	for _, order := range orders {
+		if order.Symbol = me.NormalizeSymbol(order.Symbol); order.Symbol != symbol {
+			return fmt.Errorf("order %s is for %s, not %s", order.ID, order.Symbol, symbol)
+		}
+		if reason := me.checkSanity(order); reason != "" {
+			return fmt.Errorf("order %s: %s", order.ID, reason)
+		}
+		if order.Type != models.OrderTypeLimit || order.Price <= 0 {
+			return fmt.Errorf("order %s must be a priced limit order", order.ID)
+		}
+		if !order.IsActive() || order.RemainingQuantity(me.tolerance.Quantity) <= 0 {
+			return fmt.Errorf("order %s has nothing left to rest", order.ID)
+		}
+	}
+
+	ob := me.GetOrCreateOrderBook(symbol)
+	if err := ob.AddOrders(orders); err != nil {
+		return err
+	}
+
+	me.mutex.Lock()
+	for _, order := range orders {
+		if order.Ref == "" {
+			order.Ref = me.nextRef(symbol)
+		}
+		me.orderIndex[order.ID] = order
+		if order.AccountID != "" {
+			if me.accountOrders[order.AccountID] == nil {
+				me.accountOrders[order.AccountID] = make(map[uuid.UUID]*models.Order)
+			}
+			me.accountOrders[order.AccountID][order.ID] = order
+		}
+	}
+	me.mutex.Unlock()
+
+	me.bookChanged(symbol)
+	me.checkBBO(symbol, BBOCauseImport)
+	return nil
+}
@@ -0,0 +1,111 @@
+package matching
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// historicalOrders returns n resting orders spread over many levels either
+// side of 100, bids on odd cents below and asks on even cents above
+func historicalOrders(n int) []*models.Order {
+	orders := make([]*models.Order, 0, n)
+	for i := 0; i < n; i++ {
+		offset := float64(i%500+1) * 0.01
+		side, price := models.OrderSideBuy, 100.0-offset
+		if i%2 == 1 {
+			side, price = models.OrderSideSell, 100.0+offset
+		}
+		order := models.NewOrder("AAPL", models.OrderTypeLimit, side, float64(i%7+1), price)
+		order.AccountID = fmt.Sprintf("acct-%d", i%10)
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+// checkHeap reports whether no level in a heap beats its parent
+func checkHeap(h *orderbook.PriceLevelHeap) bool {
+	for i := 1; i < h.Len(); i++ {
+		if h.Less(i, (i-1)/2) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestImportOrdersBuildsBook(t *testing.T) {
+	me := NewMatchingEngine()
+	orders := historicalOrders(20000)
+
+	if err := me.ImportOrders("aapl", orders); err != nil {
+		t.Fatalf("Expected the import to succeed, got %v", err)
+	}
+
+	ob := me.GetOrderBook("AAPL")
+	if ob.OrderCount() != len(orders) {
+		t.Errorf("Expected %d orders, got %d", len(orders), ob.OrderCount())
+	}
+	if ob.GetBestBid() != 99.99 || ob.GetBestAsk() != 100.02 {
+		t.Errorf("Expected 99.99/100.02, got %g/%g", ob.GetBestBid(), ob.GetBestAsk())
+	}
+	if !checkHeap(ob.Bids) || !checkHeap(ob.Asks) {
+		t.Error("Expected both sides to satisfy the heap invariant")
+	}
+	if ob.Bids.Len() != 250 || ob.Asks.Len() != 250 {
+		t.Errorf("Expected 250 levels a side, got %d and %d", ob.Bids.Len(), ob.Asks.Len())
+	}
+	if errs := me.ValidateState(); len(errs) > 0 {
+		t.Errorf("Expected a valid engine state, got %v", errs)
+	}
+
+	// Imported orders queue in the given order and trade like any other
+	level := ob.Bids.Peek()
+	if level.Orders[0] != orders[0] {
+		t.Error("Expected the first imported order at the front of its level")
+	}
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideSell, orders[0].Quantity, 0))
+	if len(trades) != 1 || trades[0].BuyOrderID != orders[0].ID {
+		t.Error("Expected the first imported bid to fill first")
+	}
+}
+
+func TestImportOrdersRejectsCrossing(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+
+	orders := []*models.Order{
+		models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0),
+		models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 101.5),
+	}
+	if err := me.ImportOrders("AAPL", orders); err == nil {
+		t.Error("Expected a bid through the resting offer to be rejected")
+	}
+	if ob := me.GetOrderBook("AAPL"); ob.OrderCount() != 1 || ob.GetBestBid() != 0 {
+		t.Error("Expected a failed import to leave the book unchanged")
+	}
+
+	market := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	if err := me.ImportOrders("AAPL", []*models.Order{market}); err == nil {
+		t.Error("Expected a market order to be rejected")
+	}
+}
+
+func BenchmarkImportOrders(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		me := NewMatchingEngine()
+		if err := me.ImportOrders("AAPL", historicalOrders(10000)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSubmitOrdersOneByOne(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		me := NewMatchingEngine()
+		for _, order := range historicalOrders(10000) {
+			me.SubmitOrder(order)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package matching
+
+import (
+	"slices"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// maxLatencySamples bounds the latencies kept per symbol
+const maxLatencySamples = 10000
+
+// LatencyStats summarises how long orders took from submission to being
+// fully filled or cancelled
+type LatencyStats struct {
+	Count int           `json:"count"`
+	P50   time.Duration `json:"p50_ns"`
+	P90   time.Duration `json:"p90_ns"`
+	P99   time.Duration `json:"p99_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// ExecutionLatency returns latency percentiles over a symbol's most recently
+// completed orders
+func (me *MatchingEngine) ExecutionLatency(symbol string) LatencyStats {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	samples := slices.Clone(me.latencies[symbol])
+	me.mutex.RUnlock()
+
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	slices.Sort(samples)
+
+	percentile := func(p float64) time.Duration {
+		return samples[int(p*float64(len(samples)-1))]
+	}
+	return LatencyStats{
+		Count: len(samples),
+		P50:   percentile(0.50),
+		P90:   percentile(0.90),
+		P99:   percentile(0.99),
+		Max:   samples[len(samples)-1],
+	}
+}
+
+// recordLatency notes how long a filled or cancelled order took. It must be
+// called without holding the engine mutex.
+func (me *MatchingEngine) recordLatency(order *models.Order) {
+	var done *time.Time
+	switch order.Status {
+	case models.OrderStatusFilled:
+		done = order.FilledAt
+	case models.OrderStatusCancelled:
+		done = order.CancelledAt
+	}
+	if done == nil {
+		return
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	samples := me.latencies[order.Symbol]
+	if len(samples) >= maxLatencySamples {
+		samples = samples[1:]
+	}
+	me.latencies[order.Symbol] = append(samples, done.Sub(order.SubmittedAt))
+}
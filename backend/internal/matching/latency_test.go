@@ -0,0 +1,47 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestExecutionLatency(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+
+	sell := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 150.0)
+	me.SubmitOrder(sell)
+	stale := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 140.0)
+	me.SubmitOrder(stale)
+
+	// The resting sell fills after 5s; the incoming buy fills on arrival
+	mock.Advance(5 * time.Second)
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0))
+
+	if sell.FilledAt == nil || !sell.FilledAt.Equal(mock.Now()) {
+		t.Errorf("Expected the fill time to come from the clock, got %v", sell.FilledAt)
+	}
+
+	mock.Advance(15 * time.Second)
+	me.CancelOrder("AAPL", stale.ID)
+
+	stats := me.ExecutionLatency("AAPL")
+	if stats.Count != 3 {
+		t.Fatalf("Expected 3 completed orders, got %d", stats.Count)
+	}
+	if stats.P50 != 5*time.Second {
+		t.Errorf("Expected median latency 5s, got %v", stats.P50)
+	}
+	if stats.Max != 20*time.Second {
+		t.Errorf("Expected max latency 20s, got %v", stats.Max)
+	}
+
+	if stats := me.ExecutionLatency("MSFT"); stats.Count != 0 {
+		t.Errorf("Expected no samples for an idle symbol, got %d", stats.Count)
+	}
+}
@@ -0,0 +1,80 @@
+package matching
+
+import (
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// SetMaxOrderLifetime sets how long any order may rest before
+// ExpireStaleOrders cancels it, whatever its time in force. 0 disables the
+// limit, which is the default.
+func (me *MatchingEngine) SetMaxOrderLifetime(lifetime time.Duration) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.maxLifetime = lifetime
+}
+
+// ExpireStaleOrders cancels every resting order older than the maximum
+// order lifetime, returning the number cancelled. Stop and conditional
+// orders that have never triggered expire the same way. It is meant to be
+// run periodically as housekeeping against abandoned orders.
+func (me *MatchingEngine) ExpireStaleOrders() int {
+	me.mutex.RLock()
+	lifetime := me.maxLifetime
+	books := make([]*orderbook.OrderBook, 0, len(me.orderBooks))
+	for _, ob := range me.orderBooks {
+		books = append(books, ob)
+	}
+	me.mutex.RUnlock()
+
+	if lifetime <= 0 {
+		return 0
+	}
+
+	cutoff := me.clock.Now().Add(-lifetime)
+	expired := 0
+	for _, order := range me.untriggeredBefore(cutoff) {
+		if me.CancelOrder(order.Symbol, order.ID) {
+			expired++
+		}
+	}
+	for _, ob := range books {
+		for _, orderID := range ob.OrderIDs() {
+			order, exists := ob.GetOrder(orderID)
+			if !exists || !order.SubmittedAt.Before(cutoff) {
+				continue
+			}
+			if me.CancelOrder(ob.Symbol, orderID) {
+				expired++
+			}
+		}
+	}
+	return expired
+}
+
+// untriggeredBefore returns the stop and conditional orders submitted before
+// cutoff that are still waiting for their trigger
+func (me *MatchingEngine) untriggeredBefore(cutoff time.Time) []*models.Order {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	orders := make([]*models.Order, 0)
+	for _, stops := range me.stopOrders {
+		for _, order := range stops {
+			if order.SubmittedAt.Before(cutoff) {
+				orders = append(orders, order)
+			}
+		}
+	}
+	for _, pending := range me.conditionals {
+		for _, co := range pending {
+			if co.Order.SubmittedAt.Before(cutoff) {
+				orders = append(orders, co.Order)
+			}
+		}
+	}
+	return orders
+}
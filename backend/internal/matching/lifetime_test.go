@@ -0,0 +1,78 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestExpireStaleOrders(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+
+	old := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0)
+	me.SubmitOrder(old)
+
+	// Disabled by default
+	mock.Advance(48 * time.Hour)
+	if expired := me.ExpireStaleOrders(); expired != 0 {
+		t.Errorf("Expected no expiry without a lifetime, got %d", expired)
+	}
+
+	me.SetMaxOrderLifetime(24 * time.Hour)
+	fresh := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0)
+	me.SubmitOrder(fresh)
+	mock.Advance(time.Hour)
+
+	if expired := me.ExpireStaleOrders(); expired != 1 {
+		t.Errorf("Expected 1 order expired, got %d", expired)
+	}
+	if old.Status != models.OrderStatusCancelled || old.CancelledAt == nil {
+		t.Errorf("Expected the old order to be cancelled, got %s", old.Status)
+	}
+	if fresh.Status != models.OrderStatusPending {
+		t.Errorf("Expected the fresh order to survive, got %s", fresh.Status)
+	}
+	if _, resting := me.GetOrderBook("AAPL").GetOrder(fresh.ID); !resting {
+		t.Error("Expected the fresh order to still rest in the book")
+	}
+}
+
+func TestExpireUntriggeredStops(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetMaxOrderLifetime(time.Hour)
+	printTrade(me, 100.0, 10)
+	me.GetOrCreateOrderBook("SPY")
+
+	stop := me.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideSell, 10, 90.0)
+	me.SubmitOrder(stop)
+	conditional := me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	if err := me.SubmitConditional(conditional, "SPY", TriggerAtOrAbove, 500.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mock.Advance(30 * time.Minute)
+	if expired := me.ExpireStaleOrders(); expired != 0 {
+		t.Errorf("Expected nothing expired within the lifetime, got %d", expired)
+	}
+
+	mock.Advance(time.Hour)
+	if expired := me.ExpireStaleOrders(); expired != 2 {
+		t.Errorf("Expected both untriggered orders expired, got %d", expired)
+	}
+	for _, order := range []*models.Order{stop, conditional} {
+		if order.Status != models.OrderStatusCancelled || order.CancelledAt == nil || !order.CancelledAt.Equal(mock.Now()) {
+			t.Errorf("Expected the order cancelled at %s, got %s at %v", mock.Now(), order.Status, order.CancelledAt)
+		}
+	}
+	if pending := me.GetPendingConditionals("AAPL"); len(pending) != 0 {
+		t.Errorf("Expected nothing left pending, got %d", len(pending))
+	}
+}
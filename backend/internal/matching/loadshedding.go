@@ -0,0 +1,71 @@
+package matching
+
+import (
+	"math"
+	"sort"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// LoadSheddingConfig tightens order acceptance when a book grows large
+type LoadSheddingConfig struct {
+	MaxOrders   int     // Resting orders per book before shedding starts, 0 to disable
+	MaxDistance float64 // Largest distance from the touch accepted while shedding, e.g. 0.01 for 1%
+}
+
+// SetLoadShedding sets the depth thresholds used to shed far-from-touch orders
+func (me *MatchingEngine) SetLoadShedding(config LoadSheddingConfig) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.loadShedding = config
+}
+
+// DegradedSymbols returns the symbols whose books are past the shedding
+// threshold, sorted
+func (me *MatchingEngine) DegradedSymbols() []string {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	degraded := make([]string, 0)
+	for symbol, ob := range me.orderBooks {
+		if me.isDegraded(ob) {
+			degraded = append(degraded, symbol)
+		}
+	}
+	sort.Strings(degraded)
+	return degraded
+}
+
+// isDegraded reports whether a book is past the shedding threshold. The
+// caller must hold the engine mutex.
+func (me *MatchingEngine) isDegraded(ob *orderbook.OrderBook) bool {
+	return me.loadShedding.MaxOrders > 0 && ob.OrderCount() >= me.loadShedding.MaxOrders
+}
+
+// shedOrder reports whether an incoming order should be rejected because its
+// book is degraded and the order would rest far from the touch
+func (me *MatchingEngine) shedOrder(ob *orderbook.OrderBook, order *models.Order) bool {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	if order.Type == models.OrderTypeMarket || !me.isDegraded(ob) {
+		return false
+	}
+
+	// Measure from the order's own side of the book, falling back to the
+	// other side when that side is empty
+	touch, other := ob.GetBestBid(), ob.GetBestAsk()
+	if order.Side == models.OrderSideSell {
+		touch, other = other, touch
+	}
+	if touch == 0 {
+		touch = other
+	}
+	if touch == 0 {
+		return false
+	}
+
+	return math.Abs(order.Price-touch)/touch > me.loadShedding.MaxDistance
+}
@@ -0,0 +1,46 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestLoadSheddingPastDepthThreshold(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetLoadShedding(LoadSheddingConfig{MaxOrders: 5, MaxDistance: 0.01})
+
+	// Far orders are accepted while the book is small
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	for i := 0; i < 3; i++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+	}
+	far := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 80.0)
+	me.SubmitOrder(far)
+	if far.Status == models.OrderStatusRejected {
+		t.Fatal("Expected far order to be accepted below the threshold")
+	}
+
+	if degraded := me.DegradedSymbols(); len(degraded) != 1 || degraded[0] != "AAPL" {
+		t.Fatalf("Expected AAPL to be degraded at 5 orders, got %v", degraded)
+	}
+
+	far = models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 90.0)
+	me.SubmitOrder(far)
+	if far.Status != models.OrderStatusRejected {
+		t.Errorf("Expected far order to be shed, got %s", far.Status)
+	}
+
+	near := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.5)
+	me.SubmitOrder(near)
+	if near.Status == models.OrderStatusRejected {
+		t.Error("Expected near-touch order to be accepted while degraded")
+	}
+
+	// Market orders always get through since they only remove depth
+	market := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	me.SubmitOrder(market)
+	if market.Status == models.OrderStatusRejected {
+		t.Error("Expected market order to be accepted while degraded")
+	}
+}
@@ -0,0 +1,123 @@
+package matching
+
+import (
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// MakerStats measures how an account has quoted a symbol since maker
+// tracking was enabled, for running a liquidity program
+type MakerStats struct {
+	QuotedTime    time.Duration `json:"quoted_ns"`      // Time with any displayed order resting
+	TimeAtBBO     time.Duration `json:"time_at_bbo_ns"` // Time quoting the best bid or offer
+	TwoSidedTime  time.Duration `json:"two_sided_ns"`   // Time quoting both sides
+	AverageSize   float64       `json:"average_size"`   // Time-weighted size shown at the account's best prices
+	AverageSpread float64       `json:"average_spread"` // Time-weighted spread of the account's own quotes while two-sided
+	Since         time.Time     `json:"since"`
+}
+
+// makerTotals accumulates one account's quoting in one symbol
+type makerTotals struct {
+	quoted, atBBO, twoSided    time.Duration
+	sizeSeconds, spreadSeconds float64
+}
+
+// makerSymbol holds a symbol's quotes as of the last book change, and the
+// totals they have accrued
+type makerSymbol struct {
+	since   time.Time
+	updated time.Time
+	bbo     orderbook.BBO
+	quotes  map[string]orderbook.BBO
+	totals  map[string]*makerTotals
+}
+
+// SetMakerTracking turns per-account quoting metrics on or off. Turning it
+// off discards what has been gathered.
+func (me *MatchingEngine) SetMakerTracking(enabled bool) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	if !enabled {
+		me.makers = nil
+		return
+	}
+	if me.makers == nil {
+		me.makers = make(map[string]*makerSymbol)
+	}
+}
+
+// MakerMetrics returns an account's quoting metrics for a symbol, up to the
+// current time
+func (me *MatchingEngine) MakerMetrics(accountID, symbol string) MakerStats {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	tracked, exists := me.makers[symbol]
+	if !exists {
+		return MakerStats{}
+	}
+	tracked.accrue(me.clock.Now())
+
+	stats := MakerStats{Since: tracked.since}
+	if totals, exists := tracked.totals[accountID]; exists {
+		stats.QuotedTime = totals.quoted
+		stats.TimeAtBBO = totals.atBBO
+		stats.TwoSidedTime = totals.twoSided
+		if totals.quoted > 0 {
+			stats.AverageSize = totals.sizeSeconds / totals.quoted.Seconds()
+		}
+		if totals.twoSided > 0 {
+			stats.AverageSpread = totals.spreadSeconds / totals.twoSided.Seconds()
+		}
+	}
+	return stats
+}
+
+// trackMakers accrues a symbol's quoting up to now and records the book's
+// new quotes. The caller must hold the mutex.
+func (me *MatchingEngine) trackMakers(ob *orderbook.OrderBook, bbo orderbook.BBO) {
+	if me.makers == nil {
+		return
+	}
+
+	now := me.clock.Now()
+	tracked, exists := me.makers[ob.Symbol]
+	if !exists {
+		tracked = &makerSymbol{since: now, updated: now, totals: make(map[string]*makerTotals)}
+		me.makers[ob.Symbol] = tracked
+	}
+	tracked.accrue(now)
+	tracked.bbo = bbo
+	tracked.quotes = ob.BBOByAccount()
+}
+
+// accrue credits every quoting account with the time since the last update
+func (ms *makerSymbol) accrue(now time.Time) {
+	elapsed := now.Sub(ms.updated)
+	ms.updated = now
+	if elapsed <= 0 {
+		return
+	}
+
+	for accountID, quote := range ms.quotes {
+		totals, exists := ms.totals[accountID]
+		if !exists {
+			totals = &makerTotals{}
+			ms.totals[accountID] = totals
+		}
+
+		totals.quoted += elapsed
+		totals.sizeSeconds += (quote.BidQuantity + quote.AskQuantity) * elapsed.Seconds()
+		if (quote.BidPrice > 0 && quote.BidPrice == ms.bbo.BidPrice) || (quote.AskPrice > 0 && quote.AskPrice == ms.bbo.AskPrice) {
+			totals.atBBO += elapsed
+		}
+		if quote.BidPrice > 0 && quote.AskPrice > 0 {
+			totals.twoSided += elapsed
+			totals.spreadSeconds += (quote.AskPrice - quote.BidPrice) * elapsed.Seconds()
+		}
+	}
+}
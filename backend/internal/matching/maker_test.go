@@ -0,0 +1,67 @@
+package matching
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestMakerMetricsTimeAtBBO(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetMakerTracking(true)
+
+	submit := func(accountID string, side models.OrderSide, quantity, price float64) *models.Order {
+		order := models.NewOrder("AAPL", models.OrderTypeLimit, side, quantity, price)
+		order.AccountID = accountID
+		me.SubmitOrder(order)
+		return order
+	}
+
+	// The maker holds the best bid and an offer behind someone else's
+	submit("maker", models.OrderSideBuy, 100, 150.0)
+	submit("maker", models.OrderSideSell, 100, 152.0)
+	submit("other", models.OrderSideSell, 50, 151.0)
+	mock.Advance(10 * time.Second)
+
+	// A better bid from elsewhere takes the maker off the touch
+	better := submit("other", models.OrderSideBuy, 10, 150.5)
+	mock.Advance(5 * time.Second)
+	me.CancelOrder("AAPL", better.ID)
+	mock.Advance(5 * time.Second)
+
+	stats := me.MakerMetrics("maker", "AAPL")
+	if stats.QuotedTime != 20*time.Second {
+		t.Errorf("Expected 20s quoted, got %v", stats.QuotedTime)
+	}
+	if stats.TimeAtBBO != 15*time.Second {
+		t.Errorf("Expected 15s at the BBO, got %v", stats.TimeAtBBO)
+	}
+	if stats.TwoSidedTime != 20*time.Second {
+		t.Errorf("Expected 20s two-sided, got %v", stats.TwoSidedTime)
+	}
+	if math.Abs(stats.AverageSpread-2.0) > 1e-9 || math.Abs(stats.AverageSize-200) > 1e-9 {
+		t.Errorf("Expected spread 2 and size 200, got %f and %f", stats.AverageSpread, stats.AverageSize)
+	}
+
+	if idle := me.MakerMetrics("idle", "AAPL"); idle.QuotedTime != 0 || idle.TimeAtBBO != 0 {
+		t.Errorf("Expected nothing accrued for a non-quoting account, got %+v", idle)
+	}
+}
+
+func TestMakerMetricsOffByDefault(t *testing.T) {
+	me := NewMatchingEngine()
+
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	order.AccountID = "maker"
+	me.SubmitOrder(order)
+
+	if stats := me.MakerMetrics("maker", "AAPL"); stats != (MakerStats{}) {
+		t.Errorf("Expected no metrics without tracking, got %+v", stats)
+	}
+}
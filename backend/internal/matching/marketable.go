@@ -0,0 +1,32 @@
+package matching
+
+import "github.com/acagliol/arbitrax/backend/internal/models"
+
+// checkMarketable returns why a limit order that would trade on arrival
+// can't be accepted without confirming it may rest, or "" if it can
+func (me *MatchingEngine) checkMarketable(order *models.Order) string {
+	if order.Type != models.OrderTypeLimit || order.ConfirmRest || order.IsImmediate() {
+		return ""
+	}
+	if !me.GetSymbolConfig(order.Symbol).ConfirmMarketable || !me.wouldCross(order) {
+		return ""
+	}
+	return "marketable limit order must confirm that its remainder may rest at its limit"
+}
+
+// sweepLimit returns the furthest price an order on side may sweep to from
+// the first price it meets, given the fraction it may move
+func sweepLimit(side models.OrderSide, first, fraction float64) float64 {
+	if side == models.OrderSideSell {
+		return first * (1 - fraction)
+	}
+	return first * (1 + fraction)
+}
+
+// beyondSweep reports whether a price is past an order's sweep limit
+func beyondSweep(side models.OrderSide, price, limit float64) bool {
+	if side == models.OrderSideSell {
+		return price < limit
+	}
+	return price > limit
+}
@@ -0,0 +1,70 @@
+package matching
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// laddered rests asks of 10 at each price from 150 to 158
+func laddered(me *MatchingEngine) {
+	for price := 150.0; price <= 158.0; price++ {
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, price))
+	}
+}
+
+func TestConfirmedMarketableLimitSweepsAndRests(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{ConfirmMarketable: true})
+	laddered(me)
+
+	unconfirmed := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 120, 160.0)
+	if trades := me.SubmitOrder(unconfirmed); len(trades) != 0 || unconfirmed.Status != models.OrderStatusRejected {
+		t.Fatalf("Expected an unconfirmed marketable limit rejected, got %s with %d trades", unconfirmed.Status, len(trades))
+	}
+
+	// Passive and immediate orders need no confirmation
+	passive := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 149.0)
+	me.SubmitOrder(passive)
+	ioc := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 150.0)
+	ioc.TimeInForce = models.TimeInForceIOC
+	me.SubmitOrder(ioc)
+	if passive.Status == models.OrderStatusRejected || ioc.Status != models.OrderStatusFilled {
+		t.Errorf("Expected the passive order to rest and the IOC to fill, got %s and %s", passive.Status, ioc.Status)
+	}
+
+	confirmed := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 120, 160.0)
+	confirmed.ConfirmRest = true
+	trades := me.SubmitOrder(confirmed)
+	summary := me.SummarizeOrder(confirmed, trades)
+	if summary.FilledQuantity != 85 || summary.RestedQuantity != 35 {
+		t.Errorf("Expected 85 executed and 35 rested, got %g and %g", summary.FilledQuantity, summary.RestedQuantity)
+	}
+	if ob := me.GetOrderBook("AAPL"); ob.GetBestBid() != 160.0 || ob.GetBestAsk() != 0 {
+		t.Errorf("Expected the sweep to clear 150-158 and rest at 160, got %g / %g", ob.GetBestBid(), ob.GetBestAsk())
+	}
+}
+
+func TestSweepCapStopsMarketableLimit(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{MaxSweep: 0.03})
+	laddered(me)
+
+	buy := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 120, 160.0)
+	trades := me.SubmitOrder(buy)
+
+	// 3% from 150 allows up to 154.5
+	if len(trades) != 5 || trades[4].Price != 154.0 {
+		t.Fatalf("Expected the sweep to stop after 154, got %d trades", len(trades))
+	}
+	if buy.Status != models.OrderStatusCancelled || buy.CancelledQuantity != 70 {
+		t.Errorf("Expected the remaining 70 cancelled, got %s with %g", buy.Status, buy.CancelledQuantity)
+	}
+	if len(buy.Warnings) != 1 || !strings.Contains(buy.Warnings[0], "sweep cap") {
+		t.Errorf("Expected a sweep cap warning, got %v", buy.Warnings)
+	}
+	if ob := me.GetOrderBook("AAPL"); ob.GetBestBid() != 0 || ob.GetBestAsk() != 155.0 {
+		t.Errorf("Expected nothing resting through the book, got %g / %g", ob.GetBestBid(), ob.GetBestAsk())
+	}
+}
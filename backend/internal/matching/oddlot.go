@@ -0,0 +1,24 @@
+package matching
+
+import "github.com/acagliol/arbitrax/backend/internal/models"
+
+// OddLotRule sets how trades smaller than a round lot are reported. Odd lots
+// always execute and are recorded internally either way.
+type OddLotRule struct {
+	RoundLot          float64 // Trades below this size are odd lots, 0 to disable
+	SuppressLastPrice bool    // Odd lots don't update the book's last price
+	SuppressPrint     bool    // Odd lots don't appear on the public tape
+}
+
+// printable drops odd lots that their symbol keeps off the public tape. The
+// caller must hold the mutex.
+func (me *MatchingEngine) printable(trades []*models.Trade) []*models.Trade {
+	printed := make([]*models.Trade, 0, len(trades))
+	for _, trade := range trades {
+		if trade.OddLot && me.symbolConfigs[trade.Symbol].OddLots.SuppressPrint {
+			continue
+		}
+		printed = append(printed, trade)
+	}
+	return printed
+}
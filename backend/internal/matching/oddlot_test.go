@@ -0,0 +1,49 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestOddLotDoesNotMoveLastPrice(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{
+		OddLots: OddLotRule{RoundLot: 100, SuppressLastPrice: true, SuppressPrint: true},
+	})
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0))
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 151.0))
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 50, 151.0))
+
+	if len(trades) != 1 || !trades[0].OddLot {
+		t.Fatalf("Expected the odd lot to execute, got %d trades", len(trades))
+	}
+	if last := me.GetOrderBook("AAPL").LastPrice; last != 150.0 {
+		t.Errorf("Expected last price to stay at 150, got %v", last)
+	}
+
+	if recent := me.GetRecentTrades("AAPL", 10); len(recent) != 2 {
+		t.Errorf("Expected both trades recorded internally, got %d", len(recent))
+	}
+	if public := me.GetPublicTrades("AAPL", 10); len(public) != 1 || public[0].Price != 150.0 {
+		t.Errorf("Expected only the round lot on the public tape, got %d trades", len(public))
+	}
+}
+
+func TestOddLotReportedByDefault(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{OddLots: OddLotRule{RoundLot: 100}})
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 151.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 50, 151.0))
+
+	if last := me.GetOrderBook("AAPL").LastPrice; last != 151.0 {
+		t.Errorf("Expected odd lot to set the last price, got %v", last)
+	}
+	if public := me.GetPublicTrades("AAPL", 10); len(public) != 1 || !public[0].OddLot {
+		t.Errorf("Expected the odd lot on the public tape, got %d trades", len(public))
+	}
+}
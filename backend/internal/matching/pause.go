@@ -0,0 +1,159 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// PauseMode decides what happens to aggressive orders while a symbol's
+// matching is paused. Orders that would not cross rest as usual either way.
+type PauseMode string
+
+const (
+	// PauseQueue holds aggressive orders and submits them in arrival order
+	// when matching resumes. Immediate-or-cancel and minimum-fill orders are
+	// cancelled instead.
+	PauseQueue PauseMode = "queue"
+	// PauseReject rejects aggressive orders
+	PauseReject PauseMode = "reject"
+)
+
+// matchingPause is a maintenance pause on one symbol
+type matchingPause struct {
+	mode   PauseMode
+	queued []*models.Order // Aggressive orders held for resume, oldest first
+}
+
+// PauseMatching stops a symbol from matching for a maintenance window.
+// Unlike a halt it keeps accepting orders and leaves resting orders alone:
+// only orders that would trade are queued or rejected, depending on mode.
+func (me *MatchingEngine) PauseMatching(symbol string, mode PauseMode) error {
+	switch mode {
+	case PauseQueue, PauseReject:
+	default:
+		return fmt.Errorf("unknown pause mode %q", mode)
+	}
+
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	if pause, exists := me.paused[symbol]; exists {
+		pause.mode = mode
+		return nil
+	}
+	me.paused[symbol] = &matchingPause{mode: mode}
+	return nil
+}
+
+// ResumeMatching lifts a symbol's maintenance pause and submits any queued
+// orders in the order they arrived, returning the trades they made
+func (me *MatchingEngine) ResumeMatching(symbol string) []*models.Trade {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	pause, exists := me.paused[symbol]
+	delete(me.paused, symbol)
+	me.mutex.Unlock()
+
+	trades := make([]*models.Trade, 0)
+	if !exists {
+		return trades
+	}
+	for _, order := range pause.queued {
+		trades = append(trades, me.submitOrder(order, nil)...)
+	}
+	return trades
+}
+
+// IsMatchingPaused returns true if a symbol is in a maintenance pause
+func (me *MatchingEngine) IsMatchingPaused(symbol string) bool {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	_, exists := me.paused[symbol]
+	return exists
+}
+
+// holdPaused queues or rejects an order that would trade while its symbol
+// is paused, returning true if it did either. An order that must trade at
+// once is cancelled rather than queued, as it could only trade on resume.
+func (me *MatchingEngine) holdPaused(order *models.Order) bool {
+	me.mutex.RLock()
+	_, paused := me.paused[order.Symbol]
+	me.mutex.RUnlock()
+
+	if !paused || !me.wouldCross(order) {
+		return false
+	}
+
+	me.mutex.Lock()
+	// Resumed while the book was being checked
+	pause, exists := me.paused[order.Symbol]
+	switch {
+	case !exists:
+		me.mutex.Unlock()
+		return false
+	case pause.mode == PauseReject:
+		order.Reject("matching is paused for maintenance")
+	case order.IsImmediate():
+		order.CancelRemainder(me.clock.Now(), "matching is paused for maintenance")
+	default:
+		pause.queued = append(pause.queued, order)
+	}
+	me.mutex.Unlock()
+
+	me.recordLatency(order)
+	return true
+}
+
+// wouldCross reports whether an order would trade against the book on
+// arrival. Post-only orders never take liquidity, so they never cross.
+func (me *MatchingEngine) wouldCross(order *models.Order) bool {
+	if order.PostOnly {
+		return false
+	}
+
+	ob := me.GetOrderBook(order.Symbol)
+	if ob == nil {
+		return false
+	}
+
+	if order.Side == models.OrderSideBuy {
+		bestAsk := ob.GetBestAsk()
+		return bestAsk > 0 && (order.Type == models.OrderTypeMarket || order.Price >= bestAsk || me.tolerance.PricesEqual(order.Price, bestAsk))
+	}
+	bestBid := ob.GetBestBid()
+	return bestBid > 0 && (order.Type == models.OrderTypeMarket || order.Price <= bestBid || me.tolerance.PricesEqual(order.Price, bestBid))
+}
+
+// cancelQueued cancels an order queued by a maintenance pause, reporting
+// whether there was one
+func (me *MatchingEngine) cancelQueued(symbol string, orderID uuid.UUID) bool {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	var order *models.Order
+	if pause, exists := me.paused[symbol]; exists {
+		for i, queued := range pause.queued {
+			if queued.ID == orderID {
+				order = queued
+				pause.queued = append(pause.queued[:i:i], pause.queued[i+1:]...)
+				break
+			}
+		}
+	}
+	me.mutex.Unlock()
+
+	if order == nil || !order.IsActive() {
+		return false
+	}
+	order.Cancel(me.clock.Now())
+	me.emit(Event{Type: EventOrderCancelled, Symbol: symbol, OrderID: orderID})
+	return true
+}
@@ -0,0 +1,99 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestPauseMatchingQueuesCrossingOrders(t *testing.T) {
+	me := NewMatchingEngine()
+	ask := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0)
+	me.SubmitOrder(ask)
+
+	if err := me.PauseMatching("AAPL", PauseQueue); err != nil {
+		t.Fatalf("Expected pause to succeed, got %v", err)
+	}
+
+	crossing := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 101.0)
+	if trades := me.SubmitOrder(crossing); len(trades) != 0 {
+		t.Errorf("Expected no trades while paused, got %d", len(trades))
+	}
+	if crossing.Status != models.OrderStatusPending || crossing.FilledQuantity != 0 {
+		t.Errorf("Expected the crossing order queued unfilled, got %s", crossing.Status)
+	}
+
+	// Passive orders still rest, and resting orders are kept
+	passive := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 99.0)
+	me.SubmitOrder(passive)
+	ob := me.GetOrderBook("AAPL")
+	if _, resting := ob.GetOrder(passive.ID); !resting {
+		t.Error("Expected a passive order to rest while paused")
+	}
+	if _, resting := ob.GetOrder(ask.ID); !resting {
+		t.Error("Expected the resting ask to survive the pause")
+	}
+	if me.IsHalted("AAPL") {
+		t.Error("Expected a pause not to halt the symbol")
+	}
+
+	trades := me.ResumeMatching("AAPL")
+	if len(trades) != 1 || trades[0].BuyOrderID != crossing.ID || trades[0].Price != 101.0 {
+		t.Fatalf("Expected the queued order to trade on resume, got %d trades", len(trades))
+	}
+	if crossing.Status != models.OrderStatusFilled || me.IsMatchingPaused("AAPL") {
+		t.Errorf("Expected the queued order filled and matching resumed, got %s", crossing.Status)
+	}
+}
+
+func TestPauseMatchingRejectsCrossingOrders(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	me.PauseMatching("AAPL", PauseReject)
+
+	market := me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	me.SubmitOrder(market)
+	if market.Status != models.OrderStatusRejected {
+		t.Errorf("Expected a crossing order rejected while paused, got %s", market.Status)
+	}
+
+	if trades := me.ResumeMatching("AAPL"); len(trades) != 0 {
+		t.Errorf("Expected nothing queued to trade on resume, got %d", len(trades))
+	}
+	if err := me.PauseMatching("AAPL", "sometimes"); err == nil {
+		t.Error("Expected an unknown pause mode to be rejected")
+	}
+}
+
+func TestPauseMatchingCancelsImmediateOrders(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	me.PauseMatching("AAPL", PauseQueue)
+
+	ioc := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 101.0)
+	ioc.TimeInForce = models.TimeInForceIOC
+	me.SubmitOrder(ioc)
+	if ioc.Status != models.OrderStatusCancelled || ioc.CancelledQuantity != 10 {
+		t.Errorf("Expected the IOC order cancelled rather than queued, got %s", ioc.Status)
+	}
+
+	if trades := me.ResumeMatching("AAPL"); len(trades) != 0 {
+		t.Errorf("Expected nothing queued to trade on resume, got %d trades", len(trades))
+	}
+}
+
+func TestCancelQueuedOrder(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+	me.PauseMatching("AAPL", PauseQueue)
+
+	crossing := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 101.0)
+	me.SubmitOrder(crossing)
+
+	if !me.CancelOrder("AAPL", crossing.ID) || crossing.Status != models.OrderStatusCancelled {
+		t.Fatalf("Expected the queued order cancelled, got %s", crossing.Status)
+	}
+	if trades := me.ResumeMatching("AAPL"); len(trades) != 0 {
+		t.Errorf("Expected the cancelled order not to trade on resume, got %d trades", len(trades))
+	}
+}
@@ -0,0 +1,148 @@
+package matching
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/google/uuid"
+)
+
+// pegReference reports whether an order is ignored when working out the
+// prices pegs follow. Pegs track the displayed book, not each other.
+func pegReference(order *models.Order) bool {
+	return order.Peg != "" || order.Hidden
+}
+
+// pegPrice returns the price a pegged order should rest at given the book's
+// unpegged, displayed best bid and ask, snapped to tick on the passive side
+// when tick is set, or false if there is nothing to peg to
+func pegPrice(order *models.Order, bid, ask, tick float64) (float64, bool) {
+	var reference float64
+	switch order.Peg {
+	case models.PegMidpoint:
+		if bid == 0 || ask == 0 {
+			return 0, false
+		}
+		reference = (bid + ask) / 2
+	case models.PegPrimary:
+		reference = bid
+		if order.Side == models.OrderSideSell {
+			reference = ask
+		}
+		if reference == 0 {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	price := reference - order.PegOffset
+	if order.Side == models.OrderSideBuy {
+		price = reference + order.PegOffset
+	}
+	if tick > 0 {
+		price = snapToTick(price, tick, order.Side == models.OrderSideSell)
+	}
+	return price, true
+}
+
+// clampPeg pulls a re-priced peg back to the nearest tick that neither locks
+// nor crosses the touch, or returns false to leave it where it is if there
+// is no tick size to step back by
+func clampPeg(order *models.Order, price float64, touch orderbook.Quote, tick float64, tolerance models.Tolerance) (float64, bool) {
+	buy := order.Side == models.OrderSideBuy
+	opposite := touch.Bid
+	if buy {
+		opposite = touch.Ask
+	}
+	if opposite == 0 || (buy && price < opposite || !buy && price > opposite) && !tolerance.PricesEqual(price, opposite) {
+		return price, true
+	}
+	if tick <= 0 {
+		return 0, false
+	}
+	return tickInside(opposite, tick, buy, tolerance), true
+}
+
+// tickInside returns the nearest on-tick price strictly below price, or
+// strictly above it if below is false
+func tickInside(price, tick float64, below bool, tolerance models.Tolerance) float64 {
+	snapped := snapToTick(price, tick, !below)
+	if !tolerance.PricesEqual(snapped, price) {
+		return snapped
+	}
+	if below {
+		return tickMultiple(math.Round(price/tick)-1, tick)
+	}
+	return tickMultiple(math.Round(price/tick)+1, tick)
+}
+
+// pricePeg sets an incoming pegged order's price from the book, returning a
+// reason if it can't be pegged or "" if it can
+func (me *MatchingEngine) pricePeg(order *models.Order) string {
+	if order.Type != models.OrderTypeLimit {
+		return "only limit orders can be pegged"
+	}
+	if order.Peg != models.PegMidpoint && order.Peg != models.PegPrimary {
+		return fmt.Sprintf("unknown peg %q", order.Peg)
+	}
+	if math.IsNaN(order.PegOffset) || math.IsInf(order.PegOffset, 0) {
+		return "peg offset must be a finite number"
+	}
+
+	ob := me.GetOrderBook(order.Symbol)
+	if ob == nil {
+		return fmt.Sprintf("no %s price to peg to", order.Peg)
+	}
+	bid, ask := ob.BestPricesExcluding(pegReference)
+	price, ok := pegPrice(order, bid, ask, me.GetSymbolConfig(order.Symbol).TickSize)
+	if !ok || price <= 0 {
+		return fmt.Sprintf("no %s price to peg to", order.Peg)
+	}
+	order.Price = price
+	return ""
+}
+
+// repricePegs tracks an order that has come to rest pegged, if one is given,
+// then moves every resting pegged order on the book to its current peg, all
+// under the book's lock. A peg that would lock or cross the book is held a
+// tick inside it, or stays put if the symbol has no tick size.
+func (me *MatchingEngine) repricePegs(ob *orderbook.OrderBook, incoming *models.Order) {
+	me.mutex.Lock()
+	pegs := me.pegged[ob.Symbol]
+	if incoming != nil && incoming.Peg != "" && incoming.IsActive() {
+		if _, resting := ob.GetOrder(incoming.ID); resting {
+			if pegs == nil {
+				pegs = make(map[uuid.UUID]*models.Order)
+				me.pegged[ob.Symbol] = pegs
+			}
+			pegs[incoming.ID] = incoming
+		}
+	}
+	orders := make([]*models.Order, 0, len(pegs))
+	for id, order := range pegs {
+		if !order.IsActive() {
+			delete(pegs, id)
+			continue
+		}
+		orders = append(orders, order)
+	}
+	me.mutex.Unlock()
+
+	if len(orders) == 0 {
+		return
+	}
+
+	// Re-pricing takes each order out and back in, so it joins the back of
+	// its new level
+	tick := me.GetSymbolConfig(ob.Symbol).TickSize
+	ob.Reprice(orders, pegReference, func(order *models.Order, reference, touch orderbook.Quote) (float64, bool) {
+		price, ok := pegPrice(order, reference.Bid, reference.Ask, tick)
+		if !ok {
+			return 0, false
+		}
+		return clampPeg(order, price, touch, tick, me.tolerance)
+	})
+}
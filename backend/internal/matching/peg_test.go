@@ -0,0 +1,117 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestMidpointPegFollowsTheMid(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+
+	pegged := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 0)
+	pegged.Peg = models.PegMidpoint
+	me.SubmitOrder(pegged)
+	if pegged.Status == models.OrderStatusRejected || pegged.Price != 100.0 {
+		t.Fatalf("Expected the peg to rest at the mid of 100, got %g (%s)", pegged.Price, pegged.RejectReason)
+	}
+
+	// A better bid lifts the mid to 100.5
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+	if pegged.Price != 100.5 {
+		t.Errorf("Expected the peg to re-price up to 100.5, got %g", pegged.Price)
+	}
+
+	trades := me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.5))
+	if len(trades) != 1 || trades[0].BuyOrderID != pegged.ID || trades[0].Price != 100.5 {
+		t.Fatalf("Expected the incoming sell to fill the peg at 100.5, got %d trades", len(trades))
+	}
+	if pegged.Status != models.OrderStatusFilled {
+		t.Errorf("Expected the peg filled, got %s", pegged.Status)
+	}
+}
+
+func TestPrimaryPegWithOffset(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	ask := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 102.0)
+	me.SubmitOrder(ask)
+
+	pegged := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 0)
+	pegged.Peg = models.PegPrimary
+	pegged.PegOffset = 0.5
+	me.SubmitOrder(pegged)
+	if pegged.Price != 101.5 {
+		t.Fatalf("Expected the peg half a point inside the offer at 101.5, got %g", pegged.Price)
+	}
+
+	// Cancelling the offer leaves nothing to peg to, so the order stays put
+	me.CancelOrder("AAPL", ask.ID)
+	if pegged.Price != 101.5 {
+		t.Errorf("Expected the peg to hold at 101.5 with no offer, got %g", pegged.Price)
+	}
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 103.0))
+	if pegged.Price != 102.5 {
+		t.Errorf("Expected the peg to follow the new offer to 102.5, got %g", pegged.Price)
+	}
+}
+
+func TestPegRejectedWithoutReference(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+
+	pegged := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 0)
+	pegged.Peg = models.PegMidpoint
+	me.SubmitOrder(pegged)
+	if pegged.Status != models.OrderStatusRejected {
+		t.Errorf("Expected a midpoint peg rejected on a one-sided book, got %s", pegged.Status)
+	}
+}
+
+func TestPegsSnapToTickAndNeverCross(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: 0.01})
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	ask := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 99.03)
+	me.SubmitOrder(ask)
+
+	// The mid of 99.015 is off-tick, so each side rounds away from the touch
+	buyMid := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 0)
+	buyMid.Peg = models.PegMidpoint
+	me.SubmitOrder(buyMid)
+	sellMid := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 0)
+	sellMid.Peg = models.PegMidpoint
+	me.SubmitOrder(sellMid)
+	if !models.DefaultTolerance.PricesEqual(buyMid.Price, 99.01) || !models.DefaultTolerance.PricesEqual(sellMid.Price, 99.02) {
+		t.Fatalf("Expected midpoint pegs at 99.01 and 99.02, got %g and %g", buyMid.Price, sellMid.Price)
+	}
+
+}
+
+func TestRepricedPegIsHeldInsideTheTouch(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: 0.01})
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 99.10))
+
+	primary := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 0)
+	primary.Peg = models.PegPrimary
+	primary.PegOffset = 0.05
+	me.SubmitOrder(primary)
+	if !models.DefaultTolerance.PricesEqual(primary.Price, 99.05) {
+		t.Fatalf("Expected the primary peg at 99.05, got %g", primary.Price)
+	}
+
+	// A better bid would take the peg to 99.13, through the offer, so it
+	// stops a tick short of it
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.08))
+	if !models.DefaultTolerance.PricesEqual(primary.Price, 99.09) {
+		t.Errorf("Expected the peg held a tick inside the offer at 99.09, got %g", primary.Price)
+	}
+	if errs := me.GetOrderBook("AAPL").Validate(); len(errs) != 0 {
+		t.Errorf("Expected a valid uncrossed book, got %v", errs)
+	}
+}
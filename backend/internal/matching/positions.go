@@ -0,0 +1,119 @@
+package matching
+
+import (
+	"math"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// Position is an account's net holding and PnL in a symbol
+type Position struct {
+	AccountID     string  `json:"account_id"`
+	Symbol        string  `json:"symbol"`
+	Quantity      float64 `json:"quantity"` // Negative when short
+	AveragePrice  float64 `json:"average_price"`
+	RealizedPnL   float64 `json:"realized_pnl"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+}
+
+// apply updates the position for a fill of signed quantity at price. A
+// position within epsilon of zero is flat, so fill rounding can't leave a
+// dust position behind.
+func (p *Position) apply(quantity, price, epsilon float64) {
+	// Opening or adding to the position moves the average entry price
+	if math.Abs(p.Quantity) <= epsilon {
+		p.Quantity, p.AveragePrice = 0, 0
+	}
+	if p.Quantity == 0 || (p.Quantity > 0) == (quantity > 0) {
+		total := math.Abs(p.Quantity) + math.Abs(quantity)
+		p.AveragePrice = (p.AveragePrice*math.Abs(p.Quantity) + price*math.Abs(quantity)) / total
+		p.Quantity += quantity
+		return
+	}
+
+	// Reducing the position realizes PnL on the closed quantity
+	closed := math.Min(math.Abs(quantity), math.Abs(p.Quantity))
+	direction := 1.0
+	if p.Quantity < 0 {
+		direction = -1.0
+	}
+	p.RealizedPnL += closed * (price - p.AveragePrice) * direction
+
+	previous := p.Quantity
+	p.Quantity += quantity
+	switch {
+	case math.Abs(p.Quantity) <= epsilon:
+		p.Quantity, p.AveragePrice = 0, 0
+	case (p.Quantity > 0) != (previous > 0):
+		// Flipped through flat, so the remainder opened at this price
+		p.AveragePrice = price
+	}
+}
+
+// recordFill updates the positions of both accounts on a trade. Each side's
+// fee comes off its realized PnL, so a maker rebate is credited to it.
+func (me *MatchingEngine) recordFill(trade *models.Trade) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	buyFee, sellFee := trade.MakerFee, trade.TakerFee
+	if trade.TakerSide == models.OrderSideBuy {
+		buyFee, sellFee = trade.TakerFee, trade.MakerFee
+	}
+	if trade.BuyAccountID != "" {
+		position := me.position(trade.BuyAccountID, trade.Symbol)
+		position.apply(trade.Quantity, trade.Price, me.tolerance.Quantity)
+		position.RealizedPnL -= buyFee
+	}
+	if trade.SellAccountID != "" {
+		position := me.position(trade.SellAccountID, trade.Symbol)
+		position.apply(-trade.Quantity, trade.Price, me.tolerance.Quantity)
+		position.RealizedPnL -= sellFee
+	}
+}
+
+// takerAccount returns the account on a trade's taker side, "" if none
+func takerAccount(trade *models.Trade) string {
+	if trade.TakerSide == models.OrderSideBuy {
+		return trade.BuyAccountID
+	}
+	return trade.SellAccountID
+}
+
+// position returns an account's position record, creating it if needed.
+// The caller must hold the engine mutex.
+func (me *MatchingEngine) position(accountID, symbol string) *Position {
+	bySymbol, exists := me.positions[accountID]
+	if !exists {
+		bySymbol = make(map[string]*Position)
+		me.positions[accountID] = bySymbol
+	}
+
+	position, exists := bySymbol[symbol]
+	if !exists {
+		position = &Position{AccountID: accountID, Symbol: symbol}
+		bySymbol[symbol] = position
+	}
+	return position
+}
+
+// GetPosition returns an account's position in a symbol, with unrealized
+// PnL marked to the book's mark price
+func (me *MatchingEngine) GetPosition(accountID, symbol string) Position {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	position := Position{AccountID: accountID, Symbol: symbol}
+	if existing, exists := me.positions[accountID][symbol]; exists {
+		position = *existing
+	}
+	ob := me.orderBooks[symbol]
+	me.mutex.RUnlock()
+
+	if ob != nil && position.Quantity != 0 {
+		if mark := ob.MarkPrice(); mark > 0 {
+			position.UnrealizedPnL = (mark - position.AveragePrice) * position.Quantity
+		}
+	}
+	return position
+}
@@ -0,0 +1,92 @@
+package matching
+
+import (
+	"math"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestPositionRealizedAndUnrealizedPnL(t *testing.T) {
+	me := NewMatchingEngine()
+
+	submit := func(accountID string, side models.OrderSide, quantity, price float64) {
+		order := models.NewOrder("AAPL", models.OrderTypeLimit, side, quantity, price)
+		order.AccountID = accountID
+		me.SubmitOrder(order)
+	}
+
+	// Alice buys 100 at 150 from Bob, then sells it to Carol at 155
+	submit("bob", models.OrderSideSell, 100, 150.0)
+	submit("alice", models.OrderSideBuy, 100, 150.0)
+	submit("carol", models.OrderSideBuy, 100, 155.0)
+	submit("alice", models.OrderSideSell, 100, 155.0)
+
+	alice := me.GetPosition("alice", "AAPL")
+	if alice.Quantity != 0 {
+		t.Errorf("Expected alice to be flat, got %f", alice.Quantity)
+	}
+	if math.Abs(alice.RealizedPnL-500) > 1e-9 {
+		t.Errorf("Expected alice realized PnL 500, got %f", alice.RealizedPnL)
+	}
+
+	bob := me.GetPosition("bob", "AAPL")
+	if bob.Quantity != -100 || bob.AveragePrice != 150.0 {
+		t.Errorf("Expected bob short 100 at 150, got %f at %f", bob.Quantity, bob.AveragePrice)
+	}
+
+	// Quote the book at 154/158 so the mid is 156
+	submit("", models.OrderSideBuy, 10, 154.0)
+	submit("", models.OrderSideSell, 10, 158.0)
+
+	carol := me.GetPosition("carol", "AAPL")
+	if carol.Quantity != 100 || carol.AveragePrice != 155.0 {
+		t.Errorf("Expected carol long 100 at 155, got %f at %f", carol.Quantity, carol.AveragePrice)
+	}
+	if math.Abs(carol.UnrealizedPnL-100) > 1e-9 {
+		t.Errorf("Expected carol unrealized PnL 100, got %f", carol.UnrealizedPnL)
+	}
+	if math.Abs(me.GetPosition("bob", "AAPL").UnrealizedPnL-(-600)) > 1e-9 {
+		t.Errorf("Expected bob unrealized PnL -600, got %f", me.GetPosition("bob", "AAPL").UnrealizedPnL)
+	}
+}
+
+func TestPositionClosedWithRoundingIsFlat(t *testing.T) {
+	position := &Position{}
+	position.apply(0.3, 100.0, models.DefaultQuantityEpsilon)
+	position.apply(-0.1, 101.0, models.DefaultQuantityEpsilon)
+	position.apply(-0.2, 101.0, models.DefaultQuantityEpsilon)
+
+	// 0.3 - 0.1 - 0.2 leaves float dust, which must not count as a position
+	if position.Quantity != 0 || position.AveragePrice != 0 {
+		t.Errorf("Expected a flat position, got %g at %g", position.Quantity, position.AveragePrice)
+	}
+
+	// Buying again opens at the new price rather than averaging with the dust
+	position.apply(0.1, 102.0, models.DefaultQuantityEpsilon)
+	if position.Quantity != 0.1 || position.AveragePrice != 102.0 {
+		t.Errorf("Expected long 0.1 at 102, got %g at %g", position.Quantity, position.AveragePrice)
+	}
+}
+
+func TestFeesAreChargedToRealizedPnL(t *testing.T) {
+	me := NewMatchingEngine()
+	if err := me.SetFeeSchedule(FeeSchedule{MakerRate: -0.0002, TakerRate: 0.0003}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	maker := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0)
+	maker.AccountID = "maker"
+	me.SubmitOrder(maker)
+	taker := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	taker.AccountID = "taker"
+	me.SubmitOrder(taker)
+
+	// 15000 notional: the maker is credited 3 and the taker pays 4.5
+	if pnl := me.GetPosition("maker", "AAPL").RealizedPnL; math.Abs(pnl-3) > 1e-9 {
+		t.Errorf("Expected the maker's rebate of 3 in realized PnL, got %f", pnl)
+	}
+	if pnl := me.GetPosition("taker", "AAPL").RealizedPnL; math.Abs(pnl+4.5) > 1e-9 {
+		t.Errorf("Expected the taker's fee of 4.5 in realized PnL, got %f", pnl)
+	}
+}
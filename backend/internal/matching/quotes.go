@@ -0,0 +1,144 @@
+package matching
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/google/uuid"
+)
+
+// LevelQuote is one price level of a market maker's quote set
+type LevelQuote struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// ReplaceBook swaps an account's resting orders in a symbol for a new set of
+// quotes. The quotes are validated before anything is cancelled, so an
+// invalid set leaves the old quotes in place. The old quotes are withdrawn
+// and the new ones rested under a single book lock, so no other order can
+// trade between them. New quotes that would cross, or any while the symbol
+// is halted, paused or in an auction, then go through normal matching.
+func (me *MatchingEngine) ReplaceBook(symbol, accountID string, bids, asks []LevelQuote) ([]*models.Trade, error) {
+	if accountID == "" {
+		return nil, errors.New("an account is required to replace quotes")
+	}
+	for _, quote := range append(append([]LevelQuote{}, bids...), asks...) {
+		if quote.Price <= 0 || quote.Quantity <= 0 {
+			return nil, fmt.Errorf("invalid quote %g @ %g: price and quantity must be positive", quote.Quantity, quote.Price)
+		}
+	}
+
+	symbol = me.NormalizeSymbol(symbol)
+	maxPrice := me.GetSymbolConfig(symbol).MaxPrice
+
+	quotes := make([]*models.Order, 0, len(bids)+len(asks))
+	build := func(levels []LevelQuote, side models.OrderSide) error {
+		for _, quote := range levels {
+			order := me.NewOrder(symbol, models.OrderTypeLimit, side, quote.Quantity, quote.Price)
+			order.AccountID = accountID
+			reason := me.checkSanity(order)
+			if reason == "" {
+				reason = me.checkSession(order)
+			}
+			if reason == "" && maxPrice > 0 && order.Price > maxPrice {
+				reason = fmt.Sprintf("price %g exceeds the maximum of %g for %s", order.Price, maxPrice, symbol)
+			}
+			if reason != "" {
+				return fmt.Errorf("invalid quote %g @ %g: %s", quote.Quantity, quote.Price, reason)
+			}
+			quotes = append(quotes, order)
+		}
+		return nil
+	}
+	if err := build(bids, models.OrderSideBuy); err != nil {
+		return nil, err
+	}
+	if err := build(asks, models.OrderSideSell); err != nil {
+		return nil, err
+	}
+
+	me.replaceMutex.Lock()
+	defer me.replaceMutex.Unlock()
+
+	me.runDueAuction(symbol)
+	me.mutex.RLock()
+	existing := make([]*models.Order, 0)
+	for _, order := range me.accountOrders[accountID] {
+		if order.Symbol == symbol && order.IsActive() {
+			existing = append(existing, order)
+		}
+	}
+	me.mutex.RUnlock()
+
+	// Only a book trading normally takes quotes straight into it
+	rest := quotes
+	if me.IsHalted(symbol) || me.IsMatchingPaused(symbol) || me.InVolatilityAuction(symbol) {
+		rest = nil
+	}
+
+	ob := me.GetOrCreateOrderBook(symbol)
+	removed, crossing := ob.SwapOrders(existing, rest)
+	if rest == nil {
+		crossing = quotes
+	}
+	me.settleSwap(ob, removed, rest, crossing)
+
+	trades := make([]*models.Trade, 0)
+	for _, order := range crossing {
+		trades = append(trades, me.SubmitOrder(order)...)
+	}
+	return trades, nil
+}
+
+// settleSwap records the outcome of a book swap: the removed orders are
+// cancelled and the rested ones indexed, as CancelOrder and execute would
+// for each. Orders in crossing were left out of the book.
+func (me *MatchingEngine) settleSwap(ob *orderbook.OrderBook, removed, rested, crossing []*models.Order) {
+	now := me.clock.Now()
+	for _, order := range removed {
+		order.Cancel(now)
+		me.recordLatency(order)
+	}
+
+	skipped := make(map[uuid.UUID]bool, len(crossing))
+	for _, order := range crossing {
+		skipped[order.ID] = true
+	}
+
+	added := make([]*models.Order, 0, len(rested))
+	me.mutex.Lock()
+	for _, order := range removed {
+		delete(me.accountOrders[order.AccountID], order.ID)
+		if !order.Hidden {
+			me.recordLevelChange(order.Symbol, order.Side, order.Price, orderbook.ChangeCancel)
+		}
+	}
+	for _, order := range rested {
+		if skipped[order.ID] {
+			continue
+		}
+		order.Ref = me.nextRef(order.Symbol)
+		me.orderIndex[order.ID] = order
+		if me.accountOrders[order.AccountID] == nil {
+			me.accountOrders[order.AccountID] = make(map[uuid.UUID]*models.Order)
+		}
+		me.accountOrders[order.AccountID][order.ID] = order
+		me.recordActivity(order, nil)
+		me.recordSubmission(ob, order, nil)
+		added = append(added, order)
+	}
+	me.mutex.Unlock()
+
+	if len(removed) == 0 && len(added) == 0 {
+		return
+	}
+	me.repricePegs(ob, nil)
+	me.bookChanged(ob.Symbol)
+	for _, order := range removed {
+		me.emit(Event{Type: EventOrderCancelled, Symbol: order.Symbol, OrderID: order.ID})
+	}
+	me.checkBBO(ob.Symbol, BBOCauseReplace)
+}
@@ -0,0 +1,116 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+func TestReplaceBook(t *testing.T) {
+	me := NewMatchingEngine()
+
+	_, err := me.ReplaceBook("AAPL", "mm-1",
+		[]LevelQuote{{Price: 99.0, Quantity: 10}, {Price: 98.0, Quantity: 20}},
+		[]LevelQuote{{Price: 101.0, Quantity: 10}, {Price: 102.0, Quantity: 20}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Another account's order must survive the refresh
+	other := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 97.0)
+	other.AccountID = "other"
+	me.SubmitOrder(other)
+
+	ob := me.GetOrderBook("AAPL")
+	oldIDs := ob.OrderIDs()
+
+	trades, err := me.ReplaceBook("AAPL", "mm-1",
+		[]LevelQuote{{Price: 99.5, Quantity: 15}},
+		[]LevelQuote{{Price: 100.5, Quantity: 15}, {Price: 101.5, Quantity: 25}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(trades) != 0 {
+		t.Errorf("Expected no trades, got %d", len(trades))
+	}
+
+	for _, id := range oldIDs {
+		if id == other.ID {
+			continue
+		}
+		if _, resting := ob.GetOrder(id); resting {
+			t.Errorf("Expected old quote %s to be cancelled", id)
+		}
+	}
+	if _, resting := ob.GetOrder(other.ID); !resting {
+		t.Error("Expected the other account's order to survive")
+	}
+
+	snapshot := ob.Depth(0, 0)
+	if len(snapshot.Bids) != 2 || snapshot.Bids[0].Price != 99.5 || snapshot.Bids[0].Quantity != 15 {
+		t.Errorf("Unexpected bids after replace: %+v", snapshot.Bids)
+	}
+	if len(snapshot.Asks) != 2 || snapshot.Asks[0].Price != 100.5 || snapshot.Asks[1].Quantity != 25 {
+		t.Errorf("Unexpected asks after replace: %+v", snapshot.Asks)
+	}
+}
+
+func TestReplaceBookRejectsInvalidQuotes(t *testing.T) {
+	me := NewMatchingEngine()
+	me.ReplaceBook("AAPL", "mm-1", []LevelQuote{{Price: 99.0, Quantity: 10}}, nil)
+
+	if _, err := me.ReplaceBook("AAPL", "mm-1", []LevelQuote{{Price: 99.0, Quantity: -1}}, nil); err == nil {
+		t.Error("Expected an error for a negative quantity")
+	}
+	if count := me.GetOrderBook("AAPL").OrderCount(); count != 1 {
+		t.Errorf("Expected the old quote to remain, got %d orders", count)
+	}
+}
+
+func TestReplaceBookSwapsInOneStep(t *testing.T) {
+	me := NewMatchingEngine()
+	me.ReplaceBook("AAPL", "mm-1", []LevelQuote{{Price: 99.0, Quantity: 10}}, []LevelQuote{{Price: 101.0, Quantity: 10}})
+	ob := me.GetOrderBook("AAPL")
+
+	// By the time the old quotes are reported cancelled, the new ones rest
+	cancelled := 0
+	me.OnEvent(func(event Event) {
+		if event.Type != EventOrderCancelled {
+			return
+		}
+		cancelled++
+		if ob.GetBestBid() != 99.5 || ob.GetBestAsk() != 100.5 {
+			t.Errorf("Expected the new quotes in place, got %g / %g", ob.GetBestBid(), ob.GetBestAsk())
+		}
+	})
+
+	before := ob.Snapshot().LastChangeSeq
+	if _, err := me.ReplaceBook("AAPL", "mm-1", []LevelQuote{{Price: 99.5, Quantity: 10}}, []LevelQuote{{Price: 100.5, Quantity: 10}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if snapshot := ob.Snapshot(); snapshot.LastChangeSeq != before+1 || snapshot.LastChangeReason != orderbook.ChangeReplace {
+		t.Errorf("Expected a single replace change, got %q #%d", snapshot.LastChangeReason, snapshot.LastChangeSeq-before)
+	}
+	if cancelled != 2 || ob.OrderCount() != 2 {
+		t.Errorf("Expected 2 cancels leaving 2 quotes, got %d and %d", cancelled, ob.OrderCount())
+	}
+}
+
+func TestReplaceBookMatchesCrossingQuotes(t *testing.T) {
+	me := NewMatchingEngine()
+	seller := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 100.0)
+	seller.AccountID = "other"
+	me.SubmitOrder(seller)
+
+	trades, err := me.ReplaceBook("AAPL", "mm-1", []LevelQuote{{Price: 100.0, Quantity: 10}, {Price: 99.0, Quantity: 10}}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(trades) != 1 || trades[0].Quantity != 5 || trades[0].Price != 100.0 {
+		t.Fatalf("Expected the crossing bid to buy 5 at 100, got %d trades", len(trades))
+	}
+	if bids := me.GetOrderBook("AAPL").Depth(0, 0).Bids; len(bids) != 2 || bids[0].Quantity != 5 || bids[1].Price != 99.0 {
+		t.Errorf("Expected the rest of the crossing bid above the 99 quote, got %+v", bids)
+	}
+}
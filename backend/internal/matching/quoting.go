@@ -0,0 +1,50 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// checkPostOnly returns a reason if a post-only order would take liquidity
+// or narrow the spread below the symbol's minimum, or "" if it may rest
+func (me *MatchingEngine) checkPostOnly(ob *orderbook.OrderBook, order *models.Order) string {
+	if order.Type != models.OrderTypeLimit {
+		return "only limit orders can be post-only"
+	}
+
+	var spread float64
+	if order.Side == models.OrderSideBuy {
+		bestAsk := ob.GetBestAsk()
+		if bestAsk == 0 {
+			return ""
+		}
+		spread = bestAsk - order.Price
+	} else {
+		bestBid := ob.GetBestBid()
+		if bestBid == 0 {
+			return ""
+		}
+		spread = order.Price - bestBid
+	}
+
+	if minSpread := me.GetSymbolConfig(order.Symbol).MinSpread; minSpread > 0 && spread < minSpread-me.tolerance.Price {
+		return fmt.Sprintf("post-only order would narrow the spread below the minimum of %g", minSpread)
+	}
+	if spread <= 0 {
+		return "post-only order would take liquidity"
+	}
+	return ""
+}
+
+// checkSpread halts a symbol whose book is locked or crossed after an
+// operation. Matching should make that impossible, so it is treated as a
+// fault rather than traded through.
+func (me *MatchingEngine) checkSpread(ob *orderbook.OrderBook) {
+	bestBid := ob.GetBestBid()
+	bestAsk := ob.GetBestAsk()
+	if bestBid > 0 && bestAsk > 0 && bestBid >= bestAsk {
+		me.haltSymbol(ob.Symbol)
+	}
+}
@@ -0,0 +1,60 @@
+package matching
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestPostOnlyMinimumSpread(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{MinSpread: 0.01})
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 100.0))
+
+	// Bidding at the ask would lock the book
+	locking := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0)
+	locking.PostOnly = true
+	trades := me.SubmitOrder(locking)
+
+	if locking.Status != models.OrderStatusRejected {
+		t.Fatalf("Expected locking post-only order to be rejected, got %s", locking.Status)
+	}
+	if !strings.Contains(locking.RejectReason, "minimum") {
+		t.Errorf("Expected minimum spread reason, got %q", locking.RejectReason)
+	}
+	if len(trades) != 0 {
+		t.Errorf("Expected no trades, got %d", len(trades))
+	}
+
+	narrowing := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.995)
+	narrowing.PostOnly = true
+	me.SubmitOrder(narrowing)
+	if narrowing.Status != models.OrderStatusRejected {
+		t.Errorf("Expected post-only order inside the minimum spread to be rejected, got %s", narrowing.Status)
+	}
+
+	resting := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.99)
+	resting.PostOnly = true
+	me.SubmitOrder(resting)
+	if resting.Status != models.OrderStatusPending {
+		t.Errorf("Expected post-only order at the minimum spread to rest, got %s: %s", resting.Status, resting.RejectReason)
+	}
+}
+
+func TestPostOnlyWouldTake(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 100.0))
+
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 99.0)
+	order.PostOnly = true
+	trades := me.SubmitOrder(order)
+
+	if order.Status != models.OrderStatusRejected || len(trades) != 0 {
+		t.Errorf("Expected marketable post-only order to be rejected without trading, got %s with %d trades", order.Status, len(trades))
+	}
+	if me.IsHalted("AAPL") {
+		t.Error("Expected book to stay tradeable")
+	}
+}
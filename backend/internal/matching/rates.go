@@ -0,0 +1,95 @@
+package matching
+
+import (
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// DefaultRateWindow is how far back rolling rates look
+const DefaultRateWindow = 10 * time.Second
+
+// Rates is a symbol's current activity averaged over the rate window
+type Rates struct {
+	OrdersPerSecond float64       `json:"orders_per_second"`
+	TradesPerSecond float64       `json:"trades_per_second"`
+	Window          time.Duration `json:"window_ns"`
+}
+
+// activity holds the timestamps of a symbol's recent orders and trades,
+// oldest first
+type activity struct {
+	orders []time.Time
+	trades []time.Time
+}
+
+// SetRateWindow sets how far back rolling rates look
+func (me *MatchingEngine) SetRateWindow(window time.Duration) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.rateWindow = window
+}
+
+// GetRates returns a symbol's order and trade rates over the rate window
+func (me *MatchingEngine) GetRates(symbol string) Rates {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	rates := Rates{Window: me.rateWindow}
+	a, exists := me.activity[symbol]
+	if !exists || me.rateWindow <= 0 {
+		return rates
+	}
+
+	cutoff := me.clock.Now().Add(-me.rateWindow)
+	a.orders = dropBefore(a.orders, cutoff)
+	a.trades = dropBefore(a.trades, cutoff)
+
+	seconds := me.rateWindow.Seconds()
+	rates.OrdersPerSecond = float64(len(a.orders)) / seconds
+	rates.TradesPerSecond = float64(len(a.trades)) / seconds
+	return rates
+}
+
+// recordActivity notes an order and its trades for rate tracking. The
+// caller must hold the mutex.
+func (me *MatchingEngine) recordActivity(order *models.Order, trades []*models.Trade) {
+	a := me.symbolActivity(order.Symbol)
+	now := me.clock.Now()
+	a.orders = append(dropBefore(a.orders, now.Add(-me.rateWindow)), now)
+	me.recordTradeActivity(order.Symbol, trades)
+}
+
+// recordTradeActivity notes trades alone for rate tracking, for an order
+// already counted. The caller must hold the mutex.
+func (me *MatchingEngine) recordTradeActivity(symbol string, trades []*models.Trade) {
+	a := me.symbolActivity(symbol)
+	now := me.clock.Now()
+	a.trades = dropBefore(a.trades, now.Add(-me.rateWindow))
+	for range trades {
+		a.trades = append(a.trades, now)
+	}
+}
+
+// symbolActivity returns a symbol's activity, creating it if needed. The
+// caller must hold the mutex.
+func (me *MatchingEngine) symbolActivity(symbol string) *activity {
+	a, exists := me.activity[symbol]
+	if !exists {
+		a = &activity{}
+		me.activity[symbol] = a
+	}
+	return a
+}
+
+// dropBefore removes timestamps older than cutoff from a sorted slice
+func dropBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
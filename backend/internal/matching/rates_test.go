@@ -0,0 +1,52 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestGetRates(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetRateWindow(10 * time.Second)
+
+	// Ten resting orders spread over the first five seconds
+	for i := 0; i < 10; i++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 150.0))
+		mock.Advance(500 * time.Millisecond)
+	}
+
+	// Ten more orders that each trade
+	for i := 0; i < 10; i++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0))
+	}
+
+	rates := me.GetRates("AAPL")
+	if rates.OrdersPerSecond != 2.0 {
+		t.Errorf("Expected 2 orders/sec, got %v", rates.OrdersPerSecond)
+	}
+	if rates.TradesPerSecond != 1.0 {
+		t.Errorf("Expected 1 trade/sec, got %v", rates.TradesPerSecond)
+	}
+
+	// Once the first burst ages out only the trading orders remain
+	mock.Advance(9*time.Second + 700*time.Millisecond)
+	rates = me.GetRates("AAPL")
+	if rates.OrdersPerSecond != 1.0 {
+		t.Errorf("Expected 1 order/sec after the window slides, got %v", rates.OrdersPerSecond)
+	}
+
+	mock.Advance(time.Minute)
+	if rates := me.GetRates("AAPL"); rates.OrdersPerSecond != 0 || rates.TradesPerSecond != 0 {
+		t.Errorf("Expected no activity after the window, got %+v", rates)
+	}
+
+	if rates := me.GetRates("MSFT"); rates.OrdersPerSecond != 0 {
+		t.Errorf("Expected no activity for an unknown symbol, got %+v", rates)
+	}
+}
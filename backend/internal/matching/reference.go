@@ -0,0 +1,35 @@
+package matching
+
+import "github.com/acagliol/arbitrax/backend/internal/models"
+
+// SetFirstTradeReference controls whether a symbol with no seeded reference
+// takes the price of its first trade as its reference. From then on the
+// bands follow the last trade rather than the mid of a book that may still
+// be thin, so they move with the market instead of staying anchored to the
+// first print, until SetReferencePrice seeds a reference again.
+func (me *MatchingEngine) SetFirstTradeReference(enabled bool) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.firstTradeRef = enabled
+}
+
+// establishReference makes the first of a symbol's trades its reference if
+// the policy is enabled and no reference is seeded, emitting
+// EventReferenceSet when it does
+func (me *MatchingEngine) establishReference(symbol string, trades []*models.Trade) {
+	if len(trades) == 0 {
+		return
+	}
+
+	me.mutex.Lock()
+	if !me.firstTradeRef || me.references[symbol] > 0 {
+		me.mutex.Unlock()
+		return
+	}
+	me.references[symbol] = trades[0].Price
+	me.established[symbol] = true
+	me.mutex.Unlock()
+
+	me.emit(Event{Type: EventReferenceSet, Symbol: symbol, Trade: trades[0]})
+}
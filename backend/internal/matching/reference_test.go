@@ -0,0 +1,92 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestFirstTradeEstablishesReference(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		me := NewMatchingEngine()
+		me.SetCircuitBreaker(CircuitBreakerConfig{Tiers: []PriceBandTier{{BandPercent: 0.05}}})
+		me.SetFirstTradeReference(enabled)
+
+		var established []Event
+		me.OnEvent(func(e Event) {
+			if e.Type == EventReferenceSet {
+				established = append(established, e)
+			}
+		})
+
+		// With nothing seeded the first trade prints unbanded
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 100.0))
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 110.0))
+		if trades := me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 1, 0)); len(trades) != 1 {
+			t.Fatalf("Expected the first trade to print, got %d trades", len(trades))
+		}
+
+		if enabled && (len(established) != 1 || established[0].Trade.Price != 100.0) {
+			t.Fatalf("Expected one reference event at 100, got %d", len(established))
+		}
+		if !enabled && len(established) != 0 {
+			t.Fatalf("Expected no reference event with the policy off, got %d", len(established))
+		}
+
+		// A bid at 104 puts the mid at 107, within 5% of the 110 offer but
+		// not of the 100 reference
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 104.0))
+		trades := me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 110.0))
+
+		if enabled && (len(trades) != 0 || !me.IsHalted("AAPL")) {
+			t.Errorf("Expected the 110 print to breach the band around the first trade, got %d trades", len(trades))
+		}
+		if !enabled && (len(trades) != 1 || me.IsHalted("AAPL")) {
+			t.Errorf("Expected the 110 print inside the band around the mid, got %d trades", len(trades))
+		}
+	}
+}
+
+func TestSeededReferenceIsNotReplaced(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetFirstTradeReference(true)
+	me.SetReferencePrice("AAPL", 99.0)
+
+	fired := false
+	me.OnEvent(func(e Event) {
+		if e.Type == EventReferenceSet {
+			fired = true
+		}
+	})
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 100.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 1, 0))
+	if fired {
+		t.Error("Expected a seeded reference to stand")
+	}
+}
+
+func TestEstablishedReferenceFollowsLastTrade(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetCircuitBreaker(CircuitBreakerConfig{Tiers: []PriceBandTier{{BandPercent: 0.05}}})
+	me.SetFirstTradeReference(true)
+
+	// The first trade establishes 100, and 104 is inside its band
+	printTrade(me, 100.0, 1)
+	printTrade(me, 104.0, 1)
+	if me.IsHalted("AAPL") || me.GetOrderBook("AAPL").LastPrice != 104.0 {
+		t.Fatal("Expected 104 to print inside the band around 100")
+	}
+
+	// The band has moved up with the last trade: 108.5 was outside the band
+	// around 100 but is inside the one around 104, and 110 is outside both
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 108.5))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 110.0))
+	trades := me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 2, 110.0))
+	if len(trades) != 1 || trades[0].Price != 108.5 {
+		t.Fatalf("Expected only the 108.5 print, got %d trades", len(trades))
+	}
+	if !me.IsHalted("AAPL") {
+		t.Error("Expected 110 to breach the band around 104")
+	}
+}
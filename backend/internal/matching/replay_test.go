@@ -0,0 +1,76 @@
+package matching
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// replayFixture is a recorded order sequence and the trades it must produce.
+// Trades refer to orders by their index in the sequence.
+type replayFixture struct {
+	Orders []struct {
+		Type     models.OrderType `json:"type"`
+		Side     models.OrderSide `json:"side"`
+		Quantity float64          `json:"quantity"`
+		Price    float64          `json:"price"`
+	} `json:"orders"`
+	Trades []struct {
+		Buy      int     `json:"buy"`
+		Sell     int     `json:"sell"`
+		Price    float64 `json:"price"`
+		Quantity float64 `json:"quantity"`
+	} `json:"trades"`
+}
+
+func TestReplayGoldenFixtures(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "replay", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("No replay fixtures found: %v", err)
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+			var fixture replayFixture
+			if err := json.Unmarshal(data, &fixture); err != nil {
+				t.Fatalf("Failed to parse fixture: %v", err)
+			}
+
+			me := NewMatchingEngine()
+			index := make(map[uuid.UUID]int)
+			trades := make([]*models.Trade, 0)
+			for i, o := range fixture.Orders {
+				order := models.NewOrder("TEST", o.Type, o.Side, o.Quantity, o.Price)
+				index[order.ID] = i
+				trades = append(trades, me.SubmitOrder(order)...)
+			}
+
+			if len(trades) != len(fixture.Trades) {
+				t.Fatalf("Expected %d trades, got %d", len(fixture.Trades), len(trades))
+			}
+			for i, trade := range trades {
+				expected := fixture.Trades[i]
+				buy, sell := index[trade.BuyOrderID], index[trade.SellOrderID]
+				if buy != expected.Buy || sell != expected.Sell || trade.Price != expected.Price || trade.Quantity != expected.Quantity {
+					t.Errorf("Trade %d: expected buy %d sell %d %v @ %v, got buy %d sell %d %v @ %v",
+						i, expected.Buy, expected.Sell, expected.Quantity, expected.Price,
+						buy, sell, trade.Quantity, trade.Price)
+				}
+			}
+
+			if errs := me.ValidateState(); len(errs) > 0 {
+				t.Errorf("Expected valid state after replay, got %v", errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package matching
+
+import (
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// DefaultOrderRetention is how long terminal orders stay indexed by default
+const DefaultOrderRetention = time.Hour
+
+// SetOrderRetention sets how long a filled, cancelled or rejected order stays
+// in the engine's lookup indices before PruneTerminalOrders drops it, by
+// default DefaultOrderRetention. 0 keeps them forever. Trades are kept
+// separately, up to the limit set by SetTradeHistory.
+func (me *MatchingEngine) SetOrderRetention(retention time.Duration) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.retention = retention
+}
+
+// LookupOrder returns an order the engine has accepted, by ID, until it is
+// pruned after reaching a terminal state
+func (me *MatchingEngine) LookupOrder(orderID uuid.UUID) (*models.Order, bool) {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	order, exists := me.orderIndex[orderID]
+	return order, exists
+}
+
+// PruneTerminalOrders drops orders that finished longer ago than the
+// retention window from the lookup indices, returning the number dropped.
+// Like ExpireStaleOrders it is meant to be run periodically.
+func (me *MatchingEngine) PruneTerminalOrders() int {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	if me.retention <= 0 {
+		return 0
+	}
+
+	cutoff := me.clock.Now().Add(-me.retention)
+	pruned := 0
+	for id, order := range me.orderIndex {
+		if order.IsActive() || !finishedAt(order).Before(cutoff) {
+			continue
+		}
+		delete(me.orderIndex, id)
+		if orders, exists := me.accountOrders[order.AccountID]; exists {
+			delete(orders, id)
+			if len(orders) == 0 {
+				delete(me.accountOrders, order.AccountID)
+			}
+		}
+		pruned++
+	}
+	return pruned
+}
+
+// finishedAt returns when an order reached its terminal state. Rejections
+// carry no timestamp, so they count from submission.
+func finishedAt(order *models.Order) time.Time {
+	if order.FilledAt != nil {
+		return *order.FilledAt
+	}
+	if order.CancelledAt != nil {
+		return *order.CancelledAt
+	}
+	return order.SubmittedAt
+}
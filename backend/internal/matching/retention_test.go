@@ -0,0 +1,86 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestPruneTerminalOrders(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetOrderRetention(time.Hour)
+
+	sell := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0)
+	sell.AccountID = "bob"
+	me.SubmitOrder(sell)
+	buy := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	buy.AccountID = "alice"
+	me.SubmitOrder(buy)
+	resting := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 149.0)
+	me.SubmitOrder(resting)
+
+	// Filled orders are queryable straight away
+	if order, exists := me.LookupOrder(buy.ID); !exists || order.Status != models.OrderStatusFilled {
+		t.Fatalf("Expected the filled buy to be queryable")
+	}
+
+	mock.Advance(30 * time.Minute)
+	if pruned := me.PruneTerminalOrders(); pruned != 0 {
+		t.Errorf("Expected nothing pruned inside the retention window, got %d", pruned)
+	}
+
+	mock.Advance(31 * time.Minute)
+	if pruned := me.PruneTerminalOrders(); pruned != 2 {
+		t.Errorf("Expected both filled orders pruned, got %d", pruned)
+	}
+	if _, exists := me.LookupOrder(buy.ID); exists {
+		t.Error("Expected the filled buy to be pruned")
+	}
+	if _, exists := me.LookupOrder(resting.ID); !exists {
+		t.Error("Expected the resting order to stay indexed")
+	}
+
+	// The trade history is untouched
+	if trades := me.GetRecentTrades("AAPL", 10); len(trades) != 1 {
+		t.Errorf("Expected the trade to be retained, got %d", len(trades))
+	}
+}
+
+func TestPruneTerminalOrdersByDefault(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+
+	order := me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 100, 0)
+	me.SubmitOrder(order)
+	mock.Advance(DefaultOrderRetention + time.Second)
+
+	if pruned := me.PruneTerminalOrders(); pruned != 1 {
+		t.Errorf("Expected the default retention to prune the order, got %d", pruned)
+	}
+}
+
+func TestPruneTerminalOrdersDisabled(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetOrderRetention(0)
+
+	order := me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 100, 0)
+	me.SubmitOrder(order)
+	mock.Advance(24 * time.Hour)
+
+	if pruned := me.PruneTerminalOrders(); pruned != 0 {
+		t.Errorf("Expected nothing pruned without a retention window, got %d", pruned)
+	}
+	if _, exists := me.LookupOrder(order.ID); !exists {
+		t.Error("Expected the cancelled order to stay queryable")
+	}
+}
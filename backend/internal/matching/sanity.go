@@ -0,0 +1,67 @@
+package matching
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// SanityLimits are engine-wide caps that reject clearly nonsensical orders
+// before any per-symbol checks run
+type SanityLimits struct {
+	MaxQuantity float64 // 0 disables the cap
+	MaxPrice    float64 // 0 disables the cap
+}
+
+// PriceCeiling is the highest price the engine ever accepts, whatever the
+// configured caps. It keeps notional and weighted-average fill arithmetic far
+// from float64 overflow.
+const PriceCeiling = 1e15
+
+// DefaultSanityLimits are generous enough for any real instrument
+var DefaultSanityLimits = SanityLimits{
+	MaxQuantity: 1e9,
+	MaxPrice:    1e9,
+}
+
+// SetSanityLimits sets the engine-wide quantity and price caps
+func (me *MatchingEngine) SetSanityLimits(limits SanityLimits) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.sanity = limits
+}
+
+// checkSanity returns a reason if the order's quantity or price is
+// nonsensical, or "" if it passes
+func (me *MatchingEngine) checkSanity(order *models.Order) string {
+	me.mutex.RLock()
+	limits := me.sanity
+	me.mutex.RUnlock()
+
+	if math.IsNaN(order.Quantity) || math.IsInf(order.Quantity, 0) || order.Quantity <= 0 {
+		return "quantity must be a positive finite number"
+	}
+	if math.IsNaN(order.Price) || math.IsInf(order.Price, 0) || order.Price < 0 {
+		return "price must be a non-negative finite number"
+	}
+	if math.IsNaN(order.StopPrice) || math.IsInf(order.StopPrice, 0) || order.StopPrice < 0 {
+		return "stop price must be a non-negative finite number"
+	}
+	if order.Price > PriceCeiling {
+		return fmt.Sprintf("price %g exceeds the ceiling of %g", order.Price, float64(PriceCeiling))
+	}
+	switch order.TimeInForce {
+	case "", models.TimeInForceGTC, models.TimeInForceIOC:
+	default:
+		return fmt.Sprintf("unknown time in force %q", order.TimeInForce)
+	}
+	if limits.MaxQuantity > 0 && order.Quantity > limits.MaxQuantity {
+		return fmt.Sprintf("quantity %g exceeds the sanity cap of %g", order.Quantity, limits.MaxQuantity)
+	}
+	if limits.MaxPrice > 0 && order.Price > limits.MaxPrice {
+		return fmt.Sprintf("price %g exceeds the sanity cap of %g", order.Price, limits.MaxPrice)
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package matching
+
+import (
+	"math"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestSanityCapRejectsAbsurdOrders(t *testing.T) {
+	me := NewMatchingEngine()
+
+	tests := []struct {
+		name     string
+		quantity float64
+		price    float64
+	}{
+		{"absurd quantity", 1e18, 150.0},
+		{"absurd price", 100, 1e15},
+		{"NaN quantity", math.NaN(), 150.0},
+		{"infinite price", 100, math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		// A symbol with no configuration at all is still protected
+		order := models.NewOrder("UNCONFIGURED", models.OrderTypeLimit, models.OrderSideSell, tt.quantity, tt.price)
+		trades := me.SubmitOrder(order)
+
+		if order.Status != models.OrderStatusRejected {
+			t.Errorf("%s: expected rejection, got %s", tt.name, order.Status)
+		}
+		if len(trades) != 0 {
+			t.Errorf("%s: expected no trades, got %d", tt.name, len(trades))
+		}
+	}
+
+	if me.GetOrderBook("UNCONFIGURED") != nil {
+		t.Error("Rejected orders should not create an order book")
+	}
+
+	me.SetSanityLimits(SanityLimits{MaxQuantity: 1e20})
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1e18, 150.0)
+	me.SubmitOrder(order)
+	if order.Status == models.OrderStatusRejected {
+		t.Errorf("Expected order within the raised cap to be accepted, got %s", order.RejectReason)
+	}
+}
+
+func TestMaxPriceCeiling(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSanityLimits(SanityLimits{})
+	me.SetSymbolConfig("AAPL", SymbolConfig{MaxPrice: 1000})
+
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 1000.01)
+	me.SubmitOrder(order)
+	if order.Status != models.OrderStatusRejected {
+		t.Errorf("Expected price above the symbol maximum to be rejected, got %s", order.Status)
+	}
+
+	// The global ceiling applies even with every configurable cap disabled
+	order = models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideSell, 10, PriceCeiling*2)
+	me.SubmitOrder(order)
+	if order.Status != models.OrderStatusRejected {
+		t.Errorf("Expected price above the ceiling to be rejected, got %s", order.Status)
+	}
+
+	// Just below the ceiling, notional and fill averages stay finite
+	price := PriceCeiling * 0.999
+	me.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideSell, 1e9, price))
+	buyOrder := models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideBuy, 1e9, price)
+	trades := me.SubmitOrder(buyOrder)
+
+	if len(trades) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(trades))
+	}
+	notional := trades[0].Price * trades[0].Quantity
+	if math.IsInf(notional, 0) || math.IsNaN(notional) {
+		t.Errorf("Expected finite notional, got %g", notional)
+	}
+	if buyOrder.FilledPrice != price {
+		t.Errorf("Expected filled price %g, got %g", price, buyOrder.FilledPrice)
+	}
+}
+
+func TestSanityRejectsNonFiniteStopPrice(t *testing.T) {
+	me := NewMatchingEngine()
+
+	for _, stopPrice := range []float64{math.NaN(), math.Inf(-1), -1} {
+		order := models.NewOrder("AAPL", models.OrderTypeStopLimit, models.OrderSideSell, 10, 95.0)
+		order.StopPrice = stopPrice
+		me.SubmitOrder(order)
+
+		if order.Status != models.OrderStatusRejected {
+			t.Errorf("Expected a stop price of %g rejected, got %s", stopPrice, order.Status)
+		}
+	}
+}
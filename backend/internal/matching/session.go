@@ -0,0 +1,65 @@
+package matching
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// SessionPhase is the trading phase a symbol is in
+type SessionPhase string
+
+const (
+	SessionContinuous SessionPhase = "continuous"
+	SessionAuction    SessionPhase = "auction"
+)
+
+// DefaultSessionOrderTypes are the order types accepted in each phase when a
+// symbol has no rules of its own. A phase missing from the map accepts every
+// type.
+var DefaultSessionOrderTypes = map[SessionPhase][]models.OrderType{
+	SessionAuction: {models.OrderTypeLimit},
+}
+
+// SetSessionPhase moves a symbol into a trading phase. The phase decides
+// which order types are admitted; it does not change how admitted orders
+// match.
+func (me *MatchingEngine) SetSessionPhase(symbol string, phase SessionPhase) {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.sessions[symbol] = phase
+}
+
+// GetSessionPhase returns a symbol's trading phase, continuous by default
+func (me *MatchingEngine) GetSessionPhase(symbol string) SessionPhase {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	if phase, exists := me.sessions[symbol]; exists {
+		return phase
+	}
+	return SessionContinuous
+}
+
+// checkSession returns a reason if the order's type is not accepted in the
+// symbol's current phase, or "" if it is
+func (me *MatchingEngine) checkSession(order *models.Order) string {
+	phase := me.GetSessionPhase(order.Symbol)
+
+	rules := me.GetSymbolConfig(order.Symbol).SessionOrderTypes
+	if rules == nil {
+		rules = DefaultSessionOrderTypes
+	}
+
+	allowed, restricted := rules[phase]
+	if !restricted || slices.Contains(allowed, order.Type) {
+		return ""
+	}
+	return fmt.Sprintf("%s orders are not accepted during the %s phase", order.Type, phase)
+}
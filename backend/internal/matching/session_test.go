@@ -0,0 +1,58 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestMarketOrderRejectedDuringAuction(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+
+	me.SetSessionPhase("AAPL", SessionAuction)
+
+	marketOrder := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	me.SubmitOrder(marketOrder)
+	if marketOrder.Status != models.OrderStatusRejected {
+		t.Errorf("Expected market order rejected during auction, got %s", marketOrder.Status)
+	}
+
+	limitOrder := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 149.0)
+	me.SubmitOrder(limitOrder)
+	if limitOrder.Status == models.OrderStatusRejected {
+		t.Errorf("Expected limit order accepted during auction, got %s", limitOrder.RejectReason)
+	}
+
+	me.SetSessionPhase("AAPL", SessionContinuous)
+
+	marketOrder = models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	trades := me.SubmitOrder(marketOrder)
+	if marketOrder.Status == models.OrderStatusRejected || len(trades) != 1 {
+		t.Errorf("Expected market order to trade in continuous session, got %s with %d trades", marketOrder.Status, len(trades))
+	}
+}
+
+func TestSessionOrderTypesPerSymbol(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("MSFT", SymbolConfig{
+		SessionOrderTypes: map[SessionPhase][]models.OrderType{
+			SessionAuction:    {models.OrderTypeLimit, models.OrderTypeMarket},
+			SessionContinuous: {models.OrderTypeLimit},
+		},
+	})
+
+	me.SetSessionPhase("MSFT", SessionAuction)
+	order := models.NewOrder("MSFT", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	me.SubmitOrder(order)
+	if order.Status == models.OrderStatusRejected {
+		t.Errorf("Expected market order accepted by MSFT auction rules, got %s", order.RejectReason)
+	}
+
+	me.SetSessionPhase("MSFT", SessionContinuous)
+	order = models.NewOrder("MSFT", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	me.SubmitOrder(order)
+	if order.Status != models.OrderStatusRejected {
+		t.Errorf("Expected market order rejected by MSFT continuous rules, got %s", order.Status)
+	}
+}
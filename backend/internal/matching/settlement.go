@@ -0,0 +1,95 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+	"github.com/google/uuid"
+)
+
+// SettlementPolicy decides when a resting order's backing is checked
+type SettlementPolicy string
+
+const (
+	// SettlementPreReserve trusts that backing was reserved when the order
+	// was accepted, so fills are never checked
+	SettlementPreReserve SettlementPolicy = "pre_reserve"
+	// SettlementValidateAtFill checks the resting order's backing before
+	// each fill and skips the order, untouched, if it can't settle
+	SettlementValidateAtFill SettlementPolicy = "validate_at_fill"
+)
+
+// unbackedReason is the cancel reason on a market order's remainder when the
+// only liquidity left at its price can't settle
+const unbackedReason = "resting orders could not settle"
+
+// FundsChecker reports whether a resting order's account can settle a fill
+// of quantity at price
+type FundsChecker interface {
+	CanSettle(order *models.Order, quantity, price float64) bool
+}
+
+// SetSettlementPolicy chooses when backing is checked. Validating at fill
+// needs a checker to ask.
+func (me *MatchingEngine) SetSettlementPolicy(policy SettlementPolicy, checker FundsChecker) error {
+	switch policy {
+	case SettlementPreReserve:
+	case SettlementValidateAtFill:
+		if checker == nil {
+			return fmt.Errorf("%s settlement needs a funds checker", policy)
+		}
+	default:
+		return fmt.Errorf("unknown settlement policy %q", policy)
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.settlement = policy
+	me.funds = checker
+	return nil
+}
+
+// settles reports whether a fill of a resting order can settle under the
+// current policy
+func (me *MatchingEngine) settles(resting *models.Order, quantity, price float64) bool {
+	me.mutex.RLock()
+	policy, funds := me.settlement, me.funds
+	me.mutex.RUnlock()
+
+	if policy != SettlementValidateAtFill || funds == nil {
+		return true
+	}
+	return funds.CanSettle(resting, quantity, price)
+}
+
+// settleable drops the resting orders that have already failed to settle
+// against the incoming order
+func settleable(orders []*models.Order, unsettled map[uuid.UUID]bool) []*models.Order {
+	if len(unsettled) == 0 {
+		return orders
+	}
+
+	kept := make([]*models.Order, 0, len(orders))
+	for _, order := range orders {
+		if !unsettled[order.ID] {
+			kept = append(kept, order)
+		}
+	}
+	return kept
+}
+
+// unsettledLevel reports whether every live order left at a level has failed
+// to settle against the incoming order, so matching can go no further
+func unsettledLevel(level *orderbook.PriceLevel, unsettled map[uuid.UUID]bool) bool {
+	if len(unsettled) == 0 {
+		return false
+	}
+	for _, resting := range level.Orders {
+		if resting.IsActive() && !unsettled[resting.ID] {
+			return false
+		}
+	}
+	return true
+}
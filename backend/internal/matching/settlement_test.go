@@ -0,0 +1,129 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// accountFunds backs every account except those listed as short
+type accountFunds map[string]bool
+
+func (f accountFunds) CanSettle(order *models.Order, quantity, price float64) bool {
+	return !f[order.AccountID]
+}
+
+func TestUnbackedOrderIsLeftUntouched(t *testing.T) {
+	me := NewMatchingEngine()
+	if err := me.SetSettlementPolicy(SettlementValidateAtFill, accountFunds{"broke": true}); err != nil {
+		t.Fatalf("Expected the policy to be accepted, got %v", err)
+	}
+
+	unbacked := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+	unbacked.AccountID = "broke"
+	me.SubmitOrder(unbacked)
+
+	var events []Event
+	me.OnEvent(func(e Event) {
+		if e.OrderID == unbacked.ID || e.Type == EventTrade {
+			events = append(events, e)
+		}
+	})
+
+	incoming := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0)
+	incoming.AccountID = "buyer"
+	if trades := me.SubmitOrder(incoming); len(trades) != 0 {
+		t.Fatalf("Expected no trade with the unbacked order, got %d trades", len(trades))
+	}
+
+	// Both orders are exactly as they were before the match
+	for _, order := range []*models.Order{incoming, unbacked} {
+		if order.Status != models.OrderStatusPending || order.FilledQuantity != 0 || order.FilledPrice != 0 || order.FilledAt != nil || order.CancelReason != "" {
+			t.Errorf("Expected order %s untouched, got %s with %g filled at %g (%q)", order.ID, order.Status, order.FilledQuantity, order.FilledPrice, order.CancelReason)
+		}
+	}
+
+	// The unbacked order keeps resting and the incoming one is parked rather
+	// than crossing it
+	ob := me.GetOrderBook("AAPL")
+	if _, resting := ob.GetOrder(unbacked.ID); !resting || ob.GetBestAsk() != 100.0 {
+		t.Error("Expected the unbacked order to keep resting")
+	}
+	if _, resting := ob.GetOrder(incoming.ID); resting || ob.GetBestBid() != 0 {
+		t.Error("Expected the incoming order not to rest on a crossed book")
+	}
+	if errs := ob.Validate(); len(errs) != 0 {
+		t.Errorf("Expected a valid book, got %v", errs)
+	}
+
+	if len(events) != 0 {
+		t.Errorf("Expected no events for a trade that never printed, got %d", len(events))
+	}
+	if position := me.GetPosition("broke", "AAPL"); position.Quantity != 0 {
+		t.Errorf("Expected no position from an unprinted trade, got %g", position.Quantity)
+	}
+	if recent := me.GetRecentTrades("AAPL", 10); len(recent) != 0 {
+		t.Errorf("Expected no stored trades, got %d", len(recent))
+	}
+}
+
+func TestMarketOrderCancelledByUnbackedLiquidity(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSettlementPolicy(SettlementValidateAtFill, accountFunds{"broke": true})
+
+	unbacked := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+	unbacked.AccountID = "broke"
+	me.SubmitOrder(unbacked)
+
+	market := me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	if trades := me.SubmitOrder(market); len(trades) != 0 {
+		t.Fatalf("Expected no trades, got %d", len(trades))
+	}
+	if market.Status != models.OrderStatusCancelled || market.CancelReason != unbackedReason {
+		t.Errorf("Expected the market order cancelled, got %s (%s)", market.Status, market.CancelReason)
+	}
+	if unbacked.Status != models.OrderStatusPending || unbacked.FilledQuantity != 0 {
+		t.Errorf("Expected the unbacked order untouched, got %s with %g filled", unbacked.Status, unbacked.FilledQuantity)
+	}
+}
+
+func TestUnbackedOrderIsSkippedForBackedOrder(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSettlementPolicy(SettlementValidateAtFill, accountFunds{"broke": true})
+
+	unbacked := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+	unbacked.AccountID = "broke"
+	me.SubmitOrder(unbacked)
+	backed := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+	backed.AccountID = "funded"
+	me.SubmitOrder(backed)
+
+	market := me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	trades := me.SubmitOrder(market)
+	if len(trades) != 1 || trades[0].SellOrderID != backed.ID {
+		t.Fatalf("Expected the backed order to fill past the unbacked one, got %d trades", len(trades))
+	}
+	if market.FilledQuantity != 10 || market.FilledPrice != 100.0 {
+		t.Errorf("Expected 10 filled at 100, got %g at %g", market.FilledQuantity, market.FilledPrice)
+	}
+
+	// The skipped order keeps resting where it was
+	ob := me.GetOrderBook("AAPL")
+	if _, resting := ob.GetOrder(unbacked.ID); !resting || unbacked.Status != models.OrderStatusPending {
+		t.Errorf("Expected the unbacked order to keep resting, got %s", unbacked.Status)
+	}
+}
+
+func TestPreReserveNeverBusts(t *testing.T) {
+	me := NewMatchingEngine()
+	if err := me.SetSettlementPolicy(SettlementValidateAtFill, nil); err == nil {
+		t.Error("Expected validate-at-fill without a checker to be rejected")
+	}
+
+	unbacked := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+	unbacked.AccountID = "broke"
+	me.SubmitOrder(unbacked)
+	if trades := me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)); len(trades) != 1 {
+		t.Errorf("Expected the default policy to trade without checking, got %d trades", len(trades))
+	}
+}
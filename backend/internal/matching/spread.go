@@ -0,0 +1,59 @@
+package matching
+
+import "time"
+
+// maxSpreadHistory bounds the spread points kept per symbol
+const maxSpreadHistory = 1000
+
+// SpreadPoint is the top of book at a moment it changed. Spread is 0 while
+// either side is empty.
+type SpreadPoint struct {
+	Bid       float64   `json:"bid"`
+	Ask       float64   `json:"ask"`
+	Spread    float64   `json:"spread"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// GetSpreadHistory returns up to limit spread points for a symbol, newest
+// first
+func (me *MatchingEngine) GetSpreadHistory(symbol string, limit int) []SpreadPoint {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	history := me.spreads[symbol]
+	result := make([]SpreadPoint, 0)
+	for i := len(history) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, history[i])
+	}
+	return result
+}
+
+// recordSpread appends a spread point if the symbol's best displayed bid or
+// ask has changed since the last one. Hidden orders never show in it.
+func (me *MatchingEngine) recordSpread(symbol string) {
+	ob := me.GetOrderBook(symbol)
+	if ob == nil {
+		return
+	}
+	bbo := ob.BBO()
+	bid, ask := bbo.BidPrice, bbo.AskPrice
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	history := me.spreads[symbol]
+	if n := len(history); n > 0 && history[n-1].Bid == bid && history[n-1].Ask == ask {
+		return
+	}
+
+	point := SpreadPoint{Bid: bid, Ask: ask, Timestamp: me.clock.Now()}
+	if bid > 0 && ask > 0 {
+		point.Spread = ask - bid
+	}
+	if len(history) >= maxSpreadHistory {
+		history = history[1:]
+	}
+	me.spreads[symbol] = append(history, point)
+}
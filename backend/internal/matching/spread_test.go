@@ -0,0 +1,57 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestGetSpreadHistory(t *testing.T) {
+	me := NewMatchingEngine()
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 102.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+
+	// Behind the touch, so the BBO doesn't change
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 105.0))
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+
+	history := me.GetSpreadHistory("AAPL", 10)
+
+	// Newest first
+	expected := []SpreadPoint{
+		{Bid: 100.0, Ask: 101.0, Spread: 1.0},
+		{Bid: 100.0, Ask: 102.0, Spread: 2.0},
+		{Bid: 99.0, Ask: 102.0, Spread: 3.0},
+		{Bid: 99.0, Ask: 0, Spread: 0},
+	}
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d spread points, got %d: %+v", len(expected), len(history), history)
+	}
+	for i, point := range history {
+		if point.Bid != expected[i].Bid || point.Ask != expected[i].Ask || point.Spread != expected[i].Spread {
+			t.Errorf("Point %d: expected %+v, got %+v", i, expected[i], point)
+		}
+	}
+
+	if limited := me.GetSpreadHistory("AAPL", 2); len(limited) != 2 || limited[0].Spread != 1.0 {
+		t.Errorf("Expected the 2 newest points, got %+v", limited)
+	}
+}
+
+func TestSpreadHistoryIgnoresHiddenOrders(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0)
+	hidden.Hidden = true
+	me.SubmitOrder(hidden)
+
+	history := me.GetSpreadHistory("AAPL", 10)
+	if len(history) != 2 || history[0].Bid != 99.0 || history[0].Spread != 2.0 {
+		t.Errorf("Expected the hidden bid not to narrow the spread, got %+v", history)
+	}
+}
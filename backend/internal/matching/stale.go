@@ -0,0 +1,24 @@
+package matching
+
+import (
+	"sort"
+	"time"
+)
+
+// StaleSymbols returns the symbols, sorted, whose books have not traded or
+// changed for longer than threshold, so monitoring can alert on quotes that
+// may have stopped updating
+func (me *MatchingEngine) StaleSymbols(threshold time.Duration) []string {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	cutoff := me.clock.Now().Add(-threshold)
+	stale := make([]string, 0)
+	for symbol, ob := range me.orderBooks {
+		if ob.LastUpdated().Before(cutoff) {
+			stale = append(stale, symbol)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
@@ -0,0 +1,36 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestStaleSymbols(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 150.0))
+	me.SubmitOrder(me.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideSell, 10, 300.0))
+
+	mock.Advance(4 * time.Minute)
+
+	// A trade counts as activity even though nothing new rests
+	me.SubmitOrder(me.NewOrder("MSFT", models.OrderTypeMarket, models.OrderSideBuy, 5, 0))
+
+	mock.Advance(2 * time.Minute)
+	stale := me.StaleSymbols(5 * time.Minute)
+	if len(stale) != 1 || stale[0] != "AAPL" {
+		t.Errorf("Expected only AAPL stale, got %v", stale)
+	}
+
+	// A cancel is a book change too
+	ob := me.GetOrderBook("AAPL")
+	me.CancelOrder("AAPL", ob.OrderIDs()[0])
+	if stale := me.StaleSymbols(5 * time.Minute); len(stale) != 0 {
+		t.Errorf("Expected nothing stale after the cancel, got %v", stale)
+	}
+}
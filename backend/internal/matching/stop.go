@@ -0,0 +1,143 @@
+package matching
+
+import (
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// isStop reports whether an order waits for a stop price before working
+func isStop(order *models.Order) bool {
+	return order.Type == models.OrderTypeStopLoss || order.Type == models.OrderTypeStopLimit
+}
+
+// stopPrice returns the price that triggers a stop order. Stop-limit orders
+// carry it in StopPrice, stop-loss orders in Price.
+func stopPrice(order *models.Order) float64 {
+	if order.Type == models.OrderTypeStopLimit {
+		return order.StopPrice
+	}
+	return order.Price
+}
+
+// stopTriggered reports whether a last price has reached a stop order's
+// stop price: at or below it for a sell stop, at or above it for a buy stop
+func stopTriggered(order *models.Order, lastPrice float64) bool {
+	if lastPrice <= 0 {
+		return false
+	}
+	if order.Side == models.OrderSideSell {
+		return lastPrice <= stopPrice(order)
+	}
+	return lastPrice >= stopPrice(order)
+}
+
+// activateStop turns a triggered stop into the order it stands for: a
+// market order for a stop-loss, a limit order at Price for a stop-limit
+func activateStop(order *models.Order) {
+	if order.Type == models.OrderTypeStopLimit {
+		order.Type = models.OrderTypeLimit
+		return
+	}
+	order.Type = models.OrderTypeMarket
+}
+
+// holdStop keeps a stop order dormant, out of the book, until the market
+// trades through its stop price. A stop the last price has already reached
+// is activated at once, and holdStop returns false so it carries on as the
+// order it stands for.
+func (me *MatchingEngine) holdStop(order *models.Order) bool {
+	if order.Type == models.OrderTypeStopLimit && (order.StopPrice <= 0 || order.Price <= 0) {
+		order.Reject("stop_limit orders need both a stop price and a limit price")
+		return true
+	}
+	if stopPrice(order) <= 0 {
+		order.Reject("stop orders need a stop price")
+		return true
+	}
+
+	lastPrice := 0.0
+	if ob := me.GetOrderBook(order.Symbol); ob != nil {
+		lastPrice = ob.LastPrice
+	}
+	if stopTriggered(order, lastPrice) {
+		activateStop(order)
+		return false
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.orderIndex[order.ID] = order
+	me.stopOrders[order.Symbol] = append(me.stopOrders[order.Symbol], order)
+	return true
+}
+
+// GetStopOrders returns the stop orders still dormant on a symbol
+func (me *MatchingEngine) GetStopOrders(symbol string) []*models.Order {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	result := make([]*models.Order, len(me.stopOrders[symbol]))
+	copy(result, me.stopOrders[symbol])
+	return result
+}
+
+// fireStops activates the stop orders a symbol's new last price has reached,
+// submitting each as a market or limit order. It must be called without
+// holding the mutex.
+func (me *MatchingEngine) fireStops(symbol string, lastPrice float64) {
+	me.mutex.Lock()
+	pending := me.stopOrders[symbol]
+	fired := make([]*models.Order, 0)
+	waiting := pending[:0]
+	for _, order := range pending {
+		if stopTriggered(order, lastPrice) {
+			fired = append(fired, order)
+			continue
+		}
+		waiting = append(waiting, order)
+	}
+	for i := len(waiting); i < len(pending); i++ {
+		pending[i] = nil
+	}
+	if len(waiting) == 0 {
+		delete(me.stopOrders, symbol)
+	} else {
+		me.stopOrders[symbol] = waiting
+	}
+	me.mutex.Unlock()
+
+	// Straight to matching: a triggered stop was accepted long ago and must
+	// not wait behind newer orders in the queue or the throttle
+	for _, order := range fired {
+		activateStop(order)
+		me.submitOrder(order, nil)
+	}
+}
+
+// cancelStop cancels a dormant stop order, returning false if there is none
+// with the ID
+func (me *MatchingEngine) cancelStop(symbol string, orderID uuid.UUID) bool {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	var order *models.Order
+	waiting := me.stopOrders[symbol]
+	for i, stop := range waiting {
+		if stop.ID == orderID {
+			order = stop
+			me.stopOrders[symbol] = append(waiting[:i:i], waiting[i+1:]...)
+			break
+		}
+	}
+	me.mutex.Unlock()
+
+	if order == nil || !order.IsActive() {
+		return false
+	}
+	order.Cancel(me.clock.Now())
+	me.emit(Event{Type: EventOrderCancelled, Symbol: symbol, OrderID: orderID})
+	return true
+}
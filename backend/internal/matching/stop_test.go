@@ -0,0 +1,166 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// printTrade prints quantity at price between two fresh orders
+func printTrade(me *MatchingEngine, price, quantity float64) {
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, quantity, price))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, quantity, price))
+}
+
+func TestSellStopActivatesWhenPriceFalls(t *testing.T) {
+	me := NewMatchingEngine()
+	printTrade(me, 100.0, 1)
+
+	// Bids for the activated stop to hit
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 95.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 94.0))
+
+	stop := models.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideSell, 8, 96.0)
+	if trades := me.SubmitOrder(stop); len(trades) != 0 {
+		t.Fatalf("Expected the stop to wait, got %d trades", len(trades))
+	}
+	ob := me.GetOrderBook("AAPL")
+	if _, resting := ob.GetOrder(stop.ID); resting || len(me.GetStopOrders("AAPL")) != 1 {
+		t.Fatal("Expected the stop held outside the book")
+	}
+	if ob.GetBestAsk() != 0 {
+		t.Errorf("Expected the stop not to show on the offer, got %g", ob.GetBestAsk())
+	}
+
+	// A trade above the stop leaves it dormant
+	printTrade(me, 97.0, 1)
+	if stop.FilledQuantity != 0 || len(me.GetStopOrders("AAPL")) != 1 {
+		t.Fatal("Expected the stop to stay dormant above its stop price")
+	}
+
+	// Trading down to the stop activates it as a market order
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideSell, 1, 0))
+	if len(me.GetStopOrders("AAPL")) != 0 {
+		t.Error("Expected the stop to have left the dormant list")
+	}
+	if stop.Type != models.OrderTypeMarket || stop.Status != models.OrderStatusFilled {
+		t.Fatalf("Expected the stop activated and filled, got %s %s", stop.Type, stop.Status)
+	}
+	if stop.FilledQuantity != 8 || ob.LastPrice != 94.0 {
+		t.Errorf("Expected 8 filled down to 94, got %g with last price %g", stop.FilledQuantity, ob.LastPrice)
+	}
+}
+
+func TestBuyStopActivatesWhenPriceRises(t *testing.T) {
+	me := NewMatchingEngine()
+	printTrade(me, 100.0, 1)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 105.0))
+
+	stop := models.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideBuy, 5, 103.0)
+	me.SubmitOrder(stop)
+	printTrade(me, 102.0, 1)
+	if stop.FilledQuantity != 0 {
+		t.Fatal("Expected the buy stop to wait below its stop price")
+	}
+
+	printTrade(me, 103.0, 1)
+	if stop.Status != models.OrderStatusFilled || stop.FilledPrice != 105.0 {
+		t.Errorf("Expected the buy stop filled at 105, got %s at %g", stop.Status, stop.FilledPrice)
+	}
+}
+
+func TestStopAlreadyThroughActivatesAtOnce(t *testing.T) {
+	me := NewMatchingEngine()
+	printTrade(me, 100.0, 1)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 99.0))
+
+	stop := models.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideSell, 5, 101.0)
+	if trades := me.SubmitOrder(stop); len(trades) != 1 || stop.Status != models.OrderStatusFilled {
+		t.Errorf("Expected a stop above the last price to sell at once, got %s", stop.Status)
+	}
+}
+
+func TestCancelDormantStop(t *testing.T) {
+	me := NewMatchingEngine()
+	printTrade(me, 100.0, 1)
+
+	stop := models.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideSell, 5, 95.0)
+	me.SubmitOrder(stop)
+	if !me.CancelOrder("AAPL", stop.ID) || stop.Status != models.OrderStatusCancelled {
+		t.Fatal("Expected the dormant stop to cancel")
+	}
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 94.0))
+	printTrade(me, 94.0, 1)
+	if stop.FilledQuantity != 0 {
+		t.Error("Expected a cancelled stop never to activate")
+	}
+}
+
+func TestStopLimitRestsAtItsLimitOnceTriggered(t *testing.T) {
+	me := NewMatchingEngine()
+	printTrade(me, 100.0, 1)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 3, 104.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 106.0))
+
+	stop := models.NewOrder("AAPL", models.OrderTypeStopLimit, models.OrderSideBuy, 8, 105.0)
+	stop.StopPrice = 103.0
+	me.SubmitOrder(stop)
+	if len(me.GetStopOrders("AAPL")) != 1 {
+		t.Fatal("Expected the stop-limit held below its stop price")
+	}
+
+	printTrade(me, 103.0, 1)
+	if stop.Type != models.OrderTypeLimit || len(me.GetStopOrders("AAPL")) != 0 {
+		t.Fatalf("Expected the stop-limit activated as a limit order, got %s", stop.Type)
+	}
+
+	// Only the 3 offered within the limit fill; 106 is never reached
+	if stop.FilledQuantity != 3 || stop.FilledPrice != 104.0 {
+		t.Errorf("Expected 3 filled at 104, got %g at %g", stop.FilledQuantity, stop.FilledPrice)
+	}
+	ob := me.GetOrderBook("AAPL")
+	if order, resting := ob.GetOrder(stop.ID); !resting || order.RemainingQuantity(models.DefaultQuantityEpsilon) != 5 {
+		t.Error("Expected the remaining 5 resting at the limit")
+	}
+	if ob.GetBestBid() != 105.0 || ob.GetBestAsk() != 106.0 {
+		t.Errorf("Expected the book at 105 / 106, got %g / %g", ob.GetBestBid(), ob.GetBestAsk())
+	}
+}
+
+func TestStopLimitNeedsBothPrices(t *testing.T) {
+	me := NewMatchingEngine()
+
+	noStop := models.NewOrder("AAPL", models.OrderTypeStopLimit, models.OrderSideSell, 5, 95.0)
+	me.SubmitOrder(noStop)
+	if noStop.Status != models.OrderStatusRejected {
+		t.Errorf("Expected a stop-limit without a stop price rejected, got %s", noStop.Status)
+	}
+
+	noLimit := models.NewOrder("AAPL", models.OrderTypeStopLimit, models.OrderSideSell, 5, 0)
+	noLimit.StopPrice = 96.0
+	me.SubmitOrder(noLimit)
+	if noLimit.Status != models.OrderStatusRejected {
+		t.Errorf("Expected a stop-limit without a limit price rejected, got %s", noLimit.Status)
+	}
+}
+
+func TestTriggeredStopIsNotThrottled(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetClock(clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)))
+	printTrade(me, 100.0, 1)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 95.0))
+
+	stop := models.NewOrder("AAPL", models.OrderTypeStopLoss, models.OrderSideSell, 5, 96.0)
+	me.SubmitOrder(stop)
+
+	// The trade that fires the stop uses up the match rate
+	me.SetMatchRate(1)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideSell, 1, 0))
+
+	if stop.Status != models.OrderStatusFilled || stop.FilledQuantity != 5 {
+		t.Errorf("Expected the triggered stop to fill at once, got %s with %g filled", stop.Status, stop.FilledQuantity)
+	}
+}
@@ -0,0 +1,109 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/acagliol/arbitrax/backend/internal/orderbook"
+)
+
+// STPMode decides which side of a would-be self-trade is cancelled
+type STPMode string
+
+const (
+	// STPCancelResting cancels the participant's resting orders and lets the
+	// incoming order carry on against everyone else
+	STPCancelResting STPMode = "cancel_resting"
+	// STPCancelIncoming cancels the incoming order's remainder on reaching a
+	// level that holds one of the participant's own orders, leaving those
+	// resting orders in place
+	STPCancelIncoming STPMode = "cancel_incoming"
+	// STPCancelBoth cancels the resting orders at that level and the
+	// incoming order's remainder
+	STPCancelBoth STPMode = "cancel_both"
+)
+
+// stpReason is the cancel reason recorded on orders removed by STP
+const stpReason = "self-trade prevention"
+
+// SetSTPMode chooses which side of a would-be self-trade is cancelled. The
+// mode is read at each level an order matches.
+func (me *MatchingEngine) SetSTPMode(mode STPMode) error {
+	switch mode {
+	case STPCancelResting, STPCancelIncoming, STPCancelBoth:
+	default:
+		return fmt.Errorf("unknown STP mode %q", mode)
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.stpMode = mode
+	return nil
+}
+
+// GetSTPMode returns the current self-trade prevention mode
+func (me *MatchingEngine) GetSTPMode() STPMode {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	return me.stpMode
+}
+
+// isSelfTrade reports whether two orders must not trade with each other:
+// they share an STP group, or come from the same account. Orders with
+// neither set are never self-trades.
+func isSelfTrade(a, b *models.Order) bool {
+	if a.STPGroup != "" && a.STPGroup == b.STPGroup {
+		return true
+	}
+	return a.AccountID != "" && a.AccountID == b.AccountID
+}
+
+// preventSelfTrades applies the STP mode to a level the incoming order is
+// about to match. It returns false if the incoming order was cancelled and
+// must not trade at the level.
+func (me *MatchingEngine) preventSelfTrades(ob *orderbook.OrderBook, order *models.Order, level *orderbook.PriceLevel) bool {
+	if order.STPGroup == "" && order.AccountID == "" {
+		return true
+	}
+
+	own := make([]*models.Order, 0)
+	for _, resting := range level.Orders {
+		if resting.IsActive() && isSelfTrade(order, resting) {
+			own = append(own, resting)
+		}
+	}
+	if len(own) == 0 {
+		return true
+	}
+
+	mode := me.GetSTPMode()
+	if mode != STPCancelIncoming {
+		for _, resting := range own {
+			resting.CancelRemainder(me.clock.Now(), stpReason)
+		}
+		ob.PruneLevel(level)
+		ob.MarkChanged(orderbook.ChangeCancel)
+
+		me.mutex.Lock()
+		for _, resting := range own {
+			if orders, exists := me.accountOrders[resting.AccountID]; exists {
+				delete(orders, resting.ID)
+			}
+		}
+		me.mutex.Unlock()
+
+		for _, resting := range own {
+			me.recordLatency(resting)
+			me.emit(Event{Type: EventOrderCancelled, Symbol: resting.Symbol, OrderID: resting.ID})
+		}
+	}
+	if mode == STPCancelResting {
+		return true
+	}
+
+	order.Warn(fmt.Sprintf("self-trade prevented at %g; %g unfilled was cancelled", level.Price, order.RemainingQuantity(me.tolerance.Quantity)))
+	order.CancelRemainder(me.clock.Now(), stpReason)
+	return false
+}
@@ -0,0 +1,175 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestSTPGroupPreventsTradesAcrossAccounts(t *testing.T) {
+	me := NewMatchingEngine()
+
+	newOrder := func(accountID, group string, side models.OrderSide) *models.Order {
+		order := models.NewOrder("AAPL", models.OrderTypeLimit, side, 100, 150.0)
+		order.AccountID = accountID
+		order.STPGroup = group
+		return order
+	}
+
+	// Two sub-accounts of the same firm
+	resting := newOrder("firm-a1", "firm", models.OrderSideSell)
+	me.SubmitOrder(resting)
+	trades := me.SubmitOrder(newOrder("firm-a2", "firm", models.OrderSideBuy))
+
+	if len(trades) != 0 {
+		t.Fatalf("Expected no trades within an STP group, got %d", len(trades))
+	}
+	if resting.Status != models.OrderStatusCancelled || resting.CancelReason != "self-trade prevention" {
+		t.Errorf("Expected resting order cancelled by STP, got %s (%q)", resting.Status, resting.CancelReason)
+	}
+
+	// The buy now rests, and a different group trades against it normally
+	trades = me.SubmitOrder(newOrder("other", "other-firm", models.OrderSideSell))
+	if len(trades) != 1 || trades[0].Quantity != 100 {
+		t.Fatalf("Expected one trade of 100 across groups, got %v", trades)
+	}
+}
+
+func TestSTPSameAccountWithoutGroup(t *testing.T) {
+	me := NewMatchingEngine()
+
+	sell := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0)
+	sell.AccountID = "alice"
+	me.SubmitOrder(sell)
+
+	buy := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	buy.AccountID = "alice"
+	if trades := me.SubmitOrder(buy); len(trades) != 0 {
+		t.Fatalf("Expected no self-trade, got %d trades", len(trades))
+	}
+	if _, resting := me.GetOrderBook("AAPL").GetOrder(buy.ID); !resting {
+		t.Error("Expected the incoming buy to rest once its own ask was cancelled")
+	}
+
+	// Anonymous orders are never treated as self-trades
+	me.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideSell, 10, 300.0))
+	if trades := me.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideBuy, 10, 300.0)); len(trades) != 1 {
+		t.Errorf("Expected orders without an account to trade, got %d trades", len(trades))
+	}
+}
+
+// ownLevelsBook rests asks for alice at 150 and 151 around asks from bob at
+// 149, 150 and 152, then sends alice's aggressive buy through all of them
+func ownLevelsBook(t *testing.T, mode STPMode) (me *MatchingEngine, own []*models.Order, buy *models.Order, trades []*models.Trade) {
+	t.Helper()
+	me = NewMatchingEngine()
+	if err := me.SetSTPMode(mode); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rest := func(accountID string, price float64) *models.Order {
+		order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, price)
+		order.AccountID = accountID
+		me.SubmitOrder(order)
+		return order
+	}
+	rest("bob", 149.0)
+	own = append(own, rest("alice", 150.0))
+	rest("bob", 150.0)
+	own = append(own, rest("alice", 151.0))
+	rest("bob", 152.0)
+
+	buy = models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 400, 152.0)
+	buy.AccountID = "alice"
+	trades = me.SubmitOrder(buy)
+	return me, own, buy, trades
+}
+
+func TestSTPCancelRestingAcrossOwnLevels(t *testing.T) {
+	me, own, buy, trades := ownLevelsBook(t, STPCancelResting)
+
+	// Every own ask crossed is cancelled and bob fills at each of his levels
+	if len(trades) != 3 {
+		t.Fatalf("Expected 3 trades against bob, got %d", len(trades))
+	}
+	for i, price := range []float64{149.0, 150.0, 152.0} {
+		if trades[i].Price != price {
+			t.Errorf("Expected trade %d at %f, got %f", i, price, trades[i].Price)
+		}
+	}
+	for _, order := range own {
+		if order.Status != models.OrderStatusCancelled || order.CancelReason != stpReason {
+			t.Errorf("Expected own ask at %f cancelled by STP, got %s", order.Price, order.Status)
+		}
+	}
+	if buy.FilledQuantity != 300 || buy.Status != models.OrderStatusPartial {
+		t.Errorf("Expected buy partially filled for 300, got %f (%s)", buy.FilledQuantity, buy.Status)
+	}
+	if errs := me.ValidateState(); len(errs) != 0 {
+		t.Errorf("Expected a valid book, got %v", errs)
+	}
+	if me.GetOrderBook("AAPL").GetBestBid() != 152.0 {
+		t.Errorf("Expected the remainder to rest at 152, got %f", me.GetOrderBook("AAPL").GetBestBid())
+	}
+}
+
+func TestSTPCancelIncomingAtFirstOwnLevel(t *testing.T) {
+	me, own, buy, trades := ownLevelsBook(t, STPCancelIncoming)
+
+	// Only the level ahead of alice's first ask trades
+	if len(trades) != 1 || trades[0].Price != 149.0 {
+		t.Fatalf("Expected a single trade at 149, got %v", trades)
+	}
+	if buy.Status != models.OrderStatusCancelled || buy.CancelledQuantity != 300 {
+		t.Errorf("Expected buy cancelled with 300 unfilled, got %s with %f", buy.Status, buy.CancelledQuantity)
+	}
+	if len(buy.Warnings) != 1 {
+		t.Errorf("Expected a self-trade warning, got %v", buy.Warnings)
+	}
+	for _, order := range own {
+		if order.Status != models.OrderStatusPending {
+			t.Errorf("Expected own ask at %f to keep resting, got %s", order.Price, order.Status)
+		}
+	}
+	if bid := me.GetOrderBook("AAPL").GetBestBid(); bid != 0 {
+		t.Errorf("Expected the cancelled buy not to rest, got best bid %f", bid)
+	}
+}
+
+func TestSTPCancelBoth(t *testing.T) {
+	_, own, buy, trades := ownLevelsBook(t, STPCancelBoth)
+
+	if len(trades) != 1 || trades[0].Price != 149.0 {
+		t.Fatalf("Expected a single trade at 149, got %v", trades)
+	}
+	if buy.Status != models.OrderStatusCancelled {
+		t.Errorf("Expected buy cancelled, got %s", buy.Status)
+	}
+	// The level the buy stopped at loses alice's ask; deeper ones are untouched
+	if own[0].Status != models.OrderStatusCancelled {
+		t.Errorf("Expected own ask at 150 cancelled, got %s", own[0].Status)
+	}
+	if own[1].Status != models.OrderStatusPending {
+		t.Errorf("Expected own ask at 151 to keep resting, got %s", own[1].Status)
+	}
+}
+
+func TestSetSTPModeRejectsUnknown(t *testing.T) {
+	me := NewMatchingEngine()
+	if err := me.SetSTPMode("cancel_oldest"); err == nil {
+		t.Error("Expected an error for an unknown STP mode")
+	}
+	if me.GetSTPMode() != STPCancelResting {
+		t.Errorf("Expected the default mode to remain, got %s", me.GetSTPMode())
+	}
+}
+
+func TestSTPCancelledOrdersLeaveTheAccountIndex(t *testing.T) {
+	me, own, _, _ := ownLevelsBook(t, STPCancelResting)
+
+	for _, order := range own {
+		if _, indexed := me.accountOrders["alice"][order.ID]; indexed {
+			t.Errorf("Expected the STP-cancelled order at %g dropped from alice's orders", order.Price)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package matching
+
+import "strings"
+
+// SetSymbolAliases replaces the alias table used to map the symbols clients
+// send onto canonical symbols. Keys are matched case-insensitively.
+func (me *MatchingEngine) SetSymbolAliases(aliases map[string]string) {
+	normalized := make(map[string]string, len(aliases))
+	for alias, symbol := range aliases {
+		normalized[canonicalCase(alias)] = canonicalCase(symbol)
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.symbolAliases = normalized
+}
+
+// NormalizeSymbol maps a client-supplied symbol onto its canonical form so
+// that "aapl", "AAPL" and configured aliases like "AAPL.US" share one book
+func (me *MatchingEngine) NormalizeSymbol(symbol string) string {
+	symbol = canonicalCase(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	if canonical, exists := me.symbolAliases[symbol]; exists {
+		return canonical
+	}
+	return symbol
+}
+
+// canonicalCase trims and upper-cases a symbol
+func canonicalCase(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
@@ -0,0 +1,36 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestSymbolNormalization(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolAliases(map[string]string{"aapl.us": "AAPL"})
+
+	me.SubmitOrder(models.NewOrder("aapl", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 60, 150.0))
+
+	if len(trades) != 1 {
+		t.Fatalf("Expected lower and upper case orders to match, got %d trades", len(trades))
+	}
+
+	trades = me.SubmitOrder(models.NewOrder("AAPL.US", models.OrderTypeLimit, models.OrderSideBuy, 40, 150.0))
+	if len(trades) != 1 {
+		t.Fatalf("Expected aliased order to match, got %d trades", len(trades))
+	}
+
+	if me.GetOrderBook("aapl") != me.GetOrderBook("AAPL") {
+		t.Error("Expected one book for every spelling of the symbol")
+	}
+
+	if len(me.orderBooks) != 1 {
+		t.Errorf("Expected a single book, got %d", len(me.orderBooks))
+	}
+
+	if recent := me.GetRecentTrades(" aapl.us ", 10); len(recent) != 2 {
+		t.Errorf("Expected 2 trades via the alias, got %d", len(recent))
+	}
+}
@@ -0,0 +1,121 @@
+package matching
+
+import (
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// delayedPrint is a trade waiting to be published to the public tape
+type delayedPrint struct {
+	trade     *models.Trade
+	publishAt time.Time
+}
+
+// tape holds the publicly reported trades. Trades are recorded internally as
+// soon as they execute, but hidden-order trades can be held back from the
+// public tape for a configurable delay. The published trades are kept in a
+// trade log under the same limit as the engine's own trade history.
+type tape struct {
+	trades      tradeLog
+	pending     []delayedPrint
+	hiddenDelay time.Duration
+}
+
+// newTape creates a tape keeping up to limit published trades, 0 for no
+// limit
+func newTape(limit int) tape {
+	return tape{trades: newTradeLog(limit)}
+}
+
+// record queues trades for publication, returning those published at once
+func (t *tape) record(trades []*models.Trade) []*models.Trade {
+	published := make([]*models.Trade, 0, len(trades))
+	for _, trade := range trades {
+		if trade.Hidden && t.hiddenDelay > 0 {
+			t.pending = append(t.pending, delayedPrint{
+				trade:     trade,
+				publishAt: trade.Timestamp.Add(t.hiddenDelay),
+			})
+			continue
+		}
+		t.trades.add(trade)
+		published = append(published, trade)
+	}
+	return published
+}
+
+// release publishes the delayed trades that are due at now, returning them
+func (t *tape) release(now time.Time) []*models.Trade {
+	released := make([]*models.Trade, 0)
+	remaining := t.pending[:0]
+	for _, pending := range t.pending {
+		if now.Before(pending.publishAt) {
+			remaining = append(remaining, pending)
+			continue
+		}
+		t.trades.add(pending.trade)
+		released = append(released, pending.trade)
+	}
+	t.pending = remaining
+	return released
+}
+
+// printTrades puts newly executed trades on the public tape, after any
+// delayed prints now due, and sends whatever is published to trade
+// subscribers. The caller must hold the mutex.
+func (me *MatchingEngine) printTrades(trades []*models.Trade) {
+	published := me.tape.release(me.clock.Now())
+	published = append(published, me.tape.record(me.printable(trades))...)
+	me.publishTrades(published)
+}
+
+// ReleaseHiddenPrints publishes the delayed hidden-order prints that are
+// due, returning how many. The tape also releases them whenever it is read
+// or a trade executes; this is meant to be run periodically so they reach
+// trade subscribers on time in a quiet market.
+func (me *MatchingEngine) ReleaseHiddenPrints() int {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	released := me.tape.release(me.clock.Now())
+	me.publishTrades(released)
+	return len(released)
+}
+
+// SetHiddenPrintDelay sets how long trades involving hidden orders are held
+// back from the public tape
+func (me *MatchingEngine) SetHiddenPrintDelay(delay time.Duration) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.tape.hiddenDelay = delay
+}
+
+// GetPublicTrades returns the most recent trades for a symbol as reported on
+// the public tape, newest first
+func (me *MatchingEngine) GetPublicTrades(symbol string, limit int) []*models.Trade {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.publishTrades(me.tape.release(me.clock.Now()))
+	return me.tape.recent(symbol, limit, time.Time{})
+}
+
+// recent returns up to limit of a symbol's public trades, newest first,
+// leaving out any executed after before unless it is zero. Only the
+// symbol's own trades are read.
+func (t *tape) recent(symbol string, limit int, before time.Time) []*models.Trade {
+	held := t.trades.symbol(symbol)
+	result := make([]*models.Trade, 0)
+	for i := len(held) - 1; i >= 0 && len(result) < limit; i-- {
+		trade := held[i]
+		if !before.IsZero() && trade.Timestamp.After(before) {
+			continue
+		}
+		result = append(result, trade)
+	}
+	return result
+}
@@ -0,0 +1,45 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestHiddenTradePrintDelay(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetHiddenPrintDelay(5 * time.Second)
+
+	hiddenSell := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0)
+	hiddenSell.Hidden = true
+	me.SubmitOrder(hiddenSell)
+
+	if snapshot := me.GetOrderBook("AAPL").Snapshot(); len(snapshot.Asks) != 0 {
+		t.Errorf("Expected hidden order to be left out of the snapshot, got %d ask levels", len(snapshot.Asks))
+	}
+
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0))
+	if len(trades) != 1 || !trades[0].Hidden {
+		t.Fatalf("Expected 1 hidden trade, got %v", trades)
+	}
+
+	if recorded := me.GetRecentTrades("AAPL", 10); len(recorded) != 1 {
+		t.Errorf("Expected trade to be recorded immediately, got %d", len(recorded))
+	}
+
+	if public := me.GetPublicTrades("AAPL", 10); len(public) != 0 {
+		t.Errorf("Expected no public trades before the delay, got %d", len(public))
+	}
+
+	mock.Advance(5 * time.Second)
+
+	public := me.GetPublicTrades("AAPL", 10)
+	if len(public) != 1 || public[0].ID != trades[0].ID {
+		t.Errorf("Expected the hidden trade on the public tape after the delay, got %v", public)
+	}
+}
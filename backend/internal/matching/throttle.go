@@ -0,0 +1,138 @@
+package matching
+
+import (
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// matchThrottle is a token bucket limiting how many orders per second reach
+// matching, holding the excess in arrival order
+type matchThrottle struct {
+	rate    float64 // Orders matched per second, also the burst size
+	tokens  float64
+	updated time.Time
+	pending []*models.Order
+}
+
+// refill tops the bucket up for the time elapsed since the last refill
+func (mt *matchThrottle) refill(now time.Time) {
+	if elapsed := now.Sub(mt.updated).Seconds(); elapsed > 0 {
+		mt.tokens = min(mt.rate, mt.tokens+elapsed*mt.rate)
+	}
+	mt.updated = now
+}
+
+// SetMatchRate caps how many synchronously submitted orders are matched per
+// second of engine clock time, to model an exchange with a fixed matching
+// throughput. Orders over the cap are acknowledged with EventOrderAccepted
+// and wait for ReleaseThrottled, which matches them in arrival order as the
+// clock allows; their fills arrive as EventTrade events. Orders that must
+// trade at once are cancelled rather than kept waiting. 0, the default,
+// matches every order immediately, and switching the cap off matches any
+// waiting orders at once.
+func (me *MatchingEngine) SetMatchRate(perSecond float64) {
+	me.mutex.Lock()
+	var released []*models.Order
+	if me.throttle != nil {
+		released = me.throttle.pending
+	}
+	me.throttle = nil
+	if perSecond > 0 {
+		me.throttle = &matchThrottle{rate: perSecond, tokens: perSecond, updated: me.clock.Now(), pending: released}
+		released = nil
+	}
+	me.mutex.Unlock()
+
+	for _, order := range released {
+		me.submitOrder(order, nil)
+	}
+}
+
+// ReleaseThrottled matches as many waiting orders as the match rate allows
+// at the current clock time, returning the number matched. Call it as the
+// clock advances.
+func (me *MatchingEngine) ReleaseThrottled() int {
+	me.mutex.Lock()
+	mt := me.throttle
+	if mt == nil {
+		me.mutex.Unlock()
+		return 0
+	}
+	mt.refill(me.clock.Now())
+	released := make([]*models.Order, 0)
+	for len(mt.pending) > 0 && mt.tokens >= 1 {
+		released = append(released, mt.pending[0])
+		mt.pending[0] = nil
+		mt.pending = mt.pending[1:]
+		mt.tokens--
+	}
+	me.mutex.Unlock()
+
+	for _, order := range released {
+		me.submitOrder(order, nil)
+	}
+	return len(released)
+}
+
+// throttled holds an order back when the match rate is exhausted or earlier
+// orders are still waiting, returning false if it may be matched now. An
+// immediate-or-cancel or minimum-fill order is cancelled instead of held.
+func (me *MatchingEngine) throttled(order *models.Order) bool {
+	me.ReleaseThrottled()
+	order.Symbol = me.NormalizeSymbol(order.Symbol)
+
+	me.mutex.Lock()
+	mt := me.throttle
+	if mt == nil {
+		me.mutex.Unlock()
+		return false
+	}
+	if len(mt.pending) == 0 && mt.tokens >= 1 {
+		mt.tokens--
+		me.mutex.Unlock()
+		return false
+	}
+
+	// An order that must trade at once can't wait its turn
+	if order.IsImmediate() {
+		order.CancelRemainder(me.clock.Now(), "match rate exceeded")
+		me.mutex.Unlock()
+		me.recordLatency(order)
+		me.emit(Event{Type: EventOrderCancelled, Symbol: order.Symbol, OrderID: order.ID})
+		return true
+	}
+	mt.pending = append(mt.pending, order)
+	me.mutex.Unlock()
+
+	me.emit(Event{Type: EventOrderAccepted, Symbol: order.Symbol, OrderID: order.ID})
+	return true
+}
+
+// cancelThrottled cancels an order waiting on the match rate, reporting
+// whether there was one
+func (me *MatchingEngine) cancelThrottled(symbol string, orderID uuid.UUID) bool {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	var order *models.Order
+	if mt := me.throttle; mt != nil {
+		for i, pending := range mt.pending {
+			if pending.ID == orderID && pending.Symbol == symbol {
+				order = pending
+				mt.pending = append(mt.pending[:i:i], mt.pending[i+1:]...)
+				break
+			}
+		}
+	}
+	me.mutex.Unlock()
+
+	if order == nil || !order.IsActive() {
+		return false
+	}
+	order.Cancel(me.clock.Now())
+	me.recordLatency(order)
+	me.emit(Event{Type: EventOrderCancelled, Symbol: symbol, OrderID: orderID})
+	return true
+}
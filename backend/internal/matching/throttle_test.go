@@ -0,0 +1,126 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestMatchRateSpreadsBurstOverTime(t *testing.T) {
+	start := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	mock := clock.NewMock(start)
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+
+	// A deep offer every buy can trade against
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1000, 150.0))
+
+	me.SetMatchRate(2)
+
+	tradeTimes := make([]time.Time, 0)
+	me.OnEvent(func(event Event) {
+		if event.Type == EventTrade {
+			tradeTimes = append(tradeTimes, event.Trade.Timestamp)
+		}
+	})
+
+	for i := 0; i < 6; i++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0))
+	}
+	if len(tradeTimes) != 2 {
+		t.Fatalf("Expected 2 trades in the first burst, got %d", len(tradeTimes))
+	}
+
+	for second := 1; second <= 2; second++ {
+		mock.Advance(time.Second)
+		if released := me.ReleaseThrottled(); released != 2 {
+			t.Errorf("Expected 2 orders released after %ds, got %d", second, released)
+		}
+	}
+	if released := me.ReleaseThrottled(); released != 0 {
+		t.Errorf("Expected nothing left to release, got %d", released)
+	}
+
+	if len(tradeTimes) != 6 {
+		t.Fatalf("Expected 6 trades, got %d", len(tradeTimes))
+	}
+	for i, at := range tradeTimes {
+		if expected := start.Add(time.Duration(i/2) * time.Second); !at.Equal(expected) {
+			t.Errorf("Expected trade %d at %v, got %v", i, expected, at)
+		}
+	}
+}
+
+func TestDisablingMatchRateReleasesWaitingOrders(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetMatchRate(1)
+
+	first := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0)
+	second := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 149.0)
+	me.SubmitOrder(first)
+	me.SubmitOrder(second)
+
+	if _, resting := me.GetOrderBook("AAPL").GetOrder(second.ID); resting {
+		t.Fatal("Expected the second order to wait for the match rate")
+	}
+
+	me.SetMatchRate(0)
+	if _, resting := me.GetOrderBook("AAPL").GetOrder(second.ID); !resting {
+		t.Error("Expected the waiting order to rest once the cap is removed")
+	}
+}
+
+func TestMatchRateCancelsImmediateOrders(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1000, 150.0))
+	me.SetMatchRate(1)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0))
+
+	ioc := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0)
+	ioc.TimeInForce = models.TimeInForceIOC
+	minFill := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0)
+	minFill.MinFillQuantity = 10
+	for _, order := range []*models.Order{ioc, minFill} {
+		me.SubmitOrder(order)
+		if order.Status != models.OrderStatusCancelled || order.CancelledQuantity != 10 {
+			t.Errorf("Expected the order cancelled rather than held, got %s", order.Status)
+		}
+	}
+
+	mock.Advance(time.Minute)
+	if released := me.ReleaseThrottled(); released != 0 || ioc.FilledQuantity != 0 {
+		t.Errorf("Expected nothing held to trade later, got %d released", released)
+	}
+}
+
+func TestCancelThrottledOrder(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetMatchRate(1)
+
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 99.0))
+	waiting := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 98.0)
+	me.SubmitOrder(waiting)
+
+	if !me.CancelOrder("AAPL", waiting.ID) || waiting.Status != models.OrderStatusCancelled {
+		t.Fatalf("Expected the throttled order cancelled, got %s", waiting.Status)
+	}
+
+	mock.Advance(time.Second)
+	if released := me.ReleaseThrottled(); released != 0 {
+		t.Errorf("Expected nothing left to release, got %d", released)
+	}
+	if _, resting := me.GetOrderBook("AAPL").GetOrder(waiting.ID); resting {
+		t.Error("Expected the cancelled order kept out of the book")
+	}
+}
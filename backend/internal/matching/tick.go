@@ -0,0 +1,43 @@
+package matching
+
+import (
+	"math"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// tradePrice returns the price a trade against a resting order prints at.
+// Order prices aren't checked against the tick size, so a seeded, imported
+// or adjusted order can rest off-tick; its trades are moved to the adjacent
+// tick towards the resting order's limit, so every print is on-tick and
+// never goes past the price the resting order was willing to trade at. If
+// the incoming limit is off-tick too and the tick lies beyond it, the trade
+// prints at the resting price rather than breach either limit.
+func (me *MatchingEngine) tradePrice(symbol string, incoming, resting *models.Order) float64 {
+	tick := me.GetSymbolConfig(symbol).TickSize
+	if tick <= 0 {
+		return resting.Price
+	}
+	// A resting sell rounds up and a resting buy rounds down, each staying
+	// inside its own limit
+	price := snapToTick(resting.Price, tick, resting.Side == models.OrderSideSell)
+	if incoming.Type != models.OrderTypeMarket && incoming.Price > 0 {
+		if incoming.Side == models.OrderSideBuy && price > incoming.Price ||
+			incoming.Side == models.OrderSideSell && price < incoming.Price {
+			return resting.Price
+		}
+	}
+	return price
+}
+
+// snapToTick moves an off-tick price to the next tick up or down. A price
+// already on a tick comes back unchanged.
+func snapToTick(price, tick float64, up bool) float64 {
+	if math.Abs(price-math.Round(price/tick)*tick) < tick*1e-9 {
+		return price
+	}
+	if up {
+		return tickMultiple(math.Ceil(price/tick), tick)
+	}
+	return tickMultiple(math.Floor(price/tick), tick)
+}
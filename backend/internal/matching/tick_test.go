@@ -0,0 +1,71 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestOffTickRestingPriceTradesOnTick(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: 0.01})
+
+	// Seed off-tick orders straight into the book, as an import would
+	ob := me.GetOrCreateOrderBook("AAPL")
+	seededAsk := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.004)
+	seededBid := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.996)
+	ob.AddOrder(seededAsk)
+	ob.AddOrder(seededBid)
+
+	buy := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.01)
+	trades := me.SubmitOrder(buy)
+	if len(trades) != 1 || trades[0].Price != 100.01 {
+		t.Fatalf("Expected the buy to print on-tick at the ask's 100.01, got %+v", trades)
+	}
+	if buy.FilledPrice != 100.01 || seededAsk.FilledPrice != 100.01 {
+		t.Errorf("Expected both fills at 100.01, got %g and %g", buy.FilledPrice, seededAsk.FilledPrice)
+	}
+
+	sell := me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideSell, 10, 0)
+	trades = me.SubmitOrder(sell)
+	if len(trades) != 1 || trades[0].Price != 99.99 {
+		t.Errorf("Expected the bid's print rounded down to 99.99, got %+v", trades)
+	}
+	if ob.LastPrice != 99.99 {
+		t.Errorf("Expected the last price on-tick, got %g", ob.LastPrice)
+	}
+}
+
+func TestOffTickPrintStaysWithinBothLimits(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: 0.01})
+
+	ob := me.GetOrCreateOrderBook("AAPL")
+	seededAsk := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.004)
+	ob.AddOrder(seededAsk)
+
+	// The ask would round up to 100.01, past the buyer's off-tick limit
+	buy := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.005)
+	trades := me.SubmitOrder(buy)
+	if len(trades) != 1 || trades[0].Price != 100.004 {
+		t.Errorf("Expected the trade at the resting 100.004, got %+v", trades)
+	}
+}
+
+func TestOnTickPricesUnchanged(t *testing.T) {
+	for _, tc := range []struct {
+		price, tick float64
+		up          bool
+		expected    float64
+	}{
+		{100.05, 0.05, true, 100.05},
+		{100.05, 0.05, false, 100.05},
+		{100.07, 0.05, true, 100.1},
+		{100.07, 0.05, false, 100.05},
+		{0.3, 0.1, false, 0.3},
+	} {
+		if snapped := snapToTick(tc.price, tc.tick, tc.up); snapped != tc.expected {
+			t.Errorf("Expected %g snapped to %g, got %g", tc.price, tc.expected, snapped)
+		}
+	}
+}
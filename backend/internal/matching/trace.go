@@ -0,0 +1,49 @@
+package matching
+
+import (
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// TraceAction identifies a single matching decision
+type TraceAction string
+
+const (
+	TracePeek       TraceAction = "peek"
+	TracePriceCheck TraceAction = "price_check"
+	TraceMatch      TraceAction = "match"
+	TracePop        TraceAction = "pop"
+	TraceHalt       TraceAction = "halt"
+	TraceRest       TraceAction = "rest"
+)
+
+// TraceStep is one matching decision and the level it concerned
+type TraceStep struct {
+	Action   TraceAction `json:"action"`
+	Price    float64     `json:"price"`
+	Quantity float64     `json:"quantity,omitempty"`
+	Accepted bool        `json:"accepted,omitempty"` // Result of a price check
+}
+
+// MatchTrace is the step-by-step record of how one order was matched
+type MatchTrace struct {
+	OrderID uuid.UUID   `json:"order_id"`
+	Steps   []TraceStep `json:"steps"`
+}
+
+// record appends a step. A nil trace records nothing, so untraced
+// submissions pay only for the nil check.
+func (t *MatchTrace) record(step TraceStep) {
+	if t == nil {
+		return
+	}
+	t.Steps = append(t.Steps, step)
+}
+
+// SubmitOrderTraced submits an order like SubmitOrder and also returns a
+// trace of every matching decision, for debugging unexpected fills
+func (me *MatchingEngine) SubmitOrderTraced(order *models.Order) ([]*models.Trade, *MatchTrace) {
+	trace := &MatchTrace{OrderID: order.ID, Steps: make([]TraceStep, 0)}
+	trades := me.submitOrder(order, trace)
+	return trades, trace
+}
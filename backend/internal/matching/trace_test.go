@@ -0,0 +1,48 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestSubmitOrderTracedMultiLevelSweep(t *testing.T) {
+	me := NewMatchingEngine()
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 151.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 153.0))
+
+	buyOrder := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 120, 152.0)
+	trades, trace := me.SubmitOrderTraced(buyOrder)
+
+	if len(trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %d", len(trades))
+	}
+	if trace.OrderID != buyOrder.ID {
+		t.Errorf("Expected trace for order %s, got %s", buyOrder.ID, trace.OrderID)
+	}
+
+	expected := []TraceStep{
+		{Action: TracePeek, Price: 150.0},
+		{Action: TracePriceCheck, Price: 150.0, Accepted: true},
+		{Action: TraceMatch, Price: 150.0, Quantity: 50},
+		{Action: TracePop, Price: 150.0},
+		{Action: TracePeek, Price: 151.0},
+		{Action: TracePriceCheck, Price: 151.0, Accepted: true},
+		{Action: TraceMatch, Price: 151.0, Quantity: 50},
+		{Action: TracePop, Price: 151.0},
+		{Action: TracePeek, Price: 153.0},
+		{Action: TracePriceCheck, Price: 153.0, Accepted: false},
+		{Action: TraceRest, Price: 152.0, Quantity: 20},
+	}
+
+	if len(trace.Steps) != len(expected) {
+		t.Fatalf("Expected %d trace steps, got %d: %+v", len(expected), len(trace.Steps), trace.Steps)
+	}
+	for i, step := range trace.Steps {
+		if step != expected[i] {
+			t.Errorf("Step %d: expected %+v, got %+v", i, expected[i], step)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package matching
+
+import (
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// SetMaxTradesPerOrder caps the trades one submission may make, bounding the
+// latency of an aggressive order into a fragmented book. Once the cap is
+// reached a market order's remainder is cancelled, and a limit order's is
+// parked: it stays working and carries on matching when the next order for
+// its symbol arrives or ContinueParkedOrders runs. 0 removes the cap.
+func (me *MatchingEngine) SetMaxTradesPerOrder(limit int) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.tradeCap = limit
+}
+
+// GetMaxTradesPerOrder returns the per-submission trade cap, 0 for none
+func (me *MatchingEngine) GetMaxTradesPerOrder() int {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	return me.tradeCap
+}
+
+// ContinueParkedOrders resumes matching every parked order, returning the
+// trades made. Like ExpireStaleOrders it is meant to be run
+// periodically so parked orders progress on quiet symbols.
+func (me *MatchingEngine) ContinueParkedOrders() []*models.Trade {
+	me.mutex.RLock()
+	symbols := make([]string, 0, len(me.parked))
+	for symbol := range me.parked {
+		symbols = append(symbols, symbol)
+	}
+	me.mutex.RUnlock()
+
+	trades := make([]*models.Trade, 0)
+	for _, symbol := range symbols {
+		trades = append(trades, me.continueParked(symbol)...)
+	}
+	return trades
+}
+
+// capReached reports whether trades already made use up the cap
+func capReached(maxTrades int, trades []*models.Trade) bool {
+	return maxTrades > 0 && len(trades) >= maxTrades
+}
+
+// tradesLeft returns how many more trades the cap allows, 0 for no limit
+func tradesLeft(maxTrades int, trades []*models.Trade) int {
+	if maxTrades <= 0 {
+		return 0
+	}
+	return maxTrades - len(trades)
+}
+
+// park holds a limit order's remainder until matching continues
+func (me *MatchingEngine) park(order *models.Order) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.parked[order.Symbol] = append(me.parked[order.Symbol], order)
+}
+
+// continueParked resumes a symbol's parked orders in the order they were
+// parked. They are matched without being counted again as new orders. An order that reaches the cap again is parked ahead of the rest.
+// Nothing resumes while the symbol is halted or its matching is paused.
+func (me *MatchingEngine) continueParked(symbol string) []*models.Trade {
+	if me.IsHalted(symbol) || me.IsMatchingPaused(symbol) {
+		return make([]*models.Trade, 0)
+	}
+
+	me.mutex.Lock()
+	waiting := me.parked[symbol]
+	delete(me.parked, symbol)
+	me.mutex.Unlock()
+
+	trades := make([]*models.Trade, 0)
+	ob := me.GetOrderBook(symbol)
+	if ob == nil {
+		return trades
+	}
+
+	for i, order := range waiting {
+		if !order.IsActive() {
+			continue
+		}
+		trades = append(trades, me.execute(ob, order, nil, true)...)
+
+		if _, resting := ob.GetOrder(order.ID); order.IsActive() && !resting {
+			me.mutex.Lock()
+			me.parked[symbol] = append(me.parked[symbol], waiting[i+1:]...)
+			me.mutex.Unlock()
+			break
+		}
+	}
+	return trades
+}
+
+// cancelParked cancels an order parked by the trade cap, returning false if
+// no such order is parked
+func (me *MatchingEngine) cancelParked(symbol string, orderID uuid.UUID) bool {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	var order *models.Order
+	waiting := me.parked[symbol]
+	for i, parked := range waiting {
+		if parked.ID == orderID {
+			order = parked
+			me.parked[symbol] = append(waiting[:i:i], waiting[i+1:]...)
+			break
+		}
+	}
+	me.mutex.Unlock()
+
+	if order == nil || !order.IsActive() {
+		return false
+	}
+	order.Cancel(me.clock.Now())
+	me.recordLatency(order)
+	me.emit(Event{Type: EventOrderCancelled, Symbol: symbol, OrderID: orderID})
+	return true
+}
@@ -0,0 +1,135 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// fragmentedBook rests n one-lot asks from 100 upward
+func fragmentedBook(me *MatchingEngine, n int) {
+	for i := 0; i < n; i++ {
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 100.0+float64(i%5)))
+	}
+}
+
+func TestTradeCapCancelsMarketRemainder(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetMaxTradesPerOrder(10)
+	fragmentedBook(me, 50)
+
+	market := me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 30, 0)
+	trades := me.SubmitOrder(market)
+	if len(trades) != 10 {
+		t.Fatalf("Expected the cap to stop at 10 trades, got %d", len(trades))
+	}
+	if market.Status != models.OrderStatusCancelled || market.CancelledQuantity != 20 {
+		t.Errorf("Expected the remaining 20 cancelled, got %s with %g cancelled", market.Status, market.CancelledQuantity)
+	}
+	if len(market.Warnings) == 0 {
+		t.Error("Expected a warning about the trade cap")
+	}
+}
+
+func TestTradeCapParksLimitRemainder(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetMaxTradesPerOrder(10)
+	fragmentedBook(me, 25)
+
+	limit := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 25, 105.0)
+	trades := me.SubmitOrder(limit)
+	if len(trades) != 10 || limit.FilledQuantity != 10 {
+		t.Fatalf("Expected the cap to stop at 10 trades, got %d", len(trades))
+	}
+	if !limit.IsActive() || len(limit.Warnings) == 0 {
+		t.Errorf("Expected the remainder kept working with a warning, got %s", limit.Status)
+	}
+
+	// Parked rather than resting, so the book is never crossed
+	ob := me.GetOrderBook("AAPL")
+	if _, resting := ob.GetOrder(limit.ID); resting || me.IsHalted("AAPL") {
+		t.Error("Expected the remainder parked off the book")
+	}
+
+	// The next submission lets the parked order carry on first
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 90.0))
+	if limit.FilledQuantity != 20 {
+		t.Errorf("Expected another 10 filled on the next submission, got %g", limit.FilledQuantity)
+	}
+
+	if trades := me.ContinueParkedOrders(); len(trades) != 5 {
+		t.Errorf("Expected the last 5 lots to fill, got %d trades", len(trades))
+	}
+	if limit.FilledQuantity != 25 || limit.Status != models.OrderStatusFilled {
+		t.Errorf("Expected the order filled, got %g (%s)", limit.FilledQuantity, limit.Status)
+	}
+}
+
+func TestResumedOrderIsCountedOnce(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetRateWindow(time.Second)
+	me.SetMaxTradesPerOrder(10)
+	fragmentedBook(me, 25)
+
+	limit := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 25, 105.0)
+	me.SubmitOrder(limit)
+	me.ContinueParkedOrders()
+	me.ContinueParkedOrders()
+	if limit.Status != models.OrderStatusFilled {
+		t.Fatalf("Expected the parked order filled, got %s", limit.Status)
+	}
+
+	// 26 orders were submitted, making 25 trades, however often matching resumed
+	rates := me.GetRates("AAPL")
+	if rates.OrdersPerSecond != 26 || rates.TradesPerSecond != 25 {
+		t.Errorf("Expected 26 orders and 25 trades, got %v and %v", rates.OrdersPerSecond, rates.TradesPerSecond)
+	}
+
+	// Every resting lot and the parked order each complete once
+	if latency := me.ExecutionLatency("AAPL"); latency.Count != 26 {
+		t.Errorf("Expected 26 latency samples, got %d", latency.Count)
+	}
+}
+
+func TestCancelParkedOrder(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetMaxTradesPerOrder(2)
+	fragmentedBook(me, 5)
+
+	limit := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 105.0)
+	me.SubmitOrder(limit)
+
+	if !me.CancelOrder("AAPL", limit.ID) || limit.Status != models.OrderStatusCancelled {
+		t.Fatalf("Expected the parked order cancelled, got %s", limit.Status)
+	}
+	if trades := me.ContinueParkedOrders(); len(trades) != 0 {
+		t.Errorf("Expected nothing left to continue, got %d trades", len(trades))
+	}
+}
+
+func TestParkedOrdersWaitOutAPause(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetMaxTradesPerOrder(2)
+	fragmentedBook(me, 5)
+
+	limit := me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 105.0)
+	me.SubmitOrder(limit)
+	me.PauseMatching("AAPL", PauseQueue)
+
+	// A passive order is accepted during the pause but must not set the
+	// parked order trading
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 90.0))
+	if trades := me.ContinueParkedOrders(); len(trades) != 0 || limit.FilledQuantity != 2 {
+		t.Fatalf("Expected the parked order held during the pause, got %g filled", limit.FilledQuantity)
+	}
+
+	me.ResumeMatching("AAPL")
+	if me.ContinueParkedOrders(); limit.FilledQuantity != 4 {
+		t.Errorf("Expected the parked order to carry on after the resume, got %g filled", limit.FilledQuantity)
+	}
+}
@@ -0,0 +1,104 @@
+package matching
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// DefaultRealizedSpreadHorizon is how long after a trade the mid is read for
+// its realized spread
+const DefaultRealizedSpreadHorizon = 5 * time.Minute
+
+// TradeCost is the execution cost of a trade measured against the quotes
+// around it. Realized spread is signed by the taker's side, so it is
+// positive when the price moved back towards the maker after the trade.
+type TradeCost struct {
+	TradeID         uuid.UUID        `json:"trade_id"`
+	Price           float64          `json:"price"`
+	Quantity        float64          `json:"quantity"`
+	TakerSide       models.OrderSide `json:"taker_side"`
+	Timestamp       time.Time        `json:"timestamp"`
+	Mid             float64          `json:"mid"`              // Mid just before the trade, 0 if unknown
+	EffectiveSpread float64          `json:"effective_spread"` // 2 * |price - mid|
+	LaterMid        float64          `json:"later_mid"`        // Mid at the horizon, 0 until it has passed
+	RealizedSpread  float64          `json:"realized_spread"`  // 2 * direction * (price - later mid)
+}
+
+// SetRealizedSpreadHorizon sets how long after a trade the mid is read for
+// its realized spread
+func (me *MatchingEngine) SetRealizedSpreadHorizon(horizon time.Duration) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.costHorizon = horizon
+}
+
+// TradeCostMetrics returns the effective and realized spread of up to limit
+// of a symbol's trades, newest first, read from the spread history. Trades
+// older than the kept quote history have no mid and report zero spreads.
+func (me *MatchingEngine) TradeCostMetrics(symbol string, limit int) []TradeCost {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	quotes := me.spreads[symbol]
+	now := me.clock.Now()
+
+	result := make([]TradeCost, 0)
+	for _, trade := range me.trades.recent(symbol, limit) {
+		cost := TradeCost{
+			TradeID:   trade.ID,
+			Price:     trade.Price,
+			Quantity:  trade.Quantity,
+			TakerSide: trade.TakerSide,
+			Timestamp: trade.Timestamp,
+		}
+
+		// The quote recorded at the trade's own instant is the one the trade
+		// left behind, so the prevailing quote is the last one before it
+		if mid := midAt(quotes, trade.Timestamp, false); mid > 0 {
+			cost.Mid = mid
+			cost.EffectiveSpread = 2 * math.Abs(trade.Price-mid)
+		}
+
+		horizon := trade.Timestamp.Add(me.costHorizon)
+		if !now.Before(horizon) {
+			if mid := midAt(quotes, horizon, true); mid > 0 {
+				direction := 1.0
+				if trade.TakerSide == models.OrderSideSell {
+					direction = -1.0
+				}
+				cost.LaterMid = mid
+				cost.RealizedSpread = 2 * direction * (trade.Price - mid)
+			}
+		}
+
+		result = append(result, cost)
+	}
+	return result
+}
+
+// midAt returns the mid of the last two-sided quote recorded before at, or at
+// or before it if inclusive, or 0 if there is none
+func midAt(quotes []SpreadPoint, at time.Time, inclusive bool) float64 {
+	i := sort.Search(len(quotes), func(i int) bool {
+		if inclusive {
+			return quotes[i].Timestamp.After(at)
+		}
+		return !quotes[i].Timestamp.Before(at)
+	})
+	if i == 0 {
+		return 0
+	}
+
+	quote := quotes[i-1]
+	if quote.Bid <= 0 || quote.Ask <= 0 {
+		return 0
+	}
+	return (quote.Bid + quote.Ask) / 2
+}
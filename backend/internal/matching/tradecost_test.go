@@ -0,0 +1,60 @@
+package matching
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestTradeCostMetrics(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetRealizedSpreadHorizon(time.Minute)
+
+	// 99 / 101, mid 100
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+
+	mock.Advance(time.Second)
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 5, 0))
+
+	// 100.8 / 101, mid 100.9
+	mock.Advance(29 * time.Second)
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.8))
+
+	mock.Advance(60 * time.Second)
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideSell, 4, 0))
+
+	mock.Advance(30 * time.Second)
+	costs := me.TradeCostMetrics("AAPL", 10)
+	if len(costs) != 2 {
+		t.Fatalf("Expected 2 trade costs, got %d", len(costs))
+	}
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	sell, buy := costs[0], costs[1]
+	if !near(buy.Mid, 100.0) || !near(buy.EffectiveSpread, 2.0) {
+		t.Errorf("Expected buy effective spread 2 against mid 100, got %g against %g", buy.EffectiveSpread, buy.Mid)
+	}
+	if !near(buy.LaterMid, 100.9) || !near(buy.RealizedSpread, 0.2) {
+		t.Errorf("Expected buy realized spread 0.2 against mid 100.9, got %g against %g", buy.RealizedSpread, buy.LaterMid)
+	}
+
+	if !near(sell.Mid, 100.9) || !near(sell.EffectiveSpread, 0.2) {
+		t.Errorf("Expected sell effective spread 0.2 against mid 100.9, got %g against %g", sell.EffectiveSpread, sell.Mid)
+	}
+	if sell.LaterMid != 0 || sell.RealizedSpread != 0 {
+		t.Errorf("Expected no realized spread before the horizon, got %g", sell.RealizedSpread)
+	}
+
+	mock.Advance(time.Minute)
+	sell = me.TradeCostMetrics("AAPL", 1)[0]
+	if !near(sell.LaterMid, 100.9) || !near(sell.RealizedSpread, 0.2) {
+		t.Errorf("Expected sell realized spread 0.2 against mid 100.9, got %g against %g", sell.RealizedSpread, sell.LaterMid)
+	}
+}
@@ -0,0 +1,135 @@
+package matching
+
+import (
+	"iter"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// DefaultTradeHistory is how many trades the engine keeps by default
+const DefaultTradeHistory = 100000
+
+// tradeLog keeps the most recent trades across all symbols in a ring
+// buffer, evicting the oldest once it holds its limit. Storage grows as
+// trades arrive, so a large limit costs nothing until it is used. The
+// trades held are also indexed by symbol, so reading one symbol's trades
+// costs nothing for the trades of others.
+type tradeLog struct {
+	trades   []*models.Trade
+	start    int                        // Index of the oldest trade once the ring has wrapped
+	limit    int                        // Most trades kept, 0 for no limit
+	bySymbol map[string][]*models.Trade // The trades held by symbol, oldest first
+}
+
+// newTradeLog creates a log keeping up to limit trades, 0 for no limit
+func newTradeLog(limit int) tradeLog {
+	return tradeLog{
+		trades:   make([]*models.Trade, 0),
+		limit:    limit,
+		bySymbol: make(map[string][]*models.Trade),
+	}
+}
+
+// add appends trades, evicting the oldest beyond the limit
+func (l *tradeLog) add(trades ...*models.Trade) {
+	for _, trade := range trades {
+		l.bySymbol[trade.Symbol] = append(l.bySymbol[trade.Symbol], trade)
+		if l.limit <= 0 || len(l.trades) < l.limit {
+			l.trades = append(l.trades, trade)
+			continue
+		}
+		l.evict(l.trades[l.start])
+		l.trades[l.start] = trade
+		l.start = (l.start + 1) % len(l.trades)
+	}
+}
+
+// evict drops the oldest trade from the symbol index, which being the
+// oldest trade overall is the first of its symbol's
+func (l *tradeLog) evict(trade *models.Trade) {
+	held := l.bySymbol[trade.Symbol]
+	if len(held) <= 1 {
+		delete(l.bySymbol, trade.Symbol)
+		return
+	}
+	// Clear the slot so the evicted trade can be garbage collected
+	held[0] = nil
+	l.bySymbol[trade.Symbol] = held[1:]
+}
+
+// symbol returns the trades held for a symbol, oldest first. The slice is
+// the log's own and must not be modified.
+func (l *tradeLog) symbol(symbol string) []*models.Trade {
+	return l.bySymbol[symbol]
+}
+
+// recent returns up to limit of a symbol's trades, newest first
+func (l *tradeLog) recent(symbol string, limit int) []*models.Trade {
+	held := l.bySymbol[symbol]
+	result := make([]*models.Trade, 0)
+	for i := len(held) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, held[i])
+	}
+	return result
+}
+
+// len returns the number of trades held
+func (l *tradeLog) len() int {
+	return len(l.trades)
+}
+
+// at returns the i'th oldest trade held
+func (l *tradeLog) at(i int) *models.Trade {
+	return l.trades[(l.start+i)%len(l.trades)]
+}
+
+// all yields the trades held, oldest first
+func (l *tradeLog) all() iter.Seq[*models.Trade] {
+	return func(yield func(*models.Trade) bool) {
+		for i := 0; i < l.len(); i++ {
+			if !yield(l.at(i)) {
+				return
+			}
+		}
+	}
+}
+
+// setLimit changes the limit, keeping the newest trades that fit
+func (l *tradeLog) setLimit(limit int) {
+	kept := make([]*models.Trade, 0, l.len())
+	for trade := range l.all() {
+		kept = append(kept, trade)
+	}
+	if limit > 0 && len(kept) > limit {
+		kept = kept[len(kept)-limit:]
+	}
+
+	*l = newTradeLog(limit)
+	l.add(kept...)
+}
+
+// SetTradeHistory sets how many trades the engine keeps for queries such as
+// GetRecentTrades, across all symbols. Once the limit is reached the oldest
+// trades are evicted. The public tape is held to the same limit. 0 keeps
+// every trade; DefaultTradeHistory is the default.
+func (me *MatchingEngine) SetTradeHistory(limit int) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.trades.setLimit(max(limit, 0))
+	me.tape.trades.setLimit(max(limit, 0))
+}
+
+// TradeHistoryStart returns when the oldest trade still held executed, or
+// false if none are. Queries over the trade history see nothing earlier,
+// so a caller can tell a quiet period from one already evicted.
+func (me *MatchingEngine) TradeHistoryStart() (time.Time, bool) {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	if me.trades.len() == 0 {
+		return time.Time{}, false
+	}
+	return me.trades.at(0).Timestamp, true
+}
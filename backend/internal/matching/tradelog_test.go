@@ -0,0 +1,176 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestTradeHistoryEvictsOldest(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetTradeHistory(5)
+
+	// Alternate symbols so each keeps some trades after the wrap
+	for i := 0; i < 12; i++ {
+		symbol := "AAPL"
+		if i%3 == 2 {
+			symbol = "MSFT"
+		}
+		price := 100.0 + float64(i)
+		me.SubmitOrder(models.NewOrder(symbol, models.OrderTypeLimit, models.OrderSideSell, 1, price))
+		me.SubmitOrder(models.NewOrder(symbol, models.OrderTypeLimit, models.OrderSideBuy, 1, price))
+	}
+
+	// The last 5 trades are 107 to 111, of which 108 and 111 are MSFT
+	aapl := me.GetRecentTrades("AAPL", 10)
+	expected := []float64{110, 109, 107}
+	if len(aapl) != len(expected) {
+		t.Fatalf("Expected %d AAPL trades kept, got %d", len(expected), len(aapl))
+	}
+	for i, price := range expected {
+		if aapl[i].Price != price {
+			t.Errorf("Expected AAPL trade %d at %g, got %g", i, price, aapl[i].Price)
+		}
+	}
+
+	msft := me.GetRecentTrades("MSFT", 1)
+	if len(msft) != 1 || msft[0].Price != 111 {
+		t.Errorf("Expected the newest MSFT trade at 111, got %v", msft)
+	}
+
+	all := me.GetTradesInRange(aapl[2].Timestamp, aapl[0].Timestamp.Add(1))
+	if len(all) == 0 || all[0].Price != 107 {
+		t.Errorf("Expected range queries to start from the oldest kept trade, got %v", all)
+	}
+}
+
+func TestSetTradeHistoryKeepsNewest(t *testing.T) {
+	me := NewMatchingEngine()
+	for i := 0; i < 10; i++ {
+		printTrade(me, 100.0+float64(i), 1)
+	}
+
+	me.SetTradeHistory(3)
+	trades := me.GetRecentTrades("AAPL", 10)
+	if len(trades) != 3 || trades[0].Price != 109 || trades[2].Price != 107 {
+		t.Fatalf("Expected the 3 newest trades kept, got %d", len(trades))
+	}
+
+	// Raising the limit again keeps what is left and stops evicting
+	me.SetTradeHistory(0)
+	printTrade(me, 110.0, 1)
+	if trades := me.GetRecentTrades("AAPL", 10); len(trades) != 4 || trades[0].Price != 110 {
+		t.Errorf("Expected 4 trades newest at 110, got %d", len(trades))
+	}
+}
+
+func TestPublicTapeKeepsTheHistoryLimit(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetTradeHistory(5)
+
+	for i := 0; i < 12; i++ {
+		printTrade(me, 100.0+float64(i), 1)
+		if held := me.tape.trades.len(); held > 5 {
+			t.Fatalf("Expected the tape to hold at most 5 trades, got %d", held)
+		}
+	}
+
+	public := me.GetPublicTrades("AAPL", 10)
+	if len(public) != 5 || public[0].Price != 111 || public[4].Price != 107 {
+		t.Errorf("Expected the 5 newest public trades, got %d", len(public))
+	}
+}
+
+func TestTradeHistoryWrapsRepeatedly(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetTradeHistory(7)
+	start := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+
+	// 25 trades in batches of 3 wrap a ring of 7 three times over, with the
+	// batches landing across the wrap point
+	for i := 0; i < 25; i += 3 {
+		batch := make([]*models.Trade, 0, 3)
+		for j := i; j < i+3 && j < 25; j++ {
+			symbol := "AAPL"
+			if j%2 == 1 {
+				symbol = "MSFT"
+			}
+			trade := models.NewTrade(symbol, uuid.Nil, uuid.Nil, 100.0+float64(j), 1)
+			trade.Timestamp = start.Add(time.Duration(j) * time.Second)
+			batch = append(batch, trade)
+		}
+		me.trades.add(batch...)
+	}
+
+	// Trades 18 to 24 are held, oldest first
+	held := make([]float64, 0)
+	for trade := range me.trades.all() {
+		held = append(held, trade.Price)
+	}
+	if len(held) != 7 || held[0] != 118 || held[6] != 124 {
+		t.Fatalf("Expected trades 118 to 124 held, got %v", held)
+	}
+	for i := 1; i < len(held); i++ {
+		if held[i] != held[i-1]+1 {
+			t.Errorf("Expected the held trades in order, got %v", held)
+		}
+	}
+
+	aapl, msft := me.trades.symbol("AAPL"), me.trades.symbol("MSFT")
+	if len(aapl) != 4 || aapl[0].Price != 118 || len(msft) != 3 || msft[0].Price != 119 {
+		t.Errorf("Expected the symbol index to match, got %d AAPL and %d MSFT", len(aapl), len(msft))
+	}
+
+	if oldest, ok := me.TradeHistoryStart(); !ok || !oldest.Equal(start.Add(18*time.Second)) {
+		t.Errorf("Expected the history to start at trade 18, got %s", oldest)
+	}
+	if trades := me.GetTradesInRange(start, start.Add(time.Minute)); len(trades) != 7 {
+		t.Errorf("Expected a range over everything to return only the 7 held, got %d", len(trades))
+	}
+}
+
+// BenchmarkRecentTradesRareSymbol reads the few public trades of a rare
+// symbol amid a million for a busy one, all executed through the engine,
+// the way /trades/:symbol does. The cost should match reading the busy
+// symbol's latest trades, not grow with the busy symbol's history as the
+// full scan in the baseline does.
+func BenchmarkRecentTradesRareSymbol(b *testing.B) {
+	me := NewMatchingEngine()
+	me.SetTradeHistory(0)
+
+	me.SubmitOrder(models.NewOrder("RARE", models.OrderTypeLimit, models.OrderSideSell, 10, 10.0))
+	for i := 0; i < 10; i++ {
+		me.SubmitOrder(models.NewOrder("RARE", models.OrderTypeMarket, models.OrderSideBuy, 1, 0))
+	}
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1e6, 100.0))
+	for i := 0; i < 1000000; i++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 1, 0))
+	}
+
+	for _, symbol := range []string{"RARE", "AAPL"} {
+		b.Run(symbol, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				me.GetPublicTrades(symbol, 50)
+			}
+		})
+	}
+
+	// The full scan of the tape the symbol index replaces
+	b.Run("RARE/scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			me.mutex.RLock()
+			matched := make([]*models.Trade, 0)
+			for trade := range me.tape.trades.all() {
+				if trade.Symbol == "RARE" {
+					matched = append(matched, trade)
+				}
+			}
+			me.mutex.RUnlock()
+			if len(matched) > 50 {
+				matched = matched[len(matched)-50:]
+			}
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package matching
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// DefaultTradeBuffer is how many trades a trade subscription holds for its
+// reader before it is judged too slow
+const DefaultTradeBuffer = 256
+
+// TradeSubscription delivers each trade in one symbol as the public tape
+// reports it: as it executes, in execution order, except that hidden-order
+// trades held back by SetHiddenPrintDelay arrive when they are released.
+// Matching never waits on a subscriber: one that lets its buffer fill is
+// disconnected, its channel closed and Lagged set, rather than being sent a
+// feed with gaps in it.
+type TradeSubscription struct {
+	C <-chan *models.Trade
+
+	symbol string
+	engine *MatchingEngine
+	trades chan *models.Trade
+	lagged bool
+	closed bool // Guarded by the engine mutex
+	once   sync.Once
+}
+
+// SubscribeTrades starts delivering a symbol's trades on the returned
+// subscription's channel, buffering up to buffer of them, or
+// DefaultTradeBuffer if buffer is not positive. Call Close when done.
+func (me *MatchingEngine) SubscribeTrades(symbol string, buffer int) *TradeSubscription {
+	symbol = me.NormalizeSymbol(symbol)
+	if buffer <= 0 {
+		buffer = DefaultTradeBuffer
+	}
+
+	trades := make(chan *models.Trade, buffer)
+	sub := &TradeSubscription{
+		C:      trades,
+		symbol: symbol,
+		engine: me,
+		trades: trades,
+	}
+
+	me.mutex.Lock()
+	me.tradeSubs[symbol] = append(me.tradeSubs[symbol], sub)
+	me.mutex.Unlock()
+
+	return sub
+}
+
+// Close stops the subscription and closes its channel
+func (sub *TradeSubscription) Close() {
+	sub.once.Do(func() {
+		me := sub.engine
+		me.mutex.Lock()
+		defer me.mutex.Unlock()
+
+		me.tradeSubs[sub.symbol] = slices.DeleteFunc(me.tradeSubs[sub.symbol], func(s *TradeSubscription) bool {
+			return s == sub
+		})
+		sub.shut()
+	})
+}
+
+// Lagged reports whether the subscription was disconnected for falling
+// behind. It is only meaningful once C has been closed.
+func (sub *TradeSubscription) Lagged() bool {
+	me := sub.engine
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	return sub.lagged
+}
+
+// shut closes the channel once. The engine mutex must be held.
+func (sub *TradeSubscription) shut() {
+	if !sub.closed {
+		sub.closed = true
+		close(sub.trades)
+	}
+}
+
+// publishTrades hands trades just published on the tape to their symbols'
+// subscribers, dropping any subscriber without room for them all. It must
+// be called with the mutex held, as the tape publishes them, so every
+// subscriber sees them in the tape's order.
+func (me *MatchingEngine) publishTrades(trades []*models.Trade) {
+	if len(me.tradeSubs) == 0 {
+		return
+	}
+
+	bySymbol := make(map[string][]*models.Trade)
+	order := make([]string, 0)
+	for _, trade := range trades {
+		if _, seen := bySymbol[trade.Symbol]; !seen {
+			order = append(order, trade.Symbol)
+		}
+		bySymbol[trade.Symbol] = append(bySymbol[trade.Symbol], trade)
+	}
+	for _, symbol := range order {
+		me.publishSymbolTrades(symbol, bySymbol[symbol])
+	}
+}
+
+// publishSymbolTrades hands one symbol's trades to its subscribers. The
+// caller must hold the mutex.
+func (me *MatchingEngine) publishSymbolTrades(symbol string, trades []*models.Trade) {
+	subs := me.tradeSubs[symbol]
+	if len(subs) == 0 {
+		return
+	}
+
+	kept := subs[:0]
+	for _, sub := range subs {
+		if cap(sub.trades)-len(sub.trades) < len(trades) {
+			sub.lagged = true
+			sub.shut()
+			continue
+		}
+		for _, trade := range trades {
+			sub.trades <- trade
+		}
+		kept = append(kept, sub)
+	}
+	for i := len(kept); i < len(subs); i++ {
+		subs[i] = nil
+	}
+	me.tradeSubs[symbol] = kept
+}
@@ -0,0 +1,118 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestSubscribeTradesInExecutionOrder(t *testing.T) {
+	me := NewMatchingEngine()
+	sub := me.SubscribeTrades("aapl", 0)
+	defer sub.Close()
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 100.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 101.0))
+	me.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeLimit, models.OrderSideSell, 5, 50.0))
+	me.SubmitOrder(models.NewOrder("MSFT", models.OrderTypeMarket, models.OrderSideBuy, 5, 0))
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 8, 0))
+
+	for i, expected := range trades {
+		select {
+		case trade := <-sub.C:
+			if trade != expected {
+				t.Errorf("Expected trade %d at %g, got %g", i, expected.Price, trade.Price)
+			}
+		default:
+			t.Fatalf("Expected trade %d to be delivered", i)
+		}
+	}
+	select {
+	case trade := <-sub.C:
+		t.Errorf("Expected only AAPL trades, got one in %s", trade.Symbol)
+	default:
+	}
+}
+
+func TestSubscribeTradesDisconnectsLaggards(t *testing.T) {
+	me := NewMatchingEngine()
+	slow := me.SubscribeTrades("AAPL", 2)
+	fast := me.SubscribeTrades("AAPL", 10)
+	defer fast.Close()
+
+	for i := 0; i < 3; i++ {
+		printTrade(me, 100.0, 1)
+	}
+
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != 2 || !slow.Lagged() {
+		t.Errorf("Expected the slow subscriber cut off after 2 trades, got %d and lagged %v", received, slow.Lagged())
+	}
+	if len(fast.C) != 3 || fast.Lagged() {
+		t.Errorf("Expected the fast subscriber to keep all 3 trades, got %d", len(fast.C))
+	}
+	slow.Close()
+}
+
+func TestSubscribeTradesFollowsTape(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{OddLots: OddLotRule{RoundLot: 100, SuppressPrint: true}})
+	sub := me.SubscribeTrades("AAPL", 0)
+	defer sub.Close()
+
+	printTrade(me, 100.0, 10)
+	printTrade(me, 100.0, 100)
+	if len(sub.C) != 1 {
+		t.Fatalf("Expected only the round lot streamed, got %d trades", len(sub.C))
+	}
+	if trade := <-sub.C; trade.Quantity != 100 {
+		t.Errorf("Expected the 100 lot, got %g", trade.Quantity)
+	}
+}
+
+func TestSubscribeTradesReleasesDelayedHiddenPrints(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetHiddenPrintDelay(5 * time.Second)
+	sub := me.SubscribeTrades("AAPL", 0)
+	defer sub.Close()
+
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0)
+	hidden.Hidden = true
+	me.SubmitOrder(hidden)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0))
+	if len(sub.C) != 0 {
+		t.Fatal("Expected the hidden print held back from the stream")
+	}
+
+	mock.Advance(5 * time.Second)
+	if released := me.ReleaseHiddenPrints(); released != 1 {
+		t.Fatalf("Expected 1 print released, got %d", released)
+	}
+	if len(sub.C) != 1 {
+		t.Fatal("Expected the released print streamed")
+	}
+	if trade := <-sub.C; !trade.Hidden || trade.Quantity != 10 {
+		t.Errorf("Expected the hidden trade of 10, got %+v", trade)
+	}
+
+	// A due print is also released, ahead of them, when new trades execute
+	second := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 101.0)
+	second.Hidden = true
+	me.SubmitOrder(second)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 5, 0))
+	mock.Advance(5 * time.Second)
+	printTrade(me, 102.0, 1)
+	if len(sub.C) != 2 {
+		t.Fatalf("Expected the released print and the new one, got %d", len(sub.C))
+	}
+	if first := <-sub.C; first.Price != 101.0 {
+		t.Errorf("Expected the released print first, got %g", first.Price)
+	}
+}
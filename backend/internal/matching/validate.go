@@ -0,0 +1,90 @@
+package matching
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Validate checks the engine and symbol configuration for settings that are
+// invalid or contradict each other, returning every problem found joined
+// into one error, or nil. It is meant to be called once configuration is
+// loaded and before orders are accepted.
+func (me *MatchingEngine) Validate() error {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	errs := make([]error, 0)
+	invalid := func(value float64) bool {
+		return math.IsNaN(value) || math.IsInf(value, 0) || value < 0
+	}
+
+	if invalid(me.sanity.MaxQuantity) || invalid(me.sanity.MaxPrice) {
+		errs = append(errs, fmt.Errorf("sanity limits must be non-negative finite numbers, got quantity %g and price %g", me.sanity.MaxQuantity, me.sanity.MaxPrice))
+	}
+	if err := me.fees.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("fee schedule: %w", err))
+	}
+
+	for i, tier := range me.circuitBreaker.Tiers {
+		if invalid(tier.MinPrice) || invalid(tier.MaxPrice) {
+			errs = append(errs, fmt.Errorf("price band tier %d has a negative or non-finite bound", i))
+		}
+		if tier.MaxPrice != 0 && tier.MaxPrice <= tier.MinPrice {
+			errs = append(errs, fmt.Errorf("price band tier %d has lower bound %g not below upper bound %g", i, tier.MinPrice, tier.MaxPrice))
+		}
+		if invalid(tier.BandPercent) || tier.BandPercent == 0 {
+			errs = append(errs, fmt.Errorf("price band tier %d must allow a positive move, got %g", i, tier.BandPercent))
+		}
+	}
+
+	if me.auctionConfig.Duration < 0 {
+		errs = append(errs, fmt.Errorf("volatility auction duration cannot be negative, got %s", me.auctionConfig.Duration))
+	}
+	if invalid(me.auctionConfig.MaxImbalance) || me.auctionConfig.MaxImbalance > 1 {
+		errs = append(errs, fmt.Errorf("volatility auction imbalance must be between 0 and 1, got %g", me.auctionConfig.MaxImbalance))
+	}
+	if me.loadShedding.MaxOrders < 0 || invalid(me.loadShedding.MaxDistance) {
+		errs = append(errs, fmt.Errorf("load shedding thresholds cannot be negative, got %d orders and distance %g", me.loadShedding.MaxOrders, me.loadShedding.MaxDistance))
+	}
+
+	symbols := make([]string, 0, len(me.symbolConfigs))
+	for symbol := range me.symbolConfigs {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		config := me.symbolConfigs[symbol]
+		if invalid(config.TickSize) {
+			errs = append(errs, fmt.Errorf("%s tick size must be positive when set, got %g", symbol, config.TickSize))
+		}
+		if invalid(config.MaxPrice) {
+			errs = append(errs, fmt.Errorf("%s max price cannot be negative, got %g", symbol, config.MaxPrice))
+		}
+		if config.MaxPrice > 0 && config.TickSize > 0 && config.MaxPrice < config.TickSize {
+			errs = append(errs, fmt.Errorf("%s max price %g is below its tick size %g", symbol, config.MaxPrice, config.TickSize))
+		}
+		if config.MaxPrice > 0 && me.sanity.MaxPrice > 0 && config.MaxPrice > me.sanity.MaxPrice {
+			errs = append(errs, fmt.Errorf("%s max price %g exceeds the sanity cap of %g", symbol, config.MaxPrice, me.sanity.MaxPrice))
+		}
+		if invalid(config.MaxSlippage) || invalid(config.MinSpread) {
+			errs = append(errs, fmt.Errorf("%s slippage and spread limits cannot be negative, got %g and %g", symbol, config.MaxSlippage, config.MinSpread))
+		}
+		if invalid(config.MaxSweep) {
+			errs = append(errs, fmt.Errorf("%s sweep cap cannot be negative, got %g", symbol, config.MaxSweep))
+		}
+		if invalid(config.OddLots.RoundLot) {
+			errs = append(errs, fmt.Errorf("%s round lot cannot be negative, got %g", symbol, config.OddLots.RoundLot))
+		}
+		if config.OddLots.RoundLot > 0 && me.sanity.MaxQuantity > 0 && config.OddLots.RoundLot > me.sanity.MaxQuantity {
+			errs = append(errs, fmt.Errorf("%s round lot %g exceeds the largest order quantity of %g", symbol, config.OddLots.RoundLot, me.sanity.MaxQuantity))
+		}
+		if config.RoundPrices && config.TickSize <= 0 {
+			errs = append(errs, fmt.Errorf("%s rounds prices for display but has no tick size", symbol))
+		}
+	}
+
+	return errors.Join(errs...)
+}
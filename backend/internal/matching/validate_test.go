@@ -0,0 +1,112 @@
+package matching
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateAcceptsDefaults(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: 0.01, MaxPrice: 1000, RoundPrices: true})
+	me.SetCircuitBreaker(CircuitBreakerConfig{Tiers: []PriceBandTier{
+		{MinPrice: 0, MaxPrice: 10, BandPercent: 0.1},
+		{MinPrice: 10, BandPercent: 0.05},
+	}})
+	if err := me.Validate(); err != nil {
+		t.Errorf("Expected a consistent config to validate, got %v", err)
+	}
+}
+
+func TestValidateReportsInconsistentConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(me *MatchingEngine)
+		expected  string
+	}{
+		{
+			name: "negative tick",
+			configure: func(me *MatchingEngine) {
+				me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: -0.01})
+			},
+			expected: "AAPL tick size must be positive when set",
+		},
+		{
+			name: "inverted band",
+			configure: func(me *MatchingEngine) {
+				me.SetCircuitBreaker(CircuitBreakerConfig{Tiers: []PriceBandTier{{MinPrice: 50, MaxPrice: 10, BandPercent: 0.1}}})
+			},
+			expected: "price band tier 0 has lower bound 50 not below upper bound 10",
+		},
+		{
+			name: "zero band",
+			configure: func(me *MatchingEngine) {
+				me.SetCircuitBreaker(CircuitBreakerConfig{Tiers: []PriceBandTier{{}}})
+			},
+			expected: "price band tier 0 must allow a positive move",
+		},
+		{
+			name: "excess rebate",
+			configure: func(me *MatchingEngine) {
+				// Bypass SetFeeSchedule's own check, as a loaded config might
+				me.fees = FeeSchedule{MakerRate: -0.001, TakerRate: 0.0005}
+			},
+			expected: "fee schedule: maker rate -0.001 and taker rate 0.0005 pay out a net",
+		},
+		{
+			name: "round lot above max quantity",
+			configure: func(me *MatchingEngine) {
+				me.SetSanityLimits(SanityLimits{MaxQuantity: 50})
+				me.SetSymbolConfig("AAPL", SymbolConfig{OddLots: OddLotRule{RoundLot: 100}})
+			},
+			expected: "AAPL round lot 100 exceeds the largest order quantity of 50",
+		},
+		{
+			name: "symbol cap above sanity cap",
+			configure: func(me *MatchingEngine) {
+				me.SetSanityLimits(SanityLimits{MaxPrice: 100})
+				me.SetSymbolConfig("AAPL", SymbolConfig{MaxPrice: 500})
+			},
+			expected: "AAPL max price 500 exceeds the sanity cap of 100",
+		},
+		{
+			name: "rounding without a tick",
+			configure: func(me *MatchingEngine) {
+				me.SetSymbolConfig("AAPL", SymbolConfig{RoundPrices: true})
+			},
+			expected: "AAPL rounds prices for display but has no tick size",
+		},
+		{
+			name: "negative auction",
+			configure: func(me *MatchingEngine) {
+				me.SetVolatilityAuctions(VolatilityAuctionConfig{Duration: -time.Second})
+			},
+			expected: "volatility auction duration cannot be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		me := NewMatchingEngine()
+		tt.configure(me)
+
+		err := me.Validate()
+		if err == nil {
+			t.Errorf("%s: expected a validation error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSymbolConfig("AAPL", SymbolConfig{TickSize: -1})
+	me.SetSymbolConfig("MSFT", SymbolConfig{MaxSlippage: -0.5})
+
+	err := me.Validate()
+	if err == nil || !strings.Contains(err.Error(), "AAPL") || !strings.Contains(err.Error(), "MSFT") {
+		t.Errorf("Expected problems with both symbols, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package matching
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// volatilityYear is the period realized volatility is annualized to. Trading
+// runs around the clock, so it is a calendar year.
+const volatilityYear = 365 * 24 * time.Hour
+
+// minVolatilityTrades is the fewest trades realized volatility is computed
+// from, giving at least two returns
+const minVolatilityTrades = 3
+
+// RealizedVolatility returns the annualized realized volatility of a symbol's
+// trade prices over the window ending now. The squared log returns between
+// consecutive trades are summed and scaled from the time the trades span up
+// to a year, so irregularly spaced trades are weighted by the time they
+// cover. It returns an error if the window holds too few trades. Only held
+// trades count, so a window longer than the trade history is cut short.
+func (me *MatchingEngine) RealizedVolatility(symbol string, window time.Duration) (float64, error) {
+	if window <= 0 {
+		return 0, fmt.Errorf("volatility window must be positive, got %s", window)
+	}
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	since := me.clock.Now().Add(-window)
+	var first, last time.Time
+	var previous, sumSquares float64
+	count := 0
+	for _, trade := range me.trades.symbol(symbol) {
+		if trade.Timestamp.Before(since) || trade.Price <= 0 {
+			continue
+		}
+		if count == 0 {
+			first = trade.Timestamp
+		} else {
+			r := math.Log(trade.Price / previous)
+			sumSquares += r * r
+		}
+		previous, last = trade.Price, trade.Timestamp
+		count++
+	}
+
+	if count < minVolatilityTrades {
+		return 0, fmt.Errorf("%d trades in %s for %s, need at least %d", count, window, symbol, minVolatilityTrades)
+	}
+	span := last.Sub(first)
+	if span <= 0 {
+		return 0, fmt.Errorf("trades for %s in %s all share one timestamp", symbol, window)
+	}
+	return math.Sqrt(sumSquares * float64(volatilityYear) / float64(span)), nil
+}
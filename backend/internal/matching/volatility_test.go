@@ -0,0 +1,68 @@
+package matching
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestRealizedVolatility(t *testing.T) {
+	mock := clock.NewMock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+
+	trade := func(price float64) {
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, price))
+		me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 1, 0))
+		mock.Advance(time.Minute)
+	}
+
+	// An old trade outside the window is ignored
+	trade(50.0)
+	mock.Advance(time.Hour)
+
+	prices := []float64{100, 101, 99, 102, 100}
+	for _, price := range prices {
+		trade(price)
+	}
+
+	// Four one-minute returns over a four-minute span
+	sumSquares := 0.0
+	for i := 1; i < len(prices); i++ {
+		r := math.Log(prices[i] / prices[i-1])
+		sumSquares += r * r
+	}
+	minutesPerYear := 365.0 * 24 * 60
+	expected := math.Sqrt(sumSquares / 4 * minutesPerYear)
+
+	vol, err := me.RealizedVolatility("AAPL", 30*time.Minute)
+	if err != nil {
+		t.Fatalf("Expected volatility, got error %v", err)
+	}
+	if math.Abs(vol-expected) > 1e-9*expected {
+		t.Errorf("Expected annualized volatility %g, got %g", expected, vol)
+	}
+	// About 2% per minute annualizes to around 15
+	if vol < 15 || vol > 16 {
+		t.Errorf("Expected volatility near 15, got %g", vol)
+	}
+}
+
+func TestRealizedVolatilityNeedsTrades(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 100.0))
+	me.SubmitOrder(me.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 1, 0))
+
+	if _, err := me.RealizedVolatility("AAPL", time.Hour); err == nil {
+		t.Error("Expected an error with a single trade")
+	}
+	if _, err := me.RealizedVolatility("MSFT", time.Hour); err == nil {
+		t.Error("Expected an error for a symbol with no trades")
+	}
+	if _, err := me.RealizedVolatility("AAPL", 0); err == nil {
+		t.Error("Expected an error for an empty window")
+	}
+}
@@ -0,0 +1,75 @@
+package matching
+
+import (
+	"time"
+)
+
+// VolumeBucket is the trading activity in one time bucket
+type VolumeBucket struct {
+	Start  time.Time `json:"start"`
+	Count  int       `json:"count"`
+	Volume float64   `json:"volume"`
+}
+
+// VolumeProfile returns trade count and volume for a symbol in consecutive
+// buckets of the given width covering [from, to), oldest first. Buckets with
+// no trades are included so the series has no gaps. Only trades on the
+// public tape count, so delayed hidden prints and suppressed odd lots stay
+// out. Buckets before the oldest trade the tape keeps read empty.
+func (me *MatchingEngine) VolumeProfile(symbol string, bucket time.Duration, from, to time.Time) []VolumeBucket {
+	symbol = me.NormalizeSymbol(symbol)
+	if bucket <= 0 || !from.Before(to) {
+		return []VolumeBucket{}
+	}
+
+	count := int((to.Sub(from) + bucket - 1) / bucket)
+	buckets := make([]VolumeBucket, count)
+	for i := range buckets {
+		buckets[i].Start = from.Add(time.Duration(i) * bucket)
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.publishTrades(me.tape.release(me.clock.Now()))
+	for _, trade := range me.tape.trades.symbol(symbol) {
+		if trade.Timestamp.Before(from) || !trade.Timestamp.Before(to) {
+			continue
+		}
+		i := int(trade.Timestamp.Sub(from) / bucket)
+		buckets[i].Count++
+		buckets[i].Volume += trade.Quantity
+	}
+	return buckets
+}
+
+// VolumeAtPrice returns the volume a symbol traded at each execution price
+// in [from, to), as reported on the public tape
+func (me *MatchingEngine) VolumeAtPrice(symbol string, from, to time.Time) map[float64]float64 {
+	symbol = me.NormalizeSymbol(symbol)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	me.publishTrades(me.tape.release(me.clock.Now()))
+	volumes := make(map[float64]float64)
+	for _, trade := range me.tape.trades.symbol(symbol) {
+		if trade.Timestamp.Before(from) || !trade.Timestamp.Before(to) {
+			continue
+		}
+		volumes[trade.Price] += trade.Quantity
+	}
+	return volumes
+}
+
+// PointOfControl returns the price with the most traded volume, preferring
+// the lower price on a tie, or 0 if nothing traded
+func PointOfControl(volumes map[float64]float64) float64 {
+	poc, most := 0.0, 0.0
+	for price, volume := range volumes {
+		if volume > most || (volume == most && price < poc) {
+			poc, most = price, volume
+		}
+	}
+	return poc
+}
@@ -0,0 +1,141 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/clock"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestVolumeProfile(t *testing.T) {
+	start := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	mock := clock.NewMock(start)
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+
+	trade := func(symbol string, quantity float64) {
+		me.SubmitOrder(models.NewOrder(symbol, models.OrderTypeLimit, models.OrderSideSell, quantity, 100.0))
+		me.SubmitOrder(models.NewOrder(symbol, models.OrderTypeLimit, models.OrderSideBuy, quantity, 100.0))
+	}
+
+	// Two trades in the first minute, none in the second, one in the third
+	trade("AAPL", 10)
+	mock.Advance(30 * time.Second)
+	trade("AAPL", 15)
+	trade("MSFT", 99)
+	mock.Advance(90 * time.Second)
+	trade("AAPL", 5)
+
+	buckets := me.VolumeProfile("AAPL", time.Minute, start, start.Add(3*time.Minute))
+
+	expected := []VolumeBucket{
+		{Start: start, Count: 2, Volume: 25},
+		{Start: start.Add(time.Minute), Count: 0, Volume: 0},
+		{Start: start.Add(2 * time.Minute), Count: 1, Volume: 5},
+	}
+	if len(buckets) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %d", len(expected), len(buckets))
+	}
+	for i, bucket := range buckets {
+		if !bucket.Start.Equal(expected[i].Start) || bucket.Count != expected[i].Count || bucket.Volume != expected[i].Volume {
+			t.Errorf("Bucket %d: expected %+v, got %+v", i, expected[i], bucket)
+		}
+	}
+}
+
+func TestVolumeAtPrice(t *testing.T) {
+	start := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	me := NewMatchingEngine()
+	me.SetClock(clock.NewMock(start))
+
+	trade := func(quantity, price float64) {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, quantity, price))
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, quantity, price))
+	}
+
+	trade(10, 150.0)
+	trade(30, 151.0)
+	trade(15, 150.0)
+
+	volumes := me.VolumeAtPrice("AAPL", start, start.Add(time.Minute))
+
+	if len(volumes) != 2 {
+		t.Fatalf("Expected 2 prices, got %d", len(volumes))
+	}
+	if volumes[150.0] != 25 {
+		t.Errorf("Expected 25 traded at 150, got %v", volumes[150.0])
+	}
+	if volumes[151.0] != 30 {
+		t.Errorf("Expected 30 traded at 151, got %v", volumes[151.0])
+	}
+	if poc := PointOfControl(volumes); poc != 151.0 {
+		t.Errorf("Expected point of control 151, got %v", poc)
+	}
+
+	if poc := PointOfControl(map[float64]float64{}); poc != 0 {
+		t.Errorf("Expected point of control 0 with no trades, got %v", poc)
+	}
+}
+
+func TestVolumeProfileCountsPublicTradesOnly(t *testing.T) {
+	start := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	mock := clock.NewMock(start)
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetHiddenPrintDelay(time.Minute)
+	me.SetSymbolConfig("AAPL", SymbolConfig{OddLots: OddLotRule{RoundLot: 100, SuppressPrint: true}})
+
+	// A round lot, an odd lot the tape suppresses, and a hidden round lot
+	// the tape holds back for a minute
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 151.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 50, 151.0))
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 200, 152.0)
+	hidden.Hidden = true
+	me.SubmitOrder(hidden)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 200, 152.0))
+
+	end := start.Add(time.Hour)
+	if buckets := me.VolumeProfile("AAPL", time.Hour, start, end); buckets[0].Count != 1 || buckets[0].Volume != 100 {
+		t.Errorf("Expected one public trade of 100, got %+v", buckets[0])
+	}
+
+	// Once the hidden print is published it counts
+	mock.Advance(time.Minute)
+	if buckets := me.VolumeProfile("AAPL", time.Hour, start, end); buckets[0].Count != 2 || buckets[0].Volume != 300 {
+		t.Errorf("Expected the released hidden print counted, got %+v", buckets[0])
+	}
+}
+
+func TestVolumeAtPriceCountsPublicTradesOnly(t *testing.T) {
+	start := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	mock := clock.NewMock(start)
+
+	me := NewMatchingEngine()
+	me.SetClock(mock)
+	me.SetHiddenPrintDelay(time.Minute)
+	me.SetSymbolConfig("AAPL", SymbolConfig{OddLots: OddLotRule{RoundLot: 100, SuppressPrint: true}})
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 151.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 50, 151.0))
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 200, 152.0)
+	hidden.Hidden = true
+	me.SubmitOrder(hidden)
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 200, 152.0))
+
+	end := start.Add(time.Hour)
+	if volumes := me.VolumeAtPrice("AAPL", start, end); len(volumes) != 1 || volumes[150.0] != 100 {
+		t.Errorf("Expected only the round lot at 150, got %v", volumes)
+	}
+
+	mock.Advance(time.Minute)
+	if volumes := me.VolumeAtPrice("AAPL", start, end); volumes[152.0] != 200 || volumes[151.0] != 0 {
+		t.Errorf("Expected the released hidden print at 152 and no odd lot, got %v", volumes)
+	}
+}
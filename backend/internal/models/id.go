@@ -0,0 +1,16 @@
+package models
+
+import "github.com/google/uuid"
+
+// IDGenerator produces IDs for orders and trades
+type IDGenerator interface {
+	NewID() uuid.UUID
+}
+
+// UUIDGenerator generates random UUIDs
+type UUIDGenerator struct{}
+
+// NewID returns a new random UUID
+func (UUIDGenerator) NewID() uuid.UUID {
+	return uuid.New()
+}
@@ -10,9 +10,10 @@ import (
 type OrderType string
 
 const (
-	OrderTypeMarket   OrderType = "market"
-	OrderTypeLimit    OrderType = "limit"
-	OrderTypeStopLoss OrderType = "stop_loss"
+	OrderTypeMarket    OrderType = "market"
+	OrderTypeLimit     OrderType = "limit"
+	OrderTypeStopLoss  OrderType = "stop_loss"
+	OrderTypeStopLimit OrderType = "stop_limit"
 )
 
 // OrderSide represents buy or sell
@@ -23,6 +24,22 @@ const (
 	OrderSideSell OrderSide = "sell"
 )
 
+// PegType is the reference price a pegged order follows
+type PegType string
+
+const (
+	PegMidpoint PegType = "midpoint" // Midway between the best bid and offer
+	PegPrimary  PegType = "primary"  // The best price on the order's own side
+)
+
+// TimeInForce is how long an order's unfilled remainder stays working
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "GTC" // Rests until filled or cancelled
+	TimeInForceIOC TimeInForce = "IOC" // Fills what it can on arrival, the rest is cancelled
+)
+
 // OrderStatus represents the current status of an order
 type OrderStatus string
 
@@ -31,22 +48,40 @@ const (
 	OrderStatusPartial   OrderStatus = "partial"
 	OrderStatusFilled    OrderStatus = "filled"
 	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusRejected  OrderStatus = "rejected"
 )
 
 // Order represents a trading order
 type Order struct {
-	ID             uuid.UUID   `json:"id"`
-	Symbol         string      `json:"symbol"`
-	Type           OrderType   `json:"type"`
-	Side           OrderSide   `json:"side"`
-	Quantity       float64     `json:"quantity"`
-	Price          float64     `json:"price"` // 0 for market orders
-	Status         OrderStatus `json:"status"`
-	FilledQuantity float64     `json:"filled_quantity"`
-	FilledPrice    float64     `json:"filled_price"`
-	SubmittedAt    time.Time   `json:"submitted_at"`
-	FilledAt       *time.Time  `json:"filled_at,omitempty"`
-	CancelledAt    *time.Time  `json:"cancelled_at,omitempty"`
+	ID                uuid.UUID   `json:"id"`
+	Ref               string      `json:"ref,omitempty"` // Human-readable sequential reference
+	ClientOrderID     string      `json:"client_order_id,omitempty"`
+	AccountID         string      `json:"account_id,omitempty"`
+	STPGroup          string      `json:"stp_group,omitempty"` // Accounts sharing a group never trade with each other
+	Symbol            string      `json:"symbol"`
+	Type              OrderType   `json:"type"`
+	Side              OrderSide   `json:"side"`
+	Quantity          float64     `json:"quantity"`
+	Price             float64     `json:"price"`                       // 0 for market orders
+	StopPrice         float64     `json:"stop_price,omitempty"`        // Trigger for stop_limit orders, whose limit is Price
+	Currency          string      `json:"currency,omitempty"`          // Price currency if not the symbol's own
+	MinFillQuantity   float64     `json:"min_fill_quantity,omitempty"` // Cancel unless at least this much can execute immediately
+	Hidden            bool        `json:"hidden,omitempty"`            // Rests without being displayed in the book
+	PostOnly          bool        `json:"post_only,omitempty"`         // Rejected rather than taking liquidity
+	ConfirmRest       bool        `json:"confirm_rest,omitempty"`      // Accepts a marketable limit resting what it can't fill at once
+	TimeInForce       TimeInForce `json:"time_in_force,omitempty"`     // GTC if empty
+	Peg               PegType     `json:"peg,omitempty"`               // Re-priced by the engine as the BBO moves
+	PegOffset         float64     `json:"peg_offset,omitempty"`        // Improvement on the peg: added for buys, subtracted for sells
+	Status            OrderStatus `json:"status"`
+	FilledQuantity    float64     `json:"filled_quantity"`
+	FilledPrice       float64     `json:"filled_price"`
+	SubmittedAt       time.Time   `json:"submitted_at"`
+	FilledAt          *time.Time  `json:"filled_at,omitempty"`
+	CancelledAt       *time.Time  `json:"cancelled_at,omitempty"`
+	RejectReason      string      `json:"reject_reason,omitempty"`
+	CancelReason      string      `json:"cancel_reason,omitempty"`
+	CancelledQuantity float64     `json:"cancelled_quantity,omitempty"` // Unfilled remainder at cancellation
+	Warnings          []string    `json:"-"`                            // Non-fatal conditions met while matching
 }
 
 // NewOrder creates a new order
@@ -65,29 +100,93 @@ func NewOrder(symbol string, orderType OrderType, side OrderSide, quantity, pric
 	}
 }
 
-// RemainingQuantity returns the unfilled quantity
-func (o *Order) RemainingQuantity() float64 {
-	return o.Quantity - o.FilledQuantity
+// RemainingQuantity returns the unfilled quantity, treating a residue
+// within epsilon as nothing left
+func (o *Order) RemainingQuantity(epsilon float64) float64 {
+	remaining := o.Quantity - o.FilledQuantity
+	if remaining <= epsilon {
+		return 0
+	}
+	return remaining
 }
 
-// IsFilled returns true if the order is completely filled
-func (o *Order) IsFilled() bool {
-	return o.FilledQuantity >= o.Quantity
+// IsFilled returns true if the order is filled to within epsilon
+func (o *Order) IsFilled(epsilon float64) bool {
+	return o.RemainingQuantity(epsilon) == 0
 }
 
-// Fill partially or fully fills the order
-func (o *Order) Fill(quantity, price float64) {
+// Fill partially or fully fills the order, counting it filled once what
+// is left is within epsilon
+func (o *Order) Fill(quantity, price float64, at time.Time, epsilon float64) {
 	o.FilledQuantity += quantity
 	// Update filled price as weighted average
 	if o.FilledQuantity > 0 {
 		o.FilledPrice = ((o.FilledPrice * (o.FilledQuantity - quantity)) + (price * quantity)) / o.FilledQuantity
 	}
 
-	if o.IsFilled() {
+	if o.IsFilled(epsilon) {
 		o.Status = OrderStatusFilled
-		now := time.Now()
-		o.FilledAt = &now
+		o.FilledAt = &at
 	} else if o.FilledQuantity > 0 {
 		o.Status = OrderStatusPartial
 	}
 }
+
+// FillState returns the order's cumulative fill state as it stands, with
+// a leaves quantity within epsilon reported as none
+func (o *Order) FillState(epsilon float64) FillState {
+	state := FillState{OrderID: o.ID, Status: o.Status, CumQty: o.FilledQuantity, AvgPx: o.FilledPrice}
+	if o.IsActive() {
+		state.LeavesQty = o.RemainingQuantity(epsilon)
+	}
+	return state
+}
+
+// Rescale multiplies the order's prices by priceFactor and its quantities by
+// qtyFactor, restating it after a split or other corporate action
+func (o *Order) Rescale(priceFactor, qtyFactor float64) {
+	o.Price *= priceFactor
+	o.StopPrice *= priceFactor
+	o.PegOffset *= priceFactor
+	o.FilledPrice *= priceFactor
+	o.Quantity *= qtyFactor
+	o.FilledQuantity *= qtyFactor
+	o.MinFillQuantity *= qtyFactor
+	o.CancelledQuantity *= qtyFactor
+}
+
+// IsActive returns true if the order can still trade
+func (o *Order) IsActive() bool {
+	return o.Status == OrderStatusPending || o.Status == OrderStatusPartial
+}
+
+// IsImmediate returns true if the order must trade on arrival or not at
+// all: immediate-or-cancel, or with a minimum fill to meet at once
+func (o *Order) IsImmediate() bool {
+	return o.TimeInForce == TimeInForceIOC || o.MinFillQuantity > 0
+}
+
+// Cancel marks the order as cancelled at the given time
+func (o *Order) Cancel(at time.Time) {
+	o.Status = OrderStatusCancelled
+	o.CancelledAt = &at
+}
+
+// CancelRemainder cancels whatever an order has left to fill, recording how
+// much that was and why. Any quantity already filled stands.
+func (o *Order) CancelRemainder(at time.Time, reason string) {
+	o.CancelledQuantity = max(o.Quantity-o.FilledQuantity, 0)
+	o.CancelReason = reason
+	o.Cancel(at)
+}
+
+// Reject marks the order as rejected before it reached the book
+func (o *Order) Reject(reason string) {
+	o.Status = OrderStatusRejected
+	o.RejectReason = reason
+}
+
+// Warn records a condition worth reporting that did not stop the order
+func (o *Order) Warn(warning string) {
+	o.Warnings = append(o.Warnings, warning)
+}
@@ -0,0 +1,39 @@
+package models
+
+import "math"
+
+// Default tolerances for float comparisons
+const (
+	DefaultQuantityEpsilon = 1e-9
+	DefaultPriceEpsilon    = 1e-9
+)
+
+// Tolerance is how far apart float quantities and prices may be and still be
+// treated as equal, so float residue never leaves a phantom partial fill or
+// splits one price into two levels. Books key their levels by the price
+// tolerance, so it is set when an engine or book is created.
+type Tolerance struct {
+	Quantity float64 `json:"quantity"` // Largest residue treated as nothing left
+	Price    float64 `json:"price"`    // Largest gap between prices treated as one price
+}
+
+// DefaultTolerance is the tolerance used unless another is configured
+var DefaultTolerance = Tolerance{Quantity: DefaultQuantityEpsilon, Price: DefaultPriceEpsilon}
+
+// OrDefault returns the tolerance with any margin that isn't positive
+// replaced by its default
+func (t Tolerance) OrDefault() Tolerance {
+	if t.Quantity <= 0 {
+		t.Quantity = DefaultQuantityEpsilon
+	}
+	if t.Price <= 0 {
+		t.Price = DefaultPriceEpsilon
+	}
+	return t
+}
+
+// PricesEqual reports whether two prices are equal within the price
+// tolerance
+func (t Tolerance) PricesEqual(a, b float64) bool {
+	return math.Abs(a-b) <= t.Price
+}
@@ -9,12 +9,54 @@ import (
 // Trade represents an executed trade between a buy and sell order
 type Trade struct {
 	ID          uuid.UUID `json:"id"`
+	Ref         string    `json:"ref,omitempty"` // Human-readable sequential reference
 	Symbol      string    `json:"symbol"`
 	BuyOrderID  uuid.UUID `json:"buy_order_id"`
 	SellOrderID uuid.UUID `json:"sell_order_id"`
 	Price       float64   `json:"price"`
 	Quantity    float64   `json:"quantity"`
 	Timestamp   time.Time `json:"timestamp"`
+	Hidden      bool      `json:"hidden,omitempty"`  // Either side was a hidden order
+	OddLot      bool      `json:"odd_lot,omitempty"` // Smaller than the symbol's round lot
+	TakerSide   OrderSide `json:"taker_side"`
+	MakerFee    float64   `json:"maker_fee"` // Negative for a rebate
+	TakerFee    float64   `json:"taker_fee"`
+
+	// Accounts are kept out of JSON so a trade never reveals its counterparty
+	BuyAccountID  string `json:"-"`
+	SellAccountID string `json:"-"`
+
+	// Each side's order as it stood just after this fill, for execution
+	// reports; private to the order's owner, so also kept out of JSON
+	BuyFill  FillState `json:"-"`
+	SellFill FillState `json:"-"`
+}
+
+// FillState is an order's cumulative state just after one of its fills
+type FillState struct {
+	OrderID   uuid.UUID
+	Status    OrderStatus
+	CumQty    float64
+	LeavesQty float64 // 0 once the order is filled or cancelled
+	AvgPx     float64
+}
+
+// RecordFills captures both orders' cumulative state after the trade
+// filled them, with leaves quantities within epsilon reported as none
+func (t *Trade) RecordFills(buy, sell *Order, epsilon float64) {
+	t.BuyFill, t.SellFill = buy.FillState(epsilon), sell.FillState(epsilon)
+}
+
+// FillFor returns the state an order was left in by the trade, or false if
+// none was recorded for it
+func (t *Trade) FillFor(orderID uuid.UUID) (FillState, bool) {
+	switch {
+	case t.BuyFill.OrderID == orderID && orderID != uuid.Nil:
+		return t.BuyFill, true
+	case t.SellFill.OrderID == orderID && orderID != uuid.Nil:
+		return t.SellFill, true
+	}
+	return FillState{}, false
 }
 
 // NewTrade creates a new trade
@@ -0,0 +1,19 @@
+package orderbook
+
+// Rescale multiplies every resting order's price by priceFactor and its
+// quantities by qtyFactor, along with the last price, for a split or other
+// corporate action. Levels are rebuilt in arrival sequence, so time priority
+// is unchanged. Like Compact, it must not run while an order is being
+// matched.
+func (ob *OrderBook) Rescale(priceFactor, qtyFactor float64) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	for _, order := range ob.orders {
+		order.Rescale(priceFactor, qtyFactor)
+	}
+	ob.LastPrice *= priceFactor
+
+	ob.rebuild()
+	ob.markChanged(ChangeAdjust)
+}
@@ -0,0 +1,149 @@
+package orderbook
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// AddOrders rests many orders at once, for rebuilding a book from an
+// external source. Orders are grouped into levels in bulk and each side is
+// heapified once, rather than searched and pushed per order as AddOrder
+// does. Orders at the same price queue in the order given, behind any
+// already resting there. It fails without changing the book if an order
+// belongs to another symbol, is already in the book or given twice, or if
+// the book would be left crossed.
+func (ob *OrderBook) AddOrders(orders []*models.Order) error {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	bids := make([]*models.Order, 0, len(orders))
+	asks := make([]*models.Order, 0, len(orders))
+	seen := make(map[uuid.UUID]bool, len(orders))
+	for _, order := range orders {
+		if order.Symbol != ob.Symbol {
+			return fmt.Errorf("order %s is for %s, not %s", order.ID, order.Symbol, ob.Symbol)
+		}
+		if _, exists := ob.orders[order.ID]; exists || seen[order.ID] {
+			return fmt.Errorf("order %s is already in the book", order.ID)
+		}
+		seen[order.ID] = true
+		if order.Side == models.OrderSideBuy {
+			bids = append(bids, order)
+		} else {
+			asks = append(asks, order)
+		}
+	}
+
+	bidLevels := bulkLevels(bids, ob.tolerance)
+	askLevels := bulkLevels(asks, ob.tolerance)
+
+	// Levels come back in ascending price, so the best new bid is last and
+	// the best new ask first
+	bestBid, bestAsk := 0.0, 0.0
+	if top := ob.Bids.Peek(); top != nil {
+		bestBid = top.Price
+	}
+	if top := ob.Asks.Peek(); top != nil {
+		bestAsk = top.Price
+	}
+	if len(bidLevels) > 0 {
+		bestBid = max(bestBid, bidLevels[len(bidLevels)-1].Price)
+	}
+	if len(askLevels) > 0 && (bestAsk == 0 || askLevels[0].Price < bestAsk) {
+		bestAsk = askLevels[0].Price
+	}
+	if bestBid > 0 && bestAsk > 0 && (bestBid > bestAsk || ob.tolerance.PricesEqual(bestBid, bestAsk)) {
+		return fmt.Errorf("importing would cross the book at bid %g and ask %g", bestBid, bestAsk)
+	}
+
+	for _, order := range append(bids, asks...) {
+		ob.orders[order.ID] = order
+		ob.sequence++
+		ob.sequences[order.ID] = ob.sequence
+	}
+	mergeLevels(ob.Bids, bidLevels)
+	mergeLevels(ob.Asks, askLevels)
+
+	if len(orders) > 0 {
+		ob.markChanged(ChangeAdd)
+	}
+	return nil
+}
+
+// SwapOrders takes orders out of the book and rests others in their place
+// under one lock, so nothing sees or trades against the book in between.
+// Orders no longer in the book are skipped. An added order that would lock
+// or cross the opposite side is left out and returned for the caller to
+// match. It returns the orders actually removed and those left out.
+func (ob *OrderBook) SwapOrders(remove, add []*models.Order) (removed, crossing []*models.Order) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	removed = make([]*models.Order, 0, len(remove))
+	for _, order := range remove {
+		if _, exists := ob.orders[order.ID]; exists && ob.removeOrder(order) {
+			removed = append(removed, order)
+		}
+	}
+
+	crossing = make([]*models.Order, 0)
+	for _, order := range add {
+		opposite := ob.Asks
+		if order.Side == models.OrderSideSell {
+			opposite = ob.Bids
+		}
+		if best := bestLive(opposite, nil); best > 0 {
+			if ob.tolerance.PricesEqual(order.Price, best) || (order.Side == models.OrderSideBuy) == (order.Price > best) {
+				crossing = append(crossing, order)
+				continue
+			}
+		}
+		ob.addOrder(order)
+	}
+
+	if len(removed) > 0 || len(crossing) < len(add) {
+		ob.markChanged(ChangeReplace)
+	}
+	return removed, crossing
+}
+
+// bulkLevels groups orders into price levels in ascending price, keeping the
+// given order within each level and joining prices equal within tolerance
+func bulkLevels(orders []*models.Order, tolerance models.Tolerance) []*PriceLevel {
+	sorted := make([]*models.Order, len(orders))
+	copy(sorted, orders)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Price < sorted[j].Price })
+
+	levels := make([]*PriceLevel, 0)
+	for _, order := range sorted {
+		if n := len(levels); n > 0 && tolerance.PricesEqual(levels[n-1].Price, order.Price) {
+			levels[n-1].Orders = append(levels[n-1].Orders, order)
+			continue
+		}
+		levels = append(levels, &PriceLevel{Price: order.Price, Orders: []*models.Order{order}, epsilon: tolerance.Quantity})
+	}
+	return levels
+}
+
+// mergeLevels adds levels to a heap, joining the queue of any existing level
+// at the same price, and restores the heap once
+func mergeLevels(h *PriceLevelHeap, levels []*PriceLevel) {
+	if len(levels) == 0 {
+		return
+	}
+
+	for _, level := range levels {
+		if existing := h.level(level.Price); existing != nil {
+			existing.Orders = append(existing.Orders, level.Orders...)
+			continue
+		}
+		level.pos = len(h.Levels)
+		h.Levels = append(h.Levels, level)
+		h.index(level)
+	}
+	heap.Init(h)
+}
@@ -0,0 +1,44 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestAddOrdersJoinsExistingLevels(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	resting := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0)
+	ob.AddOrder(resting)
+
+	joining := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 99.0)
+	err := ob.AddOrders([]*models.Order{
+		models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 98.0),
+		joining,
+		models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 101.0),
+		models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 100.0),
+	})
+	if err != nil {
+		t.Fatalf("Expected the orders to be added, got %v", err)
+	}
+
+	if ob.Bids.Len() != 2 || ob.GetBestBid() != 99.0 || ob.GetBestAsk() != 100.0 {
+		t.Errorf("Expected 2 bid levels with 99/100 on top, got %d at %g/%g", ob.Bids.Len(), ob.GetBestBid(), ob.GetBestAsk())
+	}
+	if level := ob.Bids.Peek(); len(level.Orders) != 2 || level.Orders[0] != resting || level.Orders[1] != joining {
+		t.Error("Expected the added 99 bid to queue behind the resting one")
+	}
+	if ob.OrderCount() != 5 {
+		t.Errorf("Expected 5 orders indexed, got %d", ob.OrderCount())
+	}
+
+	if err := ob.AddOrders([]*models.Order{joining}); err == nil {
+		t.Error("Expected an order already in the book to be rejected")
+	}
+	if err := ob.AddOrders([]*models.Order{models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 99.0)}); err == nil {
+		t.Error("Expected an ask at the best bid to be rejected as crossing")
+	}
+	if ob.OrderCount() != 5 {
+		t.Error("Expected rejected imports to leave the book unchanged")
+	}
+}
@@ -0,0 +1,107 @@
+package orderbook
+
+import "time"
+
+// ChangeReason is the kind of operation that last changed a book
+type ChangeReason string
+
+const (
+	ChangeAdd     ChangeReason = "add"
+	ChangeCancel  ChangeReason = "cancel"
+	ChangeTrade   ChangeReason = "trade"
+	ChangeAdjust  ChangeReason = "adjust"
+	ChangeReprice ChangeReason = "reprice"
+	ChangeReplace ChangeReason = "replace"
+)
+
+// MarkChanged records a change made to the book's orders from outside it,
+// such as fills during matching
+func (ob *OrderBook) MarkChanged(reason ChangeReason) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	ob.markChanged(reason)
+}
+
+// LastUpdated returns when the book last changed, or when it was created if
+// it never has
+func (ob *OrderBook) LastUpdated() time.Time {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	return ob.Timestamp
+}
+
+// maxChecksumHistory is how many sequence and checksum pairs a book keeps
+const maxChecksumHistory = 100
+
+// SeqChecksum is the checksum of a book's displayed levels just after the
+// change with the given sequence number
+type SeqChecksum struct {
+	Sequence uint64 `json:"sequence"`
+	Checksum uint32 `json:"checksum"`
+}
+
+// markChanged records a change to the book, once the change has been applied.
+// The caller must hold the mutex.
+func (ob *OrderBook) markChanged(reason ChangeReason) {
+	ob.change = reason
+	ob.changeSeq++
+	ob.Timestamp = ob.clock.Now()
+	ob.top.Store(nil)
+}
+
+// recordChecksum keeps the checksum of the book as of its latest change, if
+// it is not already kept. The caller must hold the mutex, for reading at
+// least.
+func (ob *OrderBook) recordChecksum(checksum uint32) {
+	ob.checksumLock.Lock()
+	defer ob.checksumLock.Unlock()
+
+	if ob.changeSeq == 0 {
+		return
+	}
+	if n := len(ob.checksums); n > 0 && ob.checksums[n-1].Sequence == ob.changeSeq {
+		return
+	}
+	if len(ob.checksums) >= maxChecksumHistory {
+		ob.checksums = ob.checksums[1:]
+	}
+	ob.checksums = append(ob.checksums, SeqChecksum{Sequence: ob.changeSeq, Checksum: checksum})
+}
+
+// displayedChecksum returns the checksum of the book's displayed levels. The
+// caller must hold the mutex, for reading at least.
+func (ob *OrderBook) displayedChecksum() uint32 {
+	now := ob.clock.Now()
+	return Checksum(&OrderBookSnapshot{
+		Bids: groupLevels(ob.Bids, 0, 0, now),
+		Asks: groupLevels(ob.Asks, 0, 0, now),
+	})
+}
+
+// RecentChecksums returns up to the last n sequence and checksum pairs,
+// oldest first, so a client whose local book stopped matching can find the
+// sequence it diverged at. Checksums are worked out lazily, when they or the
+// full state are read, rather than on every change, so the history holds
+// the sequences that were read and always ends at the latest. Only the last
+// 100 are kept.
+func (ob *OrderBook) RecentChecksums(n int) []SeqChecksum {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	if n <= 0 {
+		return []SeqChecksum{}
+	}
+	ob.recordChecksum(ob.displayedChecksum())
+
+	ob.checksumLock.Lock()
+	defer ob.checksumLock.Unlock()
+
+	if n > len(ob.checksums) {
+		n = len(ob.checksums)
+	}
+	history := make([]SeqChecksum, n)
+	copy(history, ob.checksums[len(ob.checksums)-n:])
+	return history
+}
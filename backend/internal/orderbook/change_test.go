@@ -0,0 +1,95 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestSnapshotLastChangeReason(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	if snapshot := ob.Snapshot(); snapshot.LastChangeReason != "" || snapshot.LastChangeSeq != 0 {
+		t.Errorf("Expected no change on a new book, got %q #%d", snapshot.LastChangeReason, snapshot.LastChangeSeq)
+	}
+
+	order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	ob.AddOrder(order)
+	if snapshot := ob.Snapshot(); snapshot.LastChangeReason != ChangeAdd || snapshot.LastChangeSeq != 1 {
+		t.Errorf("Expected add #1, got %q #%d", snapshot.LastChangeReason, snapshot.LastChangeSeq)
+	}
+
+	ob.MarkChanged(ChangeTrade)
+	if snapshot := ob.Depth(5, 0); snapshot.LastChangeReason != ChangeTrade || snapshot.LastChangeSeq != 2 {
+		t.Errorf("Expected trade #2, got %q #%d", snapshot.LastChangeReason, snapshot.LastChangeSeq)
+	}
+
+	ob.RemoveOrder(order.ID)
+	if state := ob.FullState(); state.Snapshot.LastChangeReason != ChangeCancel || state.Snapshot.LastChangeSeq != 3 {
+		t.Errorf("Expected cancel #3, got %q #%d", state.Snapshot.LastChangeReason, state.Snapshot.LastChangeSeq)
+	}
+
+	// A failed removal changes nothing
+	ob.RemoveOrder(order.ID)
+	if snapshot := ob.Snapshot(); snapshot.LastChangeSeq != 3 {
+		t.Errorf("Expected the sequence to stay at 3, got %d", snapshot.LastChangeSeq)
+	}
+}
+
+func TestRecentChecksums(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	bid := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	ask := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 151.0)
+	mutations := []func(){
+		func() { ob.AddOrder(bid) },
+		func() { ob.AddOrder(ask) },
+		func() { ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 25, 149.5)) },
+		func() {
+			ask.Fill(20, 151.0, ask.SubmittedAt, models.DefaultQuantityEpsilon)
+			ob.MarkChanged(ChangeTrade)
+		},
+		func() { ob.RemoveOrder(bid.ID) },
+	}
+
+	want := make([]SeqChecksum, 0, len(mutations))
+	for i, mutate := range mutations {
+		mutate()
+		want = append(want, SeqChecksum{Sequence: uint64(i + 1), Checksum: ob.FullState().Checksum})
+	}
+
+	history := ob.RecentChecksums(10)
+	if len(history) != len(want) {
+		t.Fatalf("Expected %d checksums, got %d", len(want), len(history))
+	}
+	for i := range want {
+		if history[i] != want[i] {
+			t.Errorf("Expected %+v at step %d, got %+v", want[i], i+1, history[i])
+		}
+	}
+
+	if last := ob.RecentChecksums(2); len(last) != 2 || last[0] != want[3] || last[1] != want[4] {
+		t.Errorf("Expected the last 2 checksums, got %+v", last)
+	}
+	if none := ob.RecentChecksums(0); len(none) != 0 {
+		t.Errorf("Expected no checksums for n=0, got %d", len(none))
+	}
+}
+
+func TestChecksumsAreWorkedOutWhenRead(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	for i := 0; i < 10; i++ {
+		ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0-float64(i)))
+	}
+
+	// Ten unread changes leave a single checksum, for the latest
+	history := ob.RecentChecksums(10)
+	if len(history) != 1 || history[0].Sequence != 10 || history[0].Checksum != ob.FullState().Checksum {
+		t.Fatalf("Expected one checksum at sequence 10, got %+v", history)
+	}
+
+	// Reading again without a change adds nothing
+	if again := ob.RecentChecksums(10); len(again) != 1 {
+		t.Errorf("Expected the checksum kept once, got %d", len(again))
+	}
+}
@@ -0,0 +1,48 @@
+package orderbook
+
+import (
+	"sort"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// Compact rebuilds both sides of the book from the order index, dropping
+// consumed, cancelled and empty entries left behind by lazy pruning. Orders
+// are re-added in arrival sequence, so price-time priority is unchanged.
+// Like matching, it must not run while an order is being matched.
+func (ob *OrderBook) Compact() {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	ob.rebuild()
+}
+
+// rebuild re-adds the book's live orders to fresh heaps in arrival sequence.
+// The caller must hold the mutex.
+func (ob *OrderBook) rebuild() {
+	live := make([]*models.Order, 0, len(ob.orders))
+	for id, order := range ob.orders {
+		if order.RemainingQuantity(ob.tolerance.Quantity) <= 0 || !order.IsActive() {
+			delete(ob.orders, id)
+			delete(ob.sequences, id)
+			continue
+		}
+		live = append(live, order)
+	}
+	sort.Slice(live, func(i, j int) bool {
+		return ob.sequences[live[i].ID] < ob.sequences[live[j].ID]
+	})
+
+	bids, asks := newHeap(true, ob.tolerance), newHeap(false, ob.tolerance)
+	for _, order := range live {
+		if order.Side == models.OrderSideBuy {
+			bids.AddOrder(order)
+		} else {
+			asks.AddOrder(order)
+		}
+	}
+	// Swap the levels in place so callers holding the heaps see the result
+	ob.Bids.adopt(bids)
+	ob.Asks.adopt(asks)
+	ob.top.Store(nil)
+}
@@ -0,0 +1,75 @@
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestCompactPreservesPriceTimePriority(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	// Churn the book so levels are repeatedly emptied, spliced and recreated
+	kept := make([]*models.Order, 0)
+	for cycle := 0; cycle < 50; cycle++ {
+		price := 100.0 + float64(cycle%7)
+		transient := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, price)
+		ob.AddOrder(transient)
+		if cycle%10 == 0 {
+			order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, float64(cycle+1), price)
+			ob.AddOrder(order)
+			kept = append(kept, order)
+		}
+		ob.RemoveOrder(transient.ID)
+	}
+	ask := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 110.0)
+	ob.AddOrder(ask)
+	kept = append(kept, ask)
+
+	// A consumed order left behind for lazy pruning
+	consumed := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 111.0)
+	ob.AddOrder(consumed)
+	consumed.Fill(10, 111.0, consumed.SubmittedAt, models.DefaultQuantityEpsilon)
+
+	before := ob.Depth(0, 0)
+	positions := make(map[*models.Order][2]float64)
+	for _, order := range kept {
+		rank, ahead, _ := ob.QueuePosition(order.ID)
+		positions[order] = [2]float64{float64(rank), ahead}
+	}
+
+	ob.Compact()
+
+	if errs := ob.Validate(); len(errs) != 0 {
+		t.Fatalf("Expected a valid book after compaction, got %v", errs)
+	}
+	if ob.OrderCount() != len(kept) {
+		t.Errorf("Expected %d orders after compaction, got %d", len(kept), ob.OrderCount())
+	}
+	if ob.Asks.Len() != 1 {
+		t.Errorf("Expected the consumed ask's level to be dropped, got %d ask levels", ob.Asks.Len())
+	}
+	for _, level := range append(ob.Bids.Levels, ob.Asks.Levels...) {
+		if len(level.Orders) == 0 {
+			t.Errorf("Expected no empty levels, found one at %f", level.Price)
+		}
+	}
+
+	// Ages move with the clock, so compare everything else
+	after := ob.Depth(0, 0)
+	for _, levels := range [][]PriceLevelSnapshot{before.Bids, after.Bids} {
+		for i := range levels {
+			levels[i].Age = 0
+		}
+	}
+	if !reflect.DeepEqual(before.Bids, after.Bids) {
+		t.Errorf("Expected bids unchanged, got %v then %v", before.Bids, after.Bids)
+	}
+	for order, position := range positions {
+		rank, ahead, err := ob.QueuePosition(order.ID)
+		if err != nil || float64(rank) != position[0] || ahead != position[1] {
+			t.Errorf("Expected order at %f to keep queue position %v, got %d/%f (%v)", order.Price, position, rank, ahead, err)
+		}
+	}
+}
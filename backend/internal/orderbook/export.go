@@ -0,0 +1,148 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ExportSchemaVersion is the version of the BookExport schema written by
+// ExportJSON. It is bumped whenever the schema changes incompatibly.
+const ExportSchemaVersion = 1
+
+// BookExport is the canonical JSON form of a full order book, every resting
+// order included, for exchanging books with external tools
+type BookExport struct {
+	SchemaVersion    int             `json:"schema_version"`
+	Symbol           string          `json:"symbol"`
+	Sequence         uint64          `json:"sequence"` // Count of changes to the book
+	Checksum         uint32          `json:"checksum"` // CRC-32 of the displayed levels, as from Checksum
+	LastPrice        float64         `json:"last_price"`
+	MarkPricePolicy  MarkPricePolicy `json:"mark_price_policy"`
+	LastChangeReason ChangeReason    `json:"last_change_reason,omitempty"`
+	Timestamp        time.Time       `json:"timestamp"`
+	Bids             []ExportLevel   `json:"bids"` // Best first
+	Asks             []ExportLevel   `json:"asks"` // Best first
+}
+
+// ExportLevel is one price level of a BookExport, with its orders in queue
+// priority
+type ExportLevel struct {
+	Price  float64         `json:"price"`
+	Orders []*models.Order `json:"orders"`
+}
+
+// ExportJSON writes the book's full state as a BookExport
+func (ob *OrderBook) ExportJSON() ([]byte, error) {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	export := BookExport{
+		SchemaVersion:    ExportSchemaVersion,
+		Symbol:           ob.Symbol,
+		Sequence:         ob.changeSeq,
+		LastPrice:        ob.LastPrice,
+		MarkPricePolicy:  ob.markBasis,
+		LastChangeReason: ob.change,
+		Timestamp:        ob.Timestamp,
+		Bids:             exportLevels(ob.Bids, func(a, b float64) bool { return a > b }),
+		Asks:             exportLevels(ob.Asks, func(a, b float64) bool { return a < b }),
+	}
+	export.Checksum = ob.displayedChecksum()
+	ob.recordChecksum(export.Checksum)
+	return json.Marshal(export)
+}
+
+// ImportJSON replaces the book's contents with a BookExport. The export must
+// be for this book's symbol and a supported schema version, and the imported
+// levels must reproduce its checksum. On error the book is left unchanged.
+func (ob *OrderBook) ImportJSON(data []byte) error {
+	var export BookExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return fmt.Errorf("invalid order book export: %w", err)
+	}
+	if export.SchemaVersion != ExportSchemaVersion {
+		return fmt.Errorf("unsupported order book schema version %d, expected %d", export.SchemaVersion, ExportSchemaVersion)
+	}
+
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	if export.Symbol != ob.Symbol {
+		return fmt.Errorf("export is for %s, not %s", export.Symbol, ob.Symbol)
+	}
+
+	bids, asks := newHeap(true, ob.tolerance), newHeap(false, ob.tolerance)
+	orders := make(map[uuid.UUID]*models.Order)
+	sequences := make(map[uuid.UUID]uint64)
+	sequence := uint64(0)
+	for _, side := range []struct {
+		levels []ExportLevel
+		heap   *PriceLevelHeap
+		side   models.OrderSide
+	}{{export.Bids, bids, models.OrderSideBuy}, {export.Asks, asks, models.OrderSideSell}} {
+		for _, level := range side.levels {
+			for _, order := range level.Orders {
+				if order == nil || order.Side != side.side || order.Symbol != ob.Symbol {
+					return fmt.Errorf("order on the %s side at %g does not belong there", side.side, level.Price)
+				}
+				if order.Price != level.Price {
+					return fmt.Errorf("order %s priced %g is on the %g level", order.ID, order.Price, level.Price)
+				}
+				if _, exists := orders[order.ID]; exists {
+					return fmt.Errorf("order %s appears more than once", order.ID)
+				}
+				side.heap.AddOrder(order)
+				orders[order.ID] = order
+				sequence++
+				sequences[order.ID] = sequence
+			}
+		}
+	}
+
+	now := ob.clock.Now()
+	checksum := Checksum(&OrderBookSnapshot{
+		Bids: groupLevels(bids, 0, 0, now),
+		Asks: groupLevels(asks, 0, 0, now),
+	})
+	if checksum != export.Checksum {
+		return fmt.Errorf("imported book checksum %d does not match the export's %d", checksum, export.Checksum)
+	}
+
+	ob.Bids.adopt(bids)
+	ob.Asks.adopt(asks)
+	ob.top.Store(nil)
+	ob.orders, ob.sequences, ob.sequence = orders, sequences, sequence
+	ob.LastPrice = export.LastPrice
+	ob.LastTrade = nil
+	if export.MarkPricePolicy != "" {
+		ob.markBasis = export.MarkPricePolicy
+	}
+	ob.change, ob.changeSeq = export.LastChangeReason, export.Sequence
+	ob.Timestamp = export.Timestamp
+	ob.checksums = []SeqChecksum{{Sequence: export.Sequence, Checksum: checksum}}
+	return nil
+}
+
+// exportLevels returns a side's levels best first with their live orders,
+// leaving out levels with none
+func exportLevels(h *PriceLevelHeap, better func(a, b float64) bool) []ExportLevel {
+	levels := make([]ExportLevel, 0, len(h.Levels))
+	for _, level := range h.Levels {
+		orders := make([]*models.Order, 0, len(level.Orders))
+		for _, order := range level.Orders {
+			if order.IsActive() && order.RemainingQuantity(level.epsilon) > 0 {
+				orders = append(orders, order)
+			}
+		}
+		if len(orders) > 0 {
+			levels = append(levels, ExportLevel{Price: level.Price, Orders: orders})
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool { return better(levels[i].Price, levels[j].Price) })
+	return levels
+}
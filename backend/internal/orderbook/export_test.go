@@ -0,0 +1,100 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	first := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 149.0)
+	second := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 149.0)
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 7, 151.0)
+	hidden.Hidden = true
+	for _, order := range []*models.Order{
+		first,
+		models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 20, 148.0),
+		second,
+		models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 8, 151.0),
+		hidden,
+		models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 3, 152.0),
+	} {
+		ob.AddOrder(order)
+	}
+	ob.LastPrice = 150.0
+
+	data, err := ob.ExportJSON()
+	if err != nil {
+		t.Fatalf("Expected export to succeed, got %v", err)
+	}
+
+	var export BookExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if export.SchemaVersion != ExportSchemaVersion || export.Sequence != 6 {
+		t.Errorf("Expected schema %d at sequence 6, got %d at %d", ExportSchemaVersion, export.SchemaVersion, export.Sequence)
+	}
+	if len(export.Bids) != 2 || export.Bids[0].Price != 149.0 || len(export.Asks[0].Orders) != 2 {
+		t.Errorf("Expected levels best first with every order, got %+v", export)
+	}
+
+	imported := NewOrderBook("AAPL")
+	if err := imported.ImportJSON(data); err != nil {
+		t.Fatalf("Expected import to succeed, got %v", err)
+	}
+
+	original, copied := ob.FullState(), imported.FullState()
+	if copied.Checksum != original.Checksum || copied.Checksum != export.Checksum {
+		t.Errorf("Expected checksum %d, got %d", original.Checksum, copied.Checksum)
+	}
+	if copied.Snapshot.LastChangeSeq != 6 || imported.OrderCount() != 6 || imported.LastPrice != 150.0 {
+		t.Errorf("Expected sequence, orders and last price preserved, got %+v", copied.Snapshot)
+	}
+	if total := imported.TotalResting(); total != ob.TotalResting() {
+		t.Errorf("Expected hidden orders imported too, got %+v", total)
+	}
+
+	// Queue priority within a level survives the round trip
+	level := imported.Bids.Peek()
+	if level.Orders[0].ID != first.ID || level.Orders[1].ID != second.ID {
+		t.Error("Expected the 149 bids in their original queue order")
+	}
+
+	again, err := imported.ExportJSON()
+	if err != nil || string(again) != string(data) {
+		t.Error("Expected a re-export of the imported book to be identical")
+	}
+}
+
+func TestImportRejectsMismatchedExport(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 149.0))
+	data, _ := ob.ExportJSON()
+
+	if err := NewOrderBook("MSFT").ImportJSON(data); err == nil {
+		t.Error("Expected an export for another symbol to be rejected")
+	}
+
+	var export BookExport
+	json.Unmarshal(data, &export)
+
+	export.SchemaVersion = ExportSchemaVersion + 1
+	future, _ := json.Marshal(export)
+	if err := NewOrderBook("AAPL").ImportJSON(future); err == nil {
+		t.Error("Expected an unknown schema version to be rejected")
+	}
+
+	export.SchemaVersion = ExportSchemaVersion
+	export.Checksum++
+	corrupt, _ := json.Marshal(export)
+	target := NewOrderBook("AAPL")
+	if err := target.ImportJSON(corrupt); err == nil {
+		t.Error("Expected a checksum mismatch to be rejected")
+	}
+	if target.OrderCount() != 0 {
+		t.Error("Expected a failed import to leave the book unchanged")
+	}
+}
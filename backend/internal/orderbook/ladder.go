@@ -0,0 +1,59 @@
+package orderbook
+
+import "github.com/acagliol/arbitrax/backend/internal/models"
+
+// LadderRow is one price on a depth-of-market ladder
+type LadderRow struct {
+	Price    float64          `json:"price"`
+	Side     models.OrderSide `json:"side"`
+	Quantity float64          `json:"quantity"`
+	Orders   int              `json:"orders"`
+}
+
+// Ladder is both sides of the book as a single array sorted from highest to
+// lowest price. Rows before MidIndex are asks and rows from MidIndex on are
+// bids, so the mid marker sits between the best ask and the best bid.
+type Ladder struct {
+	Symbol   string      `json:"symbol"`
+	Rows     []LadderRow `json:"rows"`
+	MidIndex int         `json:"mid_index"`
+	MidPrice float64     `json:"mid_price"`
+}
+
+// Ladder returns up to levels displayed price levels per side as a flat,
+// price-sorted ladder. A levels of 0 returns every level.
+func (ob *OrderBook) Ladder(levels int) *Ladder {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	now := ob.clock.Now()
+	bids := groupLevels(ob.Bids, levels, 0, now)
+	asks := groupLevels(ob.Asks, levels, 0, now)
+
+	ladder := &Ladder{
+		Symbol:   ob.Symbol,
+		Rows:     make([]LadderRow, 0, len(bids)+len(asks)),
+		MidIndex: len(asks),
+		MidPrice: ob.midPrice(),
+	}
+
+	// Asks come best first, so walk them backwards to put the highest on top
+	for i := len(asks) - 1; i >= 0; i-- {
+		ladder.Rows = append(ladder.Rows, LadderRow{
+			Price:    asks[i].Price,
+			Side:     models.OrderSideSell,
+			Quantity: asks[i].Quantity,
+			Orders:   asks[i].Orders,
+		})
+	}
+	for _, bid := range bids {
+		ladder.Rows = append(ladder.Rows, LadderRow{
+			Price:    bid.Price,
+			Side:     models.OrderSideBuy,
+			Quantity: bid.Quantity,
+			Orders:   bid.Orders,
+		})
+	}
+
+	return ladder
+}
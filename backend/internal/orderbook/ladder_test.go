@@ -0,0 +1,45 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestLadder(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	for _, price := range []float64{149.0, 150.0, 148.0} {
+		ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, price))
+	}
+	for _, price := range []float64{152.0, 151.0, 153.0} {
+		ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, price))
+	}
+
+	ladder := ob.Ladder(0)
+
+	if len(ladder.Rows) != 6 {
+		t.Fatalf("Expected 6 rows, got %d", len(ladder.Rows))
+	}
+	for i := 1; i < len(ladder.Rows); i++ {
+		if ladder.Rows[i].Price >= ladder.Rows[i-1].Price {
+			t.Errorf("Ladder not sorted at row %d: %v after %v", i, ladder.Rows[i].Price, ladder.Rows[i-1].Price)
+		}
+	}
+
+	bestAsk := ladder.Rows[ladder.MidIndex-1]
+	bestBid := ladder.Rows[ladder.MidIndex]
+	if bestAsk.Side != models.OrderSideSell || bestAsk.Price != 151.0 {
+		t.Errorf("Expected best ask 151 above the mid marker, got %+v", bestAsk)
+	}
+	if bestBid.Side != models.OrderSideBuy || bestBid.Price != 150.0 {
+		t.Errorf("Expected best bid 150 below the mid marker, got %+v", bestBid)
+	}
+	if ladder.MidPrice <= bestBid.Price || ladder.MidPrice >= bestAsk.Price {
+		t.Errorf("Expected mid price between %v and %v, got %v", bestBid.Price, bestAsk.Price, ladder.MidPrice)
+	}
+
+	if limited := ob.Ladder(2); len(limited.Rows) != 4 || limited.MidIndex != 2 {
+		t.Errorf("Expected 2 levels per side, got %d rows with mid index %d", len(limited.Rows), limited.MidIndex)
+	}
+}
@@ -0,0 +1,47 @@
+package orderbook
+
+// MarkPricePolicy selects how a book's mark price is derived
+type MarkPricePolicy string
+
+const (
+	// MarkPriceLastTrade marks at the last traded price
+	MarkPriceLastTrade MarkPricePolicy = "last_trade"
+	// MarkPriceMid marks at the mid when the book is two-sided, otherwise
+	// at the last traded price
+	MarkPriceMid MarkPricePolicy = "mid"
+	// MarkPriceMicroprice marks at the size-weighted mid, which leans
+	// towards the side with less displayed size
+	MarkPriceMicroprice MarkPricePolicy = "microprice"
+)
+
+// SetMarkPricePolicy sets how MarkPrice is computed
+func (ob *OrderBook) SetMarkPricePolicy(policy MarkPricePolicy) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	ob.markBasis = policy
+}
+
+// MarkPrice returns the book's mark price under its configured policy. Like
+// the BBO, the mid and microprice see displayed liquidity only.
+func (ob *OrderBook) MarkPrice() float64 {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	if ob.markBasis == MarkPriceLastTrade {
+		return ob.LastPrice
+	}
+
+	top := ob.displayedTop()
+	if top.BidPrice == 0 || top.AskPrice == 0 {
+		return ob.LastPrice
+	}
+
+	if ob.markBasis == MarkPriceMicroprice {
+		if size := top.BidQuantity + top.AskQuantity; size > 0 {
+			return (top.BidPrice*top.AskQuantity + top.AskPrice*top.BidQuantity) / size
+		}
+	}
+
+	return (top.BidPrice + top.AskPrice) / 2
+}
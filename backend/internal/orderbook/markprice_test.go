@@ -0,0 +1,71 @@
+package orderbook
+
+import (
+	"math"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestMarkPricePolicies(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.LastPrice = 149.0
+
+	// 300 bid at 150 against 100 offered at 152
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 300, 150.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 152.0))
+
+	tests := []struct {
+		policy   MarkPricePolicy
+		expected float64
+	}{
+		{MarkPriceLastTrade, 149.0},
+		{MarkPriceMid, 151.0},
+		// (150*100 + 152*300) / 400, leaning towards the thin ask
+		{MarkPriceMicroprice, 151.5},
+	}
+
+	for _, tt := range tests {
+		ob.SetMarkPricePolicy(tt.policy)
+		if mark := ob.MarkPrice(); math.Abs(mark-tt.expected) > 1e-9 {
+			t.Errorf("Expected %s mark %f, got %f", tt.policy, tt.expected, mark)
+		}
+	}
+}
+
+func TestMarkPriceOneSidedBook(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.LastPrice = 149.0
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 300, 150.0))
+
+	for _, policy := range []MarkPricePolicy{MarkPriceMid, MarkPriceMicroprice} {
+		ob.SetMarkPricePolicy(policy)
+		if mark := ob.MarkPrice(); mark != 149.0 {
+			t.Errorf("Expected %s to fall back to the last price, got %f", policy, mark)
+		}
+	}
+}
+
+func TestMicropriceWeighsDisplayedSize(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.SetMarkPricePolicy(MarkPriceMicroprice)
+
+	// Hidden size on either side must not move the mark
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 300, 150.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 152.0))
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1000, 152.0)
+	hidden.Hidden = true
+	ob.AddOrder(hidden)
+
+	if mark := ob.MarkPrice(); math.Abs(mark-151.5) > 1e-9 {
+		t.Errorf("Expected the microprice from displayed size at 151.5, got %f", mark)
+	}
+
+	// A level of hidden orders alone isn't a displayed quote
+	better := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 50, 151.0)
+	better.Hidden = true
+	ob.AddOrder(better)
+	if mark := ob.MarkPrice(); math.Abs(mark-151.5) > 1e-9 {
+		t.Errorf("Expected the hidden bid ignored, got %f", mark)
+	}
+}
@@ -1,34 +1,81 @@
 package orderbook
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/acagliol/arbitrax/backend/internal/clock"
 	"github.com/acagliol/arbitrax/backend/internal/models"
 	"github.com/google/uuid"
 )
 
 // OrderBook represents the order book for a single symbol
 type OrderBook struct {
-	Symbol    string
-	Bids      *PriceLevelHeap
-	Asks      *PriceLevelHeap
-	LastPrice float64
-	LastTrade *models.Trade
-	Timestamp time.Time
-	mutex     sync.RWMutex
-	orders    map[uuid.UUID]*models.Order // Track all orders by ID
+	Symbol       string
+	Bids         *PriceLevelHeap
+	Asks         *PriceLevelHeap
+	LastPrice    float64
+	LastTrade    *models.Trade
+	Timestamp    time.Time
+	mutex        sync.RWMutex
+	orders       map[uuid.UUID]*models.Order // Track all orders by ID
+	sequences    map[uuid.UUID]uint64        // Arrival order of each indexed order
+	sequence     uint64
+	checksums    []SeqChecksum // Checksums as read, by sequence
+	checksumLock sync.Mutex    // Guards checksums, which readers record
+	clock        clock.Clock
+	markBasis    MarkPricePolicy
+	change       ChangeReason        // What last changed the book
+	changeSeq    uint64              // Counts changes to the book
+	top          atomic.Pointer[BBO] // Displayed top of book, nil until read after a change
+	tolerance    models.Tolerance    // Fixed at creation, as levels are keyed by it
 }
 
-// NewOrderBook creates a new order book for a symbol
+// NewOrderBook creates a new order book for a symbol using the default
+// tolerance
 func NewOrderBook(symbol string) *OrderBook {
+	return NewOrderBookWithTolerance(symbol, models.DefaultTolerance)
+}
+
+// NewOrderBookWithTolerance creates a new order book for a symbol that
+// treats quantities and prices within tolerance as equal. A margin that
+// isn't positive takes its default.
+func NewOrderBookWithTolerance(symbol string, tolerance models.Tolerance) *OrderBook {
+	tolerance = tolerance.OrDefault()
 	return &OrderBook{
 		Symbol:    symbol,
-		Bids:      NewBidHeap(),
-		Asks:      NewAskHeap(),
+		Bids:      newHeap(true, tolerance),
+		Asks:      newHeap(false, tolerance),
 		LastPrice: 0,
 		Timestamp: time.Now(),
 		orders:    make(map[uuid.UUID]*models.Order),
+		sequences: make(map[uuid.UUID]uint64),
+		clock:     clock.Real{},
+		markBasis: MarkPriceMid,
+		tolerance: tolerance,
+	}
+}
+
+// Tolerance returns the margins within which the book treats quantities
+// and prices as equal
+func (ob *OrderBook) Tolerance() models.Tolerance {
+	return ob.tolerance
+}
+
+// SetClock replaces the clock used for timestamps and level ages. A book
+// that has not changed yet takes its timestamp from the new clock.
+func (ob *OrderBook) SetClock(c clock.Clock) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	ob.clock = c
+	if ob.changeSeq == 0 {
+		ob.Timestamp = c.Now()
 	}
 }
 
@@ -37,8 +84,17 @@ func (ob *OrderBook) AddOrder(order *models.Order) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
+	ob.addOrder(order)
+	ob.markChanged(ChangeAdd)
+}
+
+// addOrder adds an order at the back of its price level. The caller must
+// hold the mutex.
+func (ob *OrderBook) addOrder(order *models.Order) {
 	// Store order
 	ob.orders[order.ID] = order
+	ob.sequence++
+	ob.sequences[order.ID] = ob.sequence
 
 	// Add to appropriate side
 	if order.Side == models.OrderSideBuy {
@@ -46,8 +102,6 @@ func (ob *OrderBook) AddOrder(order *models.Order) {
 	} else {
 		ob.Asks.AddOrder(order)
 	}
-
-	ob.Timestamp = time.Now()
 }
 
 // RemoveOrder removes an order from the order book
@@ -60,7 +114,16 @@ func (ob *OrderBook) RemoveOrder(orderID uuid.UUID) bool {
 		return false
 	}
 
-	delete(ob.orders, orderID)
+	removed := ob.removeOrder(order)
+	ob.markChanged(ChangeCancel)
+	return removed
+}
+
+// removeOrder takes a resting order out of the book. The caller must hold
+// the mutex.
+func (ob *OrderBook) removeOrder(order *models.Order) bool {
+	delete(ob.orders, order.ID)
+	delete(ob.sequences, order.ID)
 
 	if order.Side == models.OrderSideBuy {
 		return ob.Bids.RemoveOrder(order)
@@ -68,6 +131,41 @@ func (ob *OrderBook) RemoveOrder(orderID uuid.UUID) bool {
 	return ob.Asks.RemoveOrder(order)
 }
 
+// PruneLevel removes fully consumed or cancelled orders from a price level
+// and from the order index. It returns true if the level has no live orders left.
+func (ob *OrderBook) PruneLevel(level *PriceLevel) bool {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	live := level.Orders[:0]
+	for _, order := range level.Orders {
+		if order.RemainingQuantity(ob.tolerance.Quantity) <= 0 || !order.IsActive() {
+			delete(ob.orders, order.ID)
+			delete(ob.sequences, order.ID)
+			continue
+		}
+		live = append(live, order)
+	}
+	// Clear the tail so pruned orders can be garbage collected
+	for i := len(live); i < len(level.Orders); i++ {
+		level.Orders[i] = nil
+	}
+	if len(live) < len(level.Orders) {
+		ob.top.Store(nil)
+	}
+	level.Orders = live
+
+	return len(level.Orders) == 0
+}
+
+// OrderCount returns the number of orders tracked by the book
+func (ob *OrderBook) OrderCount() int {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	return len(ob.orders)
+}
+
 // GetOrder retrieves an order by ID
 func (ob *OrderBook) GetOrder(orderID uuid.UUID) (*models.Order, bool) {
 	ob.mutex.RLock()
@@ -77,6 +175,87 @@ func (ob *OrderBook) GetOrder(orderID uuid.UUID) (*models.Order, bool) {
 	return order, exists
 }
 
+// ErrOrderNotFound is returned when an order is not resting in the book
+var ErrOrderNotFound = errors.New("order not found")
+
+// QueuePosition returns how many displayed orders, and how much displayed
+// quantity, sit ahead of an order at its price level. Hidden orders are left
+// out, so the answer never reveals undisplayed size.
+func (ob *OrderBook) QueuePosition(orderID uuid.UUID) (rank int, aheadQuantity float64, err error) {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	order, exists := ob.orders[orderID]
+	if !exists {
+		return 0, 0, ErrOrderNotFound
+	}
+
+	h := ob.Asks
+	if order.Side == models.OrderSideBuy {
+		h = ob.Bids
+	}
+
+	for _, level := range h.Levels {
+		if !ob.tolerance.PricesEqual(level.Price, order.Price) {
+			continue
+		}
+		for _, o := range level.Orders {
+			if o.ID == orderID {
+				return rank, aheadQuantity, nil
+			}
+			if o.Hidden {
+				continue
+			}
+			rank++
+			aheadQuantity += o.RemainingQuantity(ob.tolerance.Quantity)
+		}
+	}
+	return 0, 0, ErrOrderNotFound
+}
+
+// CancelImpact returns what the best bid and ask would be if the given order
+// were cancelled, without touching the book
+func (ob *OrderBook) CancelImpact(orderID uuid.UUID) (newBestBid, newBestAsk float64, err error) {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	order, exists := ob.orders[orderID]
+	if !exists {
+		return 0, 0, ErrOrderNotFound
+	}
+
+	if order.Side == models.OrderSideBuy {
+		return bestExcluding(ob.Bids, orderID), bestPrice(ob.Asks), nil
+	}
+	return bestPrice(ob.Bids), bestExcluding(ob.Asks, orderID), nil
+}
+
+// bestPrice returns the price at the top of a heap, or 0 if it is empty
+func bestPrice(h *PriceLevelHeap) float64 {
+	if h.Len() == 0 {
+		return 0
+	}
+	return h.Peek().Price
+}
+
+// bestExcluding returns the best price on a side that still has live
+// quantity once the given order is taken out, or 0 if none does
+func bestExcluding(h *PriceLevelHeap, orderID uuid.UUID) float64 {
+	best := 0.0
+	for _, level := range h.Levels {
+		if best != 0 && (h.IsBid && level.Price <= best || !h.IsBid && level.Price >= best) {
+			continue
+		}
+		for _, o := range level.Orders {
+			if o.ID != orderID && o.RemainingQuantity(level.epsilon) > 0 {
+				best = level.Price
+				break
+			}
+		}
+	}
+	return best
+}
+
 // GetBestBid returns the highest bid price
 func (ob *OrderBook) GetBestBid() float64 {
 	ob.mutex.RLock()
@@ -99,6 +278,65 @@ func (ob *OrderBook) GetBestAsk() float64 {
 	return ob.Asks.Peek().Price
 }
 
+// AvailableQuantity returns the resting quantity an incoming order on the
+// given side could execute against at or better than limitPrice. A limitPrice
+// of 0 counts the whole opposite side, as for a market order.
+func (ob *OrderBook) AvailableQuantity(side models.OrderSide, limitPrice float64) float64 {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	opposite := ob.Asks
+	if side == models.OrderSideSell {
+		opposite = ob.Bids
+	}
+
+	total := 0.0
+	for _, level := range opposite.Levels {
+		if limitPrice > 0 {
+			if side == models.OrderSideBuy && level.Price > limitPrice {
+				continue
+			}
+			if side == models.OrderSideSell && level.Price < limitPrice {
+				continue
+			}
+		}
+		for _, order := range level.Orders {
+			total += order.RemainingQuantity(ob.tolerance.Quantity)
+		}
+	}
+	return total
+}
+
+// CostToMoveTo returns the displayed quantity and notional an order on the
+// given side would have to execute to sweep every opposite level up to
+// targetPrice (down to it for a sell). Hidden orders are left out, so the
+// answer never reveals undisplayed size. The book is not modified.
+func (ob *OrderBook) CostToMoveTo(side models.OrderSide, targetPrice float64) (quantity, notional float64) {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	now := ob.clock.Now()
+
+	opposite := ob.Asks
+	if side == models.OrderSideSell {
+		opposite = ob.Bids
+	}
+
+	for _, level := range opposite.Levels {
+		if side == models.OrderSideBuy && level.Price > targetPrice {
+			continue
+		}
+		if side == models.OrderSideSell && level.Price < targetPrice {
+			continue
+		}
+		if displayed, ok := level.displayed(now); ok {
+			quantity += displayed.Quantity
+			notional += displayed.Quantity * level.Price
+		}
+	}
+	return quantity, notional
+}
+
 // GetSpread returns the bid-ask spread
 func (ob *OrderBook) GetSpread() float64 {
 	bestBid := ob.GetBestBid()
@@ -113,14 +351,93 @@ func (ob *OrderBook) GetSpread() float64 {
 
 // GetMidPrice returns the mid-market price
 func (ob *OrderBook) GetMidPrice() float64 {
-	bestBid := ob.GetBestBid()
-	bestAsk := ob.GetBestAsk()
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
 
-	if bestBid == 0 || bestAsk == 0 {
+	return ob.midPrice()
+}
+
+// midPrice returns the mid of the best displayed bid and offer, or the last
+// price if either side shows nothing, so hidden orders never move it. The
+// caller must hold the mutex.
+func (ob *OrderBook) midPrice() float64 {
+	top := ob.displayedTop()
+	if top.BidPrice == 0 || top.AskPrice == 0 {
 		return ob.LastPrice
 	}
 
-	return (bestBid + bestAsk) / 2
+	return (top.BidPrice + top.AskPrice) / 2
+}
+
+// Validate checks the book's internal invariants: the book is not crossed,
+// every resting order is indexed exactly once, and the index holds nothing
+// that isn't resting
+func (ob *OrderBook) Validate() []error {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	errs := make([]error, 0)
+	seen := make(map[uuid.UUID]bool)
+
+	check := func(h *PriceLevelHeap, side models.OrderSide) {
+		for _, level := range h.Levels {
+			for _, order := range level.Orders {
+				if seen[order.ID] {
+					errs = append(errs, fmt.Errorf("%s: order %s appears more than once", ob.Symbol, order.ID))
+					continue
+				}
+				seen[order.ID] = true
+
+				if order.Side != side {
+					errs = append(errs, fmt.Errorf("%s: %s order %s rests on the %s side", ob.Symbol, order.Side, order.ID, side))
+				}
+				if !ob.tolerance.PricesEqual(order.Price, level.Price) {
+					errs = append(errs, fmt.Errorf("%s: order %s priced %g rests at level %g", ob.Symbol, order.ID, order.Price, level.Price))
+				}
+				if indexed, exists := ob.orders[order.ID]; !exists || indexed != order {
+					errs = append(errs, fmt.Errorf("%s: resting order %s is missing from the order index", ob.Symbol, order.ID))
+				}
+			}
+		}
+	}
+	check(ob.Bids, models.OrderSideBuy)
+	check(ob.Asks, models.OrderSideSell)
+
+	for id := range ob.orders {
+		if !seen[id] {
+			errs = append(errs, fmt.Errorf("%s: indexed order %s is not resting in the book", ob.Symbol, id))
+		}
+	}
+
+	// Scan every level rather than peeking so a broken heap can't hide a cross
+	bestBid, bestAsk := 0.0, 0.0
+	for _, level := range ob.Bids.Levels {
+		if len(level.Orders) > 0 && level.Price > bestBid {
+			bestBid = level.Price
+		}
+	}
+	for _, level := range ob.Asks.Levels {
+		if len(level.Orders) > 0 && (bestAsk == 0 || level.Price < bestAsk) {
+			bestAsk = level.Price
+		}
+	}
+	if bestBid > 0 && bestAsk > 0 && bestBid >= bestAsk {
+		errs = append(errs, fmt.Errorf("%s: book is crossed with bid %g at or above ask %g", ob.Symbol, bestBid, bestAsk))
+	}
+
+	return errs
+}
+
+// OrderIDs returns the IDs of all orders tracked by the book
+func (ob *OrderBook) OrderIDs() []uuid.UUID {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	ids := make([]uuid.UUID, 0, len(ob.orders))
+	for id := range ob.orders {
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 // Snapshot returns a snapshot of the order book
@@ -133,50 +450,197 @@ func (ob *OrderBook) Snapshot() *OrderBookSnapshot {
 		Bids:      make([]PriceLevelSnapshot, 0),
 		Asks:      make([]PriceLevelSnapshot, 0),
 		LastPrice: ob.LastPrice,
+		MidPrice:  ob.midPrice(),
 		Timestamp: ob.Timestamp,
 	}
+	snapshot.LastChangeReason, snapshot.LastChangeSeq = ob.change, ob.changeSeq
+
+	now := ob.clock.Now()
 
 	// Copy bid levels
 	for _, level := range ob.Bids.Levels {
-		totalQty := 0.0
-		for _, order := range level.Orders {
-			totalQty += order.RemainingQuantity()
+		if displayed, ok := level.displayed(now); ok {
+			snapshot.Bids = append(snapshot.Bids, displayed)
 		}
-		snapshot.Bids = append(snapshot.Bids, PriceLevelSnapshot{
-			Price:    level.Price,
-			Quantity: totalQty,
-			Orders:   len(level.Orders),
-		})
 	}
 
 	// Copy ask levels
 	for _, level := range ob.Asks.Levels {
-		totalQty := 0.0
-		for _, order := range level.Orders {
-			totalQty += order.RemainingQuantity()
+		if displayed, ok := level.displayed(now); ok {
+			snapshot.Asks = append(snapshot.Asks, displayed)
 		}
-		snapshot.Asks = append(snapshot.Asks, PriceLevelSnapshot{
-			Price:    level.Price,
-			Quantity: totalQty,
-			Orders:   len(level.Orders),
-		})
 	}
 
 	return snapshot
 }
 
+// Depth returns a snapshot sorted from the touch outward, with prices grouped
+// into buckets of the given size and limited to the best depth buckets per
+// side. A depth <= 0 returns every level and a grouping <= 0 disables grouping.
+func (ob *OrderBook) Depth(depth int, grouping float64) *OrderBookSnapshot {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	now := ob.clock.Now()
+	snapshot := &OrderBookSnapshot{
+		Symbol:    ob.Symbol,
+		Bids:      groupLevels(ob.Bids, depth, grouping, now),
+		Asks:      groupLevels(ob.Asks, depth, grouping, now),
+		LastPrice: ob.LastPrice,
+		MidPrice:  ob.midPrice(),
+		Timestamp: ob.Timestamp,
+	}
+	snapshot.LastChangeReason, snapshot.LastChangeSeq = ob.change, ob.changeSeq
+	return snapshot
+}
+
+// SnapshotPage returns up to count displayed levels on one side, best first,
+// starting fromLevel levels away from the touch, so very deep books can be
+// read a page at a time. A page shorter than count is the last one.
+func (ob *OrderBook) SnapshotPage(side models.OrderSide, fromLevel, count int) []PriceLevelSnapshot {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	h := ob.Asks
+	if side == models.OrderSideBuy {
+		h = ob.Bids
+	}
+
+	return pageLevels(groupLevels(h, 0, 0, ob.clock.Now()), fromLevel, count)
+}
+
+// pageLevels returns up to count of best-first levels starting at fromLevel
+func pageLevels(levels []PriceLevelSnapshot, fromLevel, count int) []PriceLevelSnapshot {
+	if fromLevel < 0 || fromLevel >= len(levels) || count <= 0 {
+		return []PriceLevelSnapshot{}
+	}
+	end := fromLevel + count
+	if end > len(levels) {
+		end = len(levels)
+	}
+	return levels[fromLevel:end]
+}
+
+// Regroup returns a copy of a snapshot sorted best first, grouped and limited
+// as Depth would have done when it was taken
+func (s *OrderBookSnapshot) Regroup(depth int, grouping float64) *OrderBookSnapshot {
+	regrouped := *s
+	regrouped.Bids = regroupLevels(s.Bids, depth, grouping, true)
+	regrouped.Asks = regroupLevels(s.Asks, depth, grouping, false)
+	return &regrouped
+}
+
+// Page returns up to count of a snapshot's levels on one side, best first,
+// starting fromLevel levels away from the touch, as SnapshotPage would have
+// done when it was taken
+func (s *OrderBookSnapshot) Page(side models.OrderSide, fromLevel, count int) []PriceLevelSnapshot {
+	if side == models.OrderSideBuy {
+		return pageLevels(regroupLevels(s.Bids, 0, 0, true), fromLevel, count)
+	}
+	return pageLevels(regroupLevels(s.Asks, 0, 0, false), fromLevel, count)
+}
+
+// regroupLevels aggregates snapshot levels into price buckets, best first,
+// bucketing as groupLevels does
+func regroupLevels(snapshot []PriceLevelSnapshot, depth int, grouping float64, isBid bool) []PriceLevelSnapshot {
+	buckets := make(map[float64]*PriceLevelSnapshot)
+	for _, level := range snapshot {
+		price := level.Price
+		if grouping > 0 {
+			price = bucketPrice(price, grouping, isBid)
+		}
+
+		bucket, exists := buckets[price]
+		if !exists {
+			bucket = &PriceLevelSnapshot{Price: price}
+			buckets[price] = bucket
+		}
+		bucket.Quantity += level.Quantity
+		bucket.Orders += level.Orders
+		if level.Age > bucket.Age {
+			bucket.Age = level.Age
+		}
+	}
+
+	levels := make([]PriceLevelSnapshot, 0, len(buckets))
+	for _, bucket := range buckets {
+		levels = append(levels, *bucket)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if isBid {
+			return levels[i].Price > levels[j].Price
+		}
+		return levels[i].Price < levels[j].Price
+	})
+
+	if depth > 0 && len(levels) > depth {
+		levels = levels[:depth]
+	}
+	return levels
+}
+
+// groupLevels aggregates a heap's levels into price buckets, best first.
+// Bids are bucketed down and asks up so a bucket never advertises a better
+// price than the liquidity it contains.
+func groupLevels(h *PriceLevelHeap, depth int, grouping float64, now time.Time) []PriceLevelSnapshot {
+	displayed := make([]PriceLevelSnapshot, 0, len(h.Levels))
+	for _, level := range h.Levels {
+		if snapshot, ok := level.displayed(now); ok {
+			displayed = append(displayed, snapshot)
+		}
+	}
+	return regroupLevels(displayed, depth, grouping, h.IsBid)
+}
+
+// bucketPrice maps a price onto its grouping bucket
+func bucketPrice(price, grouping float64, isBid bool) float64 {
+	// Nudge by a tiny epsilon so prices already on a bucket boundary are not
+	// pushed into the neighbouring bucket by float division error
+	var index float64
+	if isBid {
+		index = math.Floor(price/grouping + groupingEpsilon)
+	} else {
+		index = math.Ceil(price/grouping - groupingEpsilon)
+	}
+	return math.Round(index*grouping/groupingEpsilon) * groupingEpsilon
+}
+
+// groupingEpsilon is the precision used when bucketing prices
+const groupingEpsilon = 1e-9
+
+// ValidateGrouping checks that a grouping is positive and, when the symbol
+// has a tick size, a whole multiple of it
+func ValidateGrouping(grouping, tickSize float64) error {
+	if grouping <= 0 {
+		return fmt.Errorf("grouping must be positive")
+	}
+	if tickSize > 0 {
+		ticks := grouping / tickSize
+		if math.Abs(ticks-math.Round(ticks)) > groupingEpsilon*ticks || math.Round(ticks) < 1 {
+			return fmt.Errorf("grouping %g is not a multiple of tick size %g", grouping, tickSize)
+		}
+	}
+	return nil
+}
+
 // OrderBookSnapshot is a read-only snapshot of the order book
 type OrderBookSnapshot struct {
-	Symbol    string                `json:"symbol"`
-	Bids      []PriceLevelSnapshot  `json:"bids"`
-	Asks      []PriceLevelSnapshot  `json:"asks"`
-	LastPrice float64               `json:"last_price"`
-	Timestamp time.Time             `json:"timestamp"`
+	Symbol    string               `json:"symbol"`
+	Bids      []PriceLevelSnapshot `json:"bids"`
+	Asks      []PriceLevelSnapshot `json:"asks"`
+	LastPrice float64              `json:"last_price"`
+	MidPrice  float64              `json:"mid_price"`
+	Timestamp time.Time            `json:"timestamp"`
+
+	// What last changed the book, and a count of changes so far
+	LastChangeReason ChangeReason `json:"last_change_reason,omitempty"`
+	LastChangeSeq    uint64       `json:"last_change_seq"`
 }
 
 // PriceLevelSnapshot represents a price level in the snapshot
 type PriceLevelSnapshot struct {
-	Price    float64 `json:"price"`
-	Quantity float64 `json:"quantity"`
-	Orders   int     `json:"orders"`
+	Price    float64       `json:"price"`
+	Quantity float64       `json:"quantity"`
+	Orders   int           `json:"orders"`
+	Age      time.Duration `json:"age_ns"` // How long the front-of-queue order has rested
 }
@@ -1,9 +1,13 @@
 package orderbook
 
 import (
+	"math"
 	"testing"
+	"time"
 
+	"github.com/acagliol/arbitrax/backend/internal/clock"
 	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
 )
 
 func TestNewOrderBook(t *testing.T) {
@@ -90,6 +94,20 @@ func TestGetMidPrice(t *testing.T) {
 	}
 }
 
+func TestMidPriceIgnoresHiddenOrders(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 152.0))
+
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 151.0)
+	hidden.Hidden = true
+	ob.AddOrder(hidden)
+
+	if mid := ob.GetMidPrice(); mid != 151.0 {
+		t.Errorf("Expected the hidden offer not to move the mid off 151, got %f", mid)
+	}
+}
+
 func TestRemoveOrder(t *testing.T) {
 	ob := NewOrderBook("AAPL")
 
@@ -135,3 +153,279 @@ func TestSnapshot(t *testing.T) {
 		t.Errorf("Expected 2 orders at bid level, got %d", snapshot.Bids[0].Orders)
 	}
 }
+
+func TestDepthWithGrouping(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	// Bids from 149.99 down to 149.50 and asks from 150.01 up to 150.50, one cent apart
+	for i := 0; i < 50; i++ {
+		ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 149.99-float64(i)*0.01))
+		ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 150.01+float64(i)*0.01))
+	}
+
+	snapshot := ob.Depth(5, 0.05)
+
+	if len(snapshot.Bids) != 5 || len(snapshot.Asks) != 5 {
+		t.Fatalf("Expected 5 buckets per side, got %d bids and %d asks", len(snapshot.Bids), len(snapshot.Asks))
+	}
+
+	expectedBids := []float64{149.95, 149.90, 149.85, 149.80, 149.75}
+	for i, level := range snapshot.Bids {
+		if math.Abs(level.Price-expectedBids[i]) > 1e-9 {
+			t.Errorf("Expected bid bucket %d at %f, got %f", i, expectedBids[i], level.Price)
+		}
+		if level.Orders != 5 || level.Quantity != 50 {
+			t.Errorf("Expected bid bucket %d to hold 5 orders for 50, got %d for %f", i, level.Orders, level.Quantity)
+		}
+	}
+
+	expectedAsks := []float64{150.05, 150.10, 150.15, 150.20, 150.25}
+	for i, level := range snapshot.Asks {
+		if math.Abs(level.Price-expectedAsks[i]) > 1e-9 {
+			t.Errorf("Expected ask bucket %d at %f, got %f", i, expectedAsks[i], level.Price)
+		}
+		if level.Orders != 5 || level.Quantity != 50 {
+			t.Errorf("Expected ask bucket %d to hold 5 orders for 50, got %d for %f", i, level.Orders, level.Quantity)
+		}
+	}
+}
+
+func TestValidateGrouping(t *testing.T) {
+	if err := ValidateGrouping(0.05, 0.01); err != nil {
+		t.Errorf("Expected 0.05 to be valid for tick 0.01, got %v", err)
+	}
+
+	if err := ValidateGrouping(0, 0.01); err == nil {
+		t.Error("Expected zero grouping to be rejected")
+	}
+
+	if err := ValidateGrouping(0.015, 0.01); err == nil {
+		t.Error("Expected grouping that is not a tick multiple to be rejected")
+	}
+
+	if err := ValidateGrouping(0.005, 0.01); err == nil {
+		t.Error("Expected grouping smaller than the tick to be rejected")
+	}
+}
+
+func TestSnapshotLevelAge(t *testing.T) {
+	start := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	mock := clock.NewMock(start)
+
+	ob := NewOrderBook("AAPL")
+	ob.SetClock(mock)
+
+	oldBid := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	oldBid.SubmittedAt = start
+	ob.AddOrder(oldBid)
+
+	newerBid := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	newerBid.SubmittedAt = start.Add(30 * time.Second)
+	ob.AddOrder(newerBid)
+
+	ask := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 152.0)
+	ask.SubmittedAt = start.Add(45 * time.Second)
+	ob.AddOrder(ask)
+
+	mock.Advance(time.Minute)
+	snapshot := ob.Snapshot()
+
+	// The bid level's age comes from its front-of-queue order
+	if snapshot.Bids[0].Age != time.Minute {
+		t.Errorf("Expected bid level age 1m, got %v", snapshot.Bids[0].Age)
+	}
+
+	if snapshot.Asks[0].Age != 15*time.Second {
+		t.Errorf("Expected ask level age 15s, got %v", snapshot.Asks[0].Age)
+	}
+}
+
+func TestQueuePosition(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	first := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	middle := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 50, 150.0)
+	last := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 25, 150.0)
+	ob.AddOrder(first)
+	ob.AddOrder(middle)
+	ob.AddOrder(last)
+
+	// Orders at other prices don't count towards the queue
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 500, 151.0))
+
+	rank, ahead, err := ob.QueuePosition(middle.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rank != 1 {
+		t.Errorf("Expected 1 order ahead, got %d", rank)
+	}
+	if ahead != 100 {
+		t.Errorf("Expected 100 ahead, got %f", ahead)
+	}
+
+	rank, ahead, _ = ob.QueuePosition(last.ID)
+	if rank != 2 || ahead != 150 {
+		t.Errorf("Expected last order to have 2 orders and 150 ahead, got %d and %f", rank, ahead)
+	}
+
+	if _, _, err := ob.QueuePosition(uuid.New()); err != ErrOrderNotFound {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+func TestQueuePositionIgnoresHiddenOrders(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	shown := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 400, 150.0)
+	hidden.Hidden = true
+	mine := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 25, 150.0)
+	ob.AddOrder(shown)
+	ob.AddOrder(hidden)
+	ob.AddOrder(mine)
+
+	rank, ahead, err := ob.QueuePosition(mine.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rank != 1 || ahead != 100 {
+		t.Errorf("Expected only the displayed order ahead, got %d orders and %f", rank, ahead)
+	}
+}
+
+func TestCancelImpact(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	best := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 150.0)
+	ob.AddOrder(best)
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 149.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 100, 148.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 151.0))
+
+	bid, ask, err := ob.CancelImpact(best.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if bid != 149.0 {
+		t.Errorf("Expected new best bid 149, got %f", bid)
+	}
+	if ask != 151.0 {
+		t.Errorf("Expected best ask to stay 151, got %f", ask)
+	}
+
+	// The book itself is untouched
+	if ob.GetBestBid() != 150.0 || ob.OrderCount() != 4 {
+		t.Errorf("Expected the book unchanged, got best bid %f and %d orders", ob.GetBestBid(), ob.OrderCount())
+	}
+
+	// A second order at the touch keeps it in place
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 150.0))
+	if bid, _, _ := ob.CancelImpact(best.ID); bid != 150.0 {
+		t.Errorf("Expected best bid to stay 150 with another order there, got %f", bid)
+	}
+
+	if _, _, err := ob.CancelImpact(uuid.New()); err != ErrOrderNotFound {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+func TestSnapshotPageReassemblesLadder(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	// 250 bid levels added out of price order
+	for i := 0; i < 250; i++ {
+		price := 100.0 - float64((i*37)%250)*0.01
+		ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, price))
+	}
+
+	full := ob.Depth(0, 0).Bids
+	paged := make([]PriceLevelSnapshot, 0, len(full))
+	for from := 0; ; from += 40 {
+		page := ob.SnapshotPage(models.OrderSideBuy, from, 40)
+		paged = append(paged, page...)
+		if len(page) < 40 {
+			break
+		}
+	}
+
+	if len(paged) != 250 {
+		t.Fatalf("Expected 250 levels across pages, got %d", len(paged))
+	}
+	for i := range paged {
+		if paged[i].Price != full[i].Price || paged[i].Quantity != full[i].Quantity {
+			t.Fatalf("Expected level %d to be %v, got %v", i, full[i], paged[i])
+		}
+		if i > 0 && paged[i].Price >= paged[i-1].Price {
+			t.Fatalf("Expected bids best first, got %f after %f", paged[i].Price, paged[i-1].Price)
+		}
+	}
+
+	if page := ob.SnapshotPage(models.OrderSideBuy, 250, 40); len(page) != 0 {
+		t.Errorf("Expected an empty page past the end, got %d levels", len(page))
+	}
+	if page := ob.SnapshotPage(models.OrderSideSell, 0, 40); len(page) != 0 {
+		t.Errorf("Expected no ask levels, got %d", len(page))
+	}
+}
+
+func TestCostToMoveTo(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 100.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 20, 101.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 5, 101.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 30, 103.0))
+
+	quantity, notional := ob.CostToMoveTo(models.OrderSideBuy, 102.0)
+
+	if quantity != 35 {
+		t.Errorf("Expected quantity 35, got %v", quantity)
+	}
+	if expected := 10*100.0 + 25*101.0; notional != expected {
+		t.Errorf("Expected notional %v, got %v", expected, notional)
+	}
+
+	if quantity, _ := ob.CostToMoveTo(models.OrderSideBuy, 99.0); quantity != 0 {
+		t.Errorf("Expected nothing to sweep below the best ask, got %v", quantity)
+	}
+
+	if ob.OrderCount() != 4 {
+		t.Errorf("Expected the book to be unchanged, got %d orders", ob.OrderCount())
+	}
+
+	// Hidden size is not counted, even where it shares a level
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 50, 101.0)
+	hidden.Hidden = true
+	ob.AddOrder(hidden)
+	if quantity, _ := ob.CostToMoveTo(models.OrderSideBuy, 102.0); quantity != 35 {
+		t.Errorf("Expected the hidden offer left out, got %v", quantity)
+	}
+}
+
+func TestSnapshotRegroupMatchesDepth(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	for i := 0; i < 50; i++ {
+		ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 149.99-float64(i)*0.01))
+		ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 150.01+float64(i)*0.01))
+	}
+
+	taken := ob.Depth(0, 0)
+	regrouped, direct := taken.Regroup(5, 0.05), ob.Depth(5, 0.05)
+	if len(regrouped.Bids) != len(direct.Bids) || len(regrouped.Asks) != len(direct.Asks) {
+		t.Fatalf("Expected %d and %d levels, got %d and %d", len(direct.Bids), len(direct.Asks), len(regrouped.Bids), len(regrouped.Asks))
+	}
+	for i := range direct.Bids {
+		if regrouped.Bids[i].Price != direct.Bids[i].Price || regrouped.Bids[i].Quantity != direct.Bids[i].Quantity {
+			t.Errorf("Expected bid bucket %d as %+v, got %+v", i, direct.Bids[i], regrouped.Bids[i])
+		}
+	}
+
+	page := taken.Page(models.OrderSideSell, 10, 5)
+	if len(page) != 5 || page[0].Price != ob.SnapshotPage(models.OrderSideSell, 10, 5)[0].Price {
+		t.Errorf("Expected the snapshot page to match the live page, got %+v", page)
+	}
+	if len(taken.Bids) != 50 {
+		t.Error("Expected regrouping to leave the original snapshot alone")
+	}
+}
@@ -2,6 +2,8 @@ package orderbook
 
 import (
 	"container/heap"
+	"math"
+	"time"
 
 	"github.com/acagliol/arbitrax/backend/internal/models"
 )
@@ -10,14 +12,110 @@ import (
 type PriceLevel struct {
 	Price  float64
 	Orders []*models.Order
+
+	pos     int     // Position in its heap's Levels, kept by Swap and Push
+	epsilon float64 // Residue treated as no quantity left, from its heap
+}
+
+// Age returns how long the front-of-queue order has rested at this level
+func (pl *PriceLevel) Age(now time.Time) time.Duration {
+	if len(pl.Orders) == 0 {
+		return 0
+	}
+	return now.Sub(pl.Orders[0].SubmittedAt)
+}
+
+// TotalQuantity returns the remaining quantity of every order at the level
+func (pl *PriceLevel) TotalQuantity() float64 {
+	total := 0.0
+	for _, order := range pl.Orders {
+		total += order.RemainingQuantity(pl.epsilon)
+	}
+	return total
+}
+
+// displayed summarises the level's visible orders, returning false if the
+// level holds only hidden orders
+func (pl *PriceLevel) displayed(now time.Time) (PriceLevelSnapshot, bool) {
+	snapshot := PriceLevelSnapshot{Price: pl.Price}
+	for _, order := range pl.Orders {
+		if order.Hidden {
+			continue
+		}
+		if snapshot.Orders == 0 {
+			snapshot.Age = now.Sub(order.SubmittedAt)
+		}
+		snapshot.Quantity += order.RemainingQuantity(pl.epsilon)
+		snapshot.Orders++
+	}
+	return snapshot, snapshot.Orders > 0
 }
 
 // PriceLevelHeap is a heap of price levels
 // For bids (buy orders), we want max-heap (highest price first)
 // For asks (sell orders), we want min-heap (lowest price first)
+//
+// Levels are also indexed by price so a level can be found without scanning
+// the heap. Levels must only be added and removed through the heap
+// interface and the methods here, which keep the index in step.
 type PriceLevelHeap struct {
 	Levels []*PriceLevel
 	IsBid  bool // true for bid (max-heap), false for ask (min-heap)
+
+	levels    map[float64]*PriceLevel // Keyed by priceKey
+	tolerance models.Tolerance        // Fixed once levels are keyed by it
+}
+
+// priceKey maps a price to its index key by rounding it to a multiple of
+// epsilon, so prices that differ only by floating point noise share a key.
+// This assumes real prices sit on a grid far coarser than epsilon, as tick
+// sizes do; two prices within epsilon of each other but either side of a
+// rounding boundary would get different keys.
+func priceKey(price, epsilon float64) float64 {
+	return math.Round(price/epsilon) * epsilon
+}
+
+// level returns the level at a price, or nil if there is none
+func (h *PriceLevelHeap) level(price float64) *PriceLevel {
+	return h.levels[priceKey(price, h.tolerance.Price)]
+}
+
+// index records a level under its price
+func (h *PriceLevelHeap) index(level *PriceLevel) {
+	if h.levels == nil {
+		h.levels = make(map[float64]*PriceLevel)
+	}
+	h.levels[priceKey(level.Price, h.tolerance.Price)] = level
+}
+
+// unindex forgets a level, leaving any other level at the price indexed
+func (h *PriceLevelHeap) unindex(level *PriceLevel) {
+	key := priceKey(level.Price, h.tolerance.Price)
+	if h.levels[key] == level {
+		delete(h.levels, key)
+	}
+}
+
+// position returns a level's index in Levels, or -1 if it is not there.
+// The tracked position is checked and the slice searched only if it has
+// gone stale.
+func (h *PriceLevelHeap) position(level *PriceLevel) int {
+	if level.pos >= 0 && level.pos < len(h.Levels) && h.Levels[level.pos] == level {
+		return level.pos
+	}
+	for i, l := range h.Levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// adopt takes over another heap's levels and index, in place so that
+// holders of h see the result
+func (h *PriceLevelHeap) adopt(other *PriceLevelHeap) {
+	h.Levels = other.Levels
+	h.levels = other.levels
 }
 
 // Len returns the number of price levels
@@ -38,11 +136,16 @@ func (h *PriceLevelHeap) Less(i, j int) bool {
 // Swap swaps two price levels
 func (h *PriceLevelHeap) Swap(i, j int) {
 	h.Levels[i], h.Levels[j] = h.Levels[j], h.Levels[i]
+	h.Levels[i].pos = i
+	h.Levels[j].pos = j
 }
 
 // Push adds a price level to the heap
 func (h *PriceLevelHeap) Push(x interface{}) {
-	h.Levels = append(h.Levels, x.(*PriceLevel))
+	level := x.(*PriceLevel)
+	level.pos = len(h.Levels)
+	h.Levels = append(h.Levels, level)
+	h.index(level)
 }
 
 // Pop removes and returns the top price level
@@ -51,6 +154,7 @@ func (h *PriceLevelHeap) Pop() interface{} {
 	n := len(old)
 	level := old[n-1]
 	h.Levels = old[0 : n-1]
+	h.unindex(level)
 	return level
 }
 
@@ -64,19 +168,22 @@ func (h *PriceLevelHeap) Peek() *PriceLevel {
 
 // NewBidHeap creates a new max-heap for bid orders
 func NewBidHeap() *PriceLevelHeap {
-	h := &PriceLevelHeap{
-		Levels: make([]*PriceLevel, 0),
-		IsBid:  true,
-	}
-	heap.Init(h)
-	return h
+	return newHeap(true, models.DefaultTolerance)
 }
 
 // NewAskHeap creates a new min-heap for ask orders
 func NewAskHeap() *PriceLevelHeap {
+	return newHeap(false, models.DefaultTolerance)
+}
+
+// newHeap creates an empty heap for one side, comparing prices and
+// quantities within tolerance
+func newHeap(isBid bool, tolerance models.Tolerance) *PriceLevelHeap {
 	h := &PriceLevelHeap{
-		Levels: make([]*PriceLevel, 0),
-		IsBid:  false,
+		Levels:    make([]*PriceLevel, 0),
+		IsBid:     isBid,
+		levels:    make(map[float64]*PriceLevel),
+		tolerance: tolerance.OrDefault(),
 	}
 	heap.Init(h)
 	return h
@@ -85,38 +192,38 @@ func NewAskHeap() *PriceLevelHeap {
 // AddOrder adds an order to the appropriate price level
 func (h *PriceLevelHeap) AddOrder(order *models.Order) {
 	// Find existing price level
-	for _, level := range h.Levels {
-		if level.Price == order.Price {
-			level.Orders = append(level.Orders, order)
-			return
-		}
+	if level := h.level(order.Price); level != nil {
+		level.Orders = append(level.Orders, order)
+		return
 	}
 
 	// Create new price level
 	newLevel := &PriceLevel{
-		Price:  order.Price,
-		Orders: []*models.Order{order},
+		Price:   order.Price,
+		Orders:  []*models.Order{order},
+		epsilon: h.tolerance.Quantity,
 	}
 	heap.Push(h, newLevel)
 }
 
 // RemoveOrder removes an order from the heap
 func (h *PriceLevelHeap) RemoveOrder(order *models.Order) bool {
-	for i, level := range h.Levels {
-		if level.Price == order.Price {
-			for j, o := range level.Orders {
-				if o.ID == order.ID {
-					// Remove order from price level
-					level.Orders = append(level.Orders[:j], level.Orders[j+1:]...)
-
-					// If price level is empty, remove it
-					if len(level.Orders) == 0 {
-						h.Levels = append(h.Levels[:i], h.Levels[i+1:]...)
-						heap.Init(h) // Re-heapify
-					}
-					return true
+	level := h.level(order.Price)
+	if level == nil {
+		return false
+	}
+	for j, o := range level.Orders {
+		if o.ID == order.ID {
+			// Remove order from price level
+			level.Orders = append(level.Orders[:j], level.Orders[j+1:]...)
+
+			// If price level is empty, remove it
+			if len(level.Orders) == 0 {
+				if i := h.position(level); i >= 0 {
+					heap.Remove(h, i)
 				}
 			}
+			return true
 		}
 	}
 	return false
@@ -0,0 +1,103 @@
+package orderbook
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestPriceLevelIndexFollowsHeap(t *testing.T) {
+	h := NewAskHeap()
+	first := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 0.3)
+	h.AddOrder(first)
+
+	// 0.1 + 0.2 is not exactly 0.3, but must join the same level
+	second := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 0.1+0.2)
+	h.AddOrder(second)
+	if h.Len() != 1 || len(h.Peek().Orders) != 2 {
+		t.Fatalf("Expected one level of 2 orders, got %d levels", h.Len())
+	}
+
+	h.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 1, 0.25))
+	level := heap.Pop(h).(*PriceLevel)
+	if level.Price != 0.25 || h.level(0.25) != nil {
+		t.Errorf("Expected the popped 0.25 level dropped from the index, got %g", level.Price)
+	}
+
+	h.RemoveOrder(first)
+	h.RemoveOrder(second)
+	if h.Len() != 0 || len(h.levels) != 0 {
+		t.Errorf("Expected the emptied level removed from heap and index, got %d and %d", h.Len(), len(h.levels))
+	}
+}
+
+func TestRemoveOrderKeepsBestPrice(t *testing.T) {
+	for _, isBid := range []bool{true, false} {
+		h, side := NewAskHeap(), models.OrderSideSell
+		if isBid {
+			h, side = NewBidHeap(), models.OrderSideBuy
+		}
+
+		orders := make([]*models.Order, 0)
+		for i := 0; i < 200; i++ {
+			// Two orders a level, so some cancels leave the level in place
+			for j := 0; j < 2; j++ {
+				order := models.NewOrder("AAPL", models.OrderTypeLimit, side, 1, 100+float64(i)*0.01)
+				h.AddOrder(order)
+				orders = append(orders, order)
+			}
+		}
+
+		rng := rand.New(rand.NewSource(1))
+		rng.Shuffle(len(orders), func(i, j int) { orders[i], orders[j] = orders[j], orders[i] })
+
+		for n, order := range orders {
+			if !h.RemoveOrder(order) {
+				t.Fatalf("Expected order %d to be found", n)
+			}
+
+			best := 0.0
+			for _, left := range orders[n+1:] {
+				if best == 0 || (isBid && left.Price > best) || (!isBid && left.Price < best) {
+					best = left.Price
+				}
+			}
+			top := h.Peek()
+			if best == 0 {
+				if top != nil {
+					t.Errorf("Expected an empty heap, got a level at %g", top.Price)
+				}
+				continue
+			}
+			if top == nil || !models.DefaultTolerance.PricesEqual(top.Price, best) {
+				t.Fatalf("bid=%v: expected best %g after %d cancels, got %v", isBid, best, n+1, top)
+			}
+		}
+		if len(h.levels) != 0 {
+			t.Errorf("Expected the index emptied, got %d levels", len(h.levels))
+		}
+	}
+}
+
+// BenchmarkAddOrderLevels adds and removes an order at a mid-book level of
+// books of increasing depth. The cost should not grow with the depth.
+func BenchmarkAddOrderLevels(b *testing.B) {
+	for _, depth := range []int{10, 100, 1000, 10000} {
+		b.Run(fmt.Sprintf("levels=%d", depth), func(b *testing.B) {
+			h := NewBidHeap()
+			for i := 0; i < depth; i++ {
+				h.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 100+float64(i)*0.01))
+			}
+			order := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 100+float64(depth/2)*0.01)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.AddOrder(order)
+				h.RemoveOrder(order)
+			}
+		})
+	}
+}
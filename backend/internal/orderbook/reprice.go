@@ -0,0 +1,62 @@
+package orderbook
+
+import "github.com/acagliol/arbitrax/backend/internal/models"
+
+// Quote is a best bid and ask, either of them 0 when its side is empty
+type Quote struct {
+	Bid float64
+	Ask float64
+}
+
+// RepriceFunc returns the price a resting order should move to, or false to
+// leave it where it is. reference is the best bid and ask among the orders
+// the reprice leaves out of its reference; touch is the best bid and ask of
+// every live order, which the new price must not lock or cross.
+type RepriceFunc func(order *models.Order, reference, touch Quote) (float64, bool)
+
+// Reprice moves each of orders that is still resting to the price reprice
+// gives it, all under one lock so the book is never seen half repriced.
+// exclude must leave out every order being moved, so the reference quote
+// can be worked out once; the touch is kept up to date as orders move. A
+// moved order joins the back of its new level. It returns how many moved.
+func (ob *OrderBook) Reprice(orders []*models.Order, exclude func(*models.Order) bool, reprice RepriceFunc) int {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	reference := Quote{Bid: bestLive(ob.Bids, exclude), Ask: bestLive(ob.Asks, exclude)}
+	touch := Quote{Bid: bestLive(ob.Bids, nil), Ask: bestLive(ob.Asks, nil)}
+
+	moved := 0
+	for _, order := range orders {
+		if _, resting := ob.orders[order.ID]; !resting {
+			continue
+		}
+		price, ok := reprice(order, reference, touch)
+		if !ok || price <= 0 || ob.tolerance.PricesEqual(price, order.Price) {
+			continue
+		}
+
+		from := order.Price
+		ob.removeOrder(order)
+		order.Price = price
+		ob.addOrder(order)
+		moved++
+
+		// Keep the touch current without rescanning unless the order left it
+		side, best := ob.Asks, &touch.Ask
+		if order.Side == models.OrderSideBuy {
+			side, best = ob.Bids, &touch.Bid
+		}
+		switch {
+		case *best == 0 || side.IsBid && price > *best || !side.IsBid && price < *best:
+			*best = price
+		case ob.tolerance.PricesEqual(from, *best):
+			*best = bestLive(side, nil)
+		}
+	}
+
+	if moved > 0 {
+		ob.markChanged(ChangeReprice)
+	}
+	return moved
+}
@@ -0,0 +1,43 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestRepriceMovesOrdersInOneChange(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+
+	first := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 98.0)
+	second := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 97.0)
+	ob.AddOrder(first)
+	ob.AddOrder(second)
+	before := ob.Snapshot().LastChangeSeq
+
+	moving := map[*models.Order]bool{first: true, second: true}
+	exclude := func(order *models.Order) bool { return moving[order] }
+	moved := ob.Reprice([]*models.Order{first, second}, exclude, func(order *models.Order, reference, touch Quote) (float64, bool) {
+		if reference.Bid != 99.0 || reference.Ask != 101.0 {
+			t.Errorf("Expected a 99 / 101 reference, got %+v", reference)
+		}
+		if order == first {
+			return reference.Bid + 0.5, true
+		}
+		// The touch already includes the first order's move
+		return touch.Bid, true
+	})
+
+	if moved != 2 || first.Price != 99.5 || second.Price != 99.5 {
+		t.Fatalf("Expected both orders moved to 99.5, got %d moved to %g and %g", moved, first.Price, second.Price)
+	}
+	snapshot := ob.Snapshot()
+	if snapshot.LastChangeReason != ChangeReprice || snapshot.LastChangeSeq != before+1 {
+		t.Errorf("Expected a single reprice change, got %q #%d", snapshot.LastChangeReason, snapshot.LastChangeSeq)
+	}
+	if rank, _, _ := ob.QueuePosition(second.ID); rank != 1 {
+		t.Errorf("Expected the second order queued behind the first, got rank %d", rank)
+	}
+}
@@ -0,0 +1,240 @@
+package orderbook
+
+import (
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+// BBO is the best displayed bid and offer
+type BBO struct {
+	BidPrice    float64 `json:"bid_price"`
+	BidQuantity float64 `json:"bid_quantity"`
+	AskPrice    float64 `json:"ask_price"`
+	AskQuantity float64 `json:"ask_quantity"`
+}
+
+// BookStats summarises the size of the book
+type BookStats struct {
+	BidLevels   int     `json:"bid_levels"`
+	AskLevels   int     `json:"ask_levels"`
+	BidQuantity float64 `json:"bid_quantity"`
+	AskQuantity float64 `json:"ask_quantity"`
+	Orders      int     `json:"orders"` // Displayed orders only
+}
+
+// RestingTotals is the quantity and notional resting on each side
+type RestingTotals struct {
+	BidQuantity float64 `json:"bid_quantity"`
+	AskQuantity float64 `json:"ask_quantity"`
+	BidNotional float64 `json:"bid_notional"`
+	AskNotional float64 `json:"ask_notional"`
+}
+
+// FullState is a consistent view of the book for clients that need several
+// pieces of it at once
+type FullState struct {
+	Snapshot *OrderBookSnapshot `json:"snapshot"`
+	BBO      BBO                `json:"bbo"`
+	Stats    BookStats          `json:"stats"`
+	Checksum uint32             `json:"checksum"` // CRC-32 of the snapshot's levels
+}
+
+// FullState returns the snapshot, BBO, stats and checksum all read under a
+// single lock, so they describe the same instant. Like the snapshot, every
+// part shows displayed liquidity only. Snapshot levels are sorted best first.
+func (ob *OrderBook) FullState() *FullState {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	now := ob.clock.Now()
+	snapshot := &OrderBookSnapshot{
+		Symbol:    ob.Symbol,
+		Bids:      groupLevels(ob.Bids, 0, 0, now),
+		Asks:      groupLevels(ob.Asks, 0, 0, now),
+		LastPrice: ob.LastPrice,
+		MidPrice:  ob.midPrice(),
+		Timestamp: ob.Timestamp,
+	}
+	snapshot.LastChangeReason, snapshot.LastChangeSeq = ob.change, ob.changeSeq
+
+	state := &FullState{
+		Snapshot: snapshot,
+		Stats: BookStats{
+			BidLevels: len(snapshot.Bids),
+			AskLevels: len(snapshot.Asks),
+		},
+		Checksum: Checksum(snapshot),
+	}
+	ob.recordChecksum(state.Checksum)
+
+	if len(snapshot.Bids) > 0 {
+		state.BBO.BidPrice = snapshot.Bids[0].Price
+		state.BBO.BidQuantity = snapshot.Bids[0].Quantity
+	}
+	if len(snapshot.Asks) > 0 {
+		state.BBO.AskPrice = snapshot.Asks[0].Price
+		state.BBO.AskQuantity = snapshot.Asks[0].Quantity
+	}
+	for _, level := range snapshot.Bids {
+		state.Stats.BidQuantity += level.Quantity
+		state.Stats.Orders += level.Orders
+	}
+	for _, level := range snapshot.Asks {
+		state.Stats.AskQuantity += level.Quantity
+		state.Stats.Orders += level.Orders
+	}
+
+	return state
+}
+
+// BBO returns the best displayed bid and offer. Levels holding only hidden
+// orders are skipped.
+func (ob *OrderBook) BBO() BBO {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	return ob.displayedTop()
+}
+
+// displayedTop returns the best displayed bid and offer, cached until the
+// book next changes. The caller must hold the mutex, for reading at least.
+func (ob *OrderBook) displayedTop() BBO {
+	if top := ob.top.Load(); top != nil {
+		return *top
+	}
+
+	var top BBO
+	now := ob.clock.Now()
+	top.BidPrice, top.BidQuantity = bestDisplayed(ob.Bids, now)
+	top.AskPrice, top.AskQuantity = bestDisplayed(ob.Asks, now)
+	ob.top.Store(&top)
+	return top
+}
+
+// BBOByAccount returns each account's own best displayed bid and offer, with
+// the quantity the account shows at those prices. Orders without an account
+// are left out.
+func (ob *OrderBook) BBOByAccount() map[string]BBO {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	quotes := make(map[string]BBO)
+	for _, level := range ob.Bids.Levels {
+		for _, order := range level.Orders {
+			if order.AccountID == "" || order.Hidden || order.RemainingQuantity(ob.tolerance.Quantity) <= 0 {
+				continue
+			}
+			quote := quotes[order.AccountID]
+			switch {
+			case quote.BidPrice == 0 || level.Price > quote.BidPrice:
+				quote.BidPrice, quote.BidQuantity = level.Price, order.RemainingQuantity(ob.tolerance.Quantity)
+			case level.Price == quote.BidPrice:
+				quote.BidQuantity += order.RemainingQuantity(ob.tolerance.Quantity)
+			}
+			quotes[order.AccountID] = quote
+		}
+	}
+	for _, level := range ob.Asks.Levels {
+		for _, order := range level.Orders {
+			if order.AccountID == "" || order.Hidden || order.RemainingQuantity(ob.tolerance.Quantity) <= 0 {
+				continue
+			}
+			quote := quotes[order.AccountID]
+			switch {
+			case quote.AskPrice == 0 || level.Price < quote.AskPrice:
+				quote.AskPrice, quote.AskQuantity = level.Price, order.RemainingQuantity(ob.tolerance.Quantity)
+			case level.Price == quote.AskPrice:
+				quote.AskQuantity += order.RemainingQuantity(ob.tolerance.Quantity)
+			}
+			quotes[order.AccountID] = quote
+		}
+	}
+	return quotes
+}
+
+// bestDisplayed returns the price and displayed quantity of a heap's best
+// level with visible orders, or zeros if there is none
+func bestDisplayed(h *PriceLevelHeap, now time.Time) (price, quantity float64) {
+	for _, level := range h.Levels {
+		if price != 0 && (h.IsBid && level.Price <= price || !h.IsBid && level.Price >= price) {
+			continue
+		}
+		if displayed, ok := level.displayed(now); ok {
+			price, quantity = displayed.Price, displayed.Quantity
+		}
+	}
+	return price, quantity
+}
+
+// BestPricesExcluding returns the best bid and ask among live orders that
+// skip does not exclude, 0 for a side with none
+func (ob *OrderBook) BestPricesExcluding(skip func(*models.Order) bool) (bid, ask float64) {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	return bestLive(ob.Bids, skip), bestLive(ob.Asks, skip)
+}
+
+// bestLive returns the best price on one side among live orders that skip
+// does not exclude, 0 if there are none. A nil skip excludes nothing.
+func bestLive(h *PriceLevelHeap, skip func(*models.Order) bool) float64 {
+	best := 0.0
+	for _, level := range h.Levels {
+		if best != 0 && (h.IsBid && level.Price <= best || !h.IsBid && level.Price >= best) {
+			continue
+		}
+		if hasLiveOrder(level, skip) {
+			best = level.Price
+		}
+	}
+	return best
+}
+
+// hasLiveOrder reports whether a level holds a live order skip does not
+// exclude
+func hasLiveOrder(level *PriceLevel, skip func(*models.Order) bool) bool {
+	for _, order := range level.Orders {
+		if order.IsActive() && order.RemainingQuantity(level.epsilon) > 0 && (skip == nil || !skip(order)) {
+			return true
+		}
+	}
+	return false
+}
+
+// TotalResting returns the quantity and notional resting on each side across
+// every level, hidden orders included
+func (ob *OrderBook) TotalResting() RestingTotals {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	var totals RestingTotals
+	for _, level := range ob.Bids.Levels {
+		quantity := level.TotalQuantity()
+		totals.BidQuantity += quantity
+		totals.BidNotional += quantity * level.Price
+	}
+	for _, level := range ob.Asks.Levels {
+		quantity := level.TotalQuantity()
+		totals.AskQuantity += quantity
+		totals.AskNotional += quantity * level.Price
+	}
+	return totals
+}
+
+// Checksum returns a CRC-32 over a snapshot's bid then ask levels, so a
+// client can confirm its copy of the book matches the server's
+func Checksum(snapshot *OrderBookSnapshot) uint32 {
+	var b strings.Builder
+	for _, level := range snapshot.Bids {
+		fmt.Fprintf(&b, "%g:%g|", level.Price, level.Quantity)
+	}
+	b.WriteString("/")
+	for _, level := range snapshot.Asks {
+		fmt.Fprintf(&b, "%g:%g|", level.Price, level.Quantity)
+	}
+	return crc32.ChecksumIEEE([]byte(b.String()))
+}
@@ -0,0 +1,116 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestFullStateConsistent(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	for i, price := range []float64{148.0, 150.0, 149.0} {
+		ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, float64(10*(i+1)), price))
+	}
+	for i, price := range []float64{153.0, 151.0, 152.0} {
+		ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, float64(5*(i+1)), price))
+	}
+
+	state := ob.FullState()
+
+	top := state.Snapshot
+	if state.BBO.BidPrice != top.Bids[0].Price || state.BBO.BidQuantity != top.Bids[0].Quantity {
+		t.Errorf("BBO bid %+v does not match top bid level %+v", state.BBO, top.Bids[0])
+	}
+	if state.BBO.AskPrice != top.Asks[0].Price || state.BBO.AskQuantity != top.Asks[0].Quantity {
+		t.Errorf("BBO ask %+v does not match top ask level %+v", state.BBO, top.Asks[0])
+	}
+	if state.BBO.BidPrice != 150.0 || state.BBO.AskPrice != 151.0 {
+		t.Errorf("Expected BBO 150/151, got %v/%v", state.BBO.BidPrice, state.BBO.AskPrice)
+	}
+
+	if state.Stats.BidLevels != 3 || state.Stats.AskLevels != 3 || state.Stats.Orders != 6 {
+		t.Errorf("Unexpected stats %+v", state.Stats)
+	}
+	if state.Stats.BidQuantity != 60 || state.Stats.AskQuantity != 30 {
+		t.Errorf("Expected 60 bid and 30 ask quantity, got %+v", state.Stats)
+	}
+
+	if state.Checksum != Checksum(state.Snapshot) {
+		t.Error("Checksum does not match the returned snapshot")
+	}
+
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 1, 147.0))
+	if ob.FullState().Checksum == state.Checksum {
+		t.Error("Expected checksum to change with the book")
+	}
+}
+
+func TestTotalResting(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 100.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 20, 100.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 99.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 15, 101.0))
+
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 25, 102.0)
+	hidden.Hidden = true
+	ob.AddOrder(hidden)
+
+	totals := ob.TotalResting()
+
+	if totals.BidQuantity != 35 || totals.AskQuantity != 40 {
+		t.Errorf("Expected 35 bid and 40 ask quantity, got %+v", totals)
+	}
+	if expected := 30*100.0 + 5*99.0; totals.BidNotional != expected {
+		t.Errorf("Expected bid notional %v, got %v", expected, totals.BidNotional)
+	}
+	if expected := 15*101.0 + 25*102.0; totals.AskNotional != expected {
+		t.Errorf("Expected ask notional %v, got %v", expected, totals.AskNotional)
+	}
+}
+
+func TestFullStateHidesHiddenLiquidity(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 149.0))
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 151.0))
+
+	hidden := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 50, 150.5)
+	hidden.Hidden = true
+	ob.AddOrder(hidden)
+
+	state := ob.FullState()
+	if state.BBO.BidPrice != 149.0 || state.Stats.Orders != 2 || state.Stats.BidLevels != 1 {
+		t.Errorf("Expected the hidden bid left out, got %+v and %+v", state.BBO, state.Stats)
+	}
+}
+
+func TestDisplayedTopIsCachedUntilTheBookChanges(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	bid := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 10, 99.0)
+	ob.AddOrder(bid)
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 101.0))
+
+	if bbo := ob.BBO(); bbo.BidPrice != 99.0 || bbo.AskPrice != 101.0 || ob.top.Load() == nil {
+		t.Fatalf("Expected a cached 99 / 101 top, got %+v", bbo)
+	}
+	if mid := ob.GetMidPrice(); mid != 100.0 {
+		t.Errorf("Expected the mid from the cached top, got %g", mid)
+	}
+
+	// A fill applied from outside shows once it is marked
+	bid.Fill(4, 99.0, bid.SubmittedAt, models.DefaultQuantityEpsilon)
+	ob.MarkChanged(ChangeTrade)
+	if ob.top.Load() != nil {
+		t.Error("Expected the change to drop the cached top")
+	}
+	if bbo := ob.BBO(); bbo.BidQuantity != 6 {
+		t.Errorf("Expected 6 left on the bid, got %g", bbo.BidQuantity)
+	}
+
+	ob.AddOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideBuy, 5, 100.0))
+	if bbo := ob.BBO(); bbo.BidPrice != 100.0 || bbo.BidQuantity != 5 {
+		t.Errorf("Expected the new 100 bid on top, got %+v", bbo)
+	}
+}
@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/execreport"
+	"github.com/acagliol/arbitrax/backend/internal/matching"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// Config configures delivery of trades to a webhook
+type Config struct {
+	URL        string
+	BufferSize int           // Trades held awaiting delivery before new ones are dropped
+	MaxRetries int           // Attempts after the first before giving up, negative for none
+	Backoff    time.Duration // Wait before the first retry, doubling for each one after
+	Timeout    time.Duration // Per-request timeout
+
+	// Formatter, if set, posts an execution report for each order in a
+	// trade instead of the trade itself. Orders finds the trade's orders,
+	// e.g. MatchingEngine.LookupOrder; one it can't find is skipped.
+	Formatter execreport.Formatter
+	Orders    func(uuid.UUID) (*models.Order, bool)
+}
+
+// DefaultConfig is used for any zero field in a Config
+var DefaultConfig = Config{
+	BufferSize: 1024,
+	MaxRetries: 3,
+	Backoff:    500 * time.Millisecond,
+	Timeout:    5 * time.Second,
+}
+
+// Notifier POSTs each executed trade as JSON, or its execution reports in
+// the configured format, to a webhook. Payloads are handed to a buffered
+// worker, so a slow or failing webhook never holds up matching; when the
+// buffer is full new ones are dropped and counted.
+type Notifier struct {
+	config   Config
+	client   *http.Client
+	payloads chan payload
+	done     chan struct{}
+	dropped  atomic.Uint64
+	failed   atomic.Uint64
+}
+
+// payload is a rendered body awaiting delivery
+type payload struct {
+	body        []byte
+	contentType string
+}
+
+// NewNotifier creates a notifier and starts its delivery worker
+func NewNotifier(config Config) *Notifier {
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultConfig.BufferSize
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultConfig.MaxRetries
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = DefaultConfig.Backoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultConfig.Timeout
+	}
+
+	n := &Notifier{
+		config:   config,
+		client:   &http.Client{Timeout: config.Timeout},
+		payloads: make(chan payload, config.BufferSize),
+		done:     make(chan struct{}),
+	}
+	go n.deliver()
+	return n
+}
+
+// HandleEvent queues the trade carried by a trade event for delivery. It is
+// meant to be registered with MatchingEngine.OnEvent and never blocks.
+// Execution reports show each order as the trade left it, from the fill
+// state the trade carries, however much more of the order traded before
+// the event was emitted.
+func (n *Notifier) HandleEvent(event matching.Event) {
+	if event.Type != matching.EventTrade || event.Trade == nil {
+		return
+	}
+
+	if n.config.Formatter == nil {
+		if body, err := json.Marshal(event.Trade); err == nil {
+			n.enqueue(payload{body: body, contentType: "application/json"})
+		}
+		return
+	}
+
+	if n.config.Orders == nil {
+		return
+	}
+	for _, orderID := range []uuid.UUID{event.Trade.BuyOrderID, event.Trade.SellOrderID} {
+		order, exists := n.config.Orders(orderID)
+		if !exists {
+			continue
+		}
+		if body, err := n.config.Formatter.Format(event.Trade, order); err == nil {
+			n.enqueue(payload{body: body, contentType: n.config.Formatter.ContentType()})
+		}
+	}
+}
+
+// enqueue hands a payload to the worker, dropping it if the buffer is full
+func (n *Notifier) enqueue(p payload) {
+	select {
+	case n.payloads <- p:
+	default:
+		n.dropped.Add(1)
+	}
+}
+
+// Close stops accepting trades and waits for queued ones to be delivered or
+// given up on
+func (n *Notifier) Close() {
+	close(n.payloads)
+	<-n.done
+}
+
+// Dropped returns how many payloads were dropped because the buffer was full
+func (n *Notifier) Dropped() uint64 {
+	return n.dropped.Load()
+}
+
+// Failed returns how many payloads were given up on after every retry failed
+func (n *Notifier) Failed() uint64 {
+	return n.failed.Load()
+}
+
+// deliver posts queued payloads one at a time until the queue closes
+func (n *Notifier) deliver() {
+	defer close(n.done)
+
+	for p := range n.payloads {
+		if !n.post(p) {
+			n.failed.Add(1)
+		}
+	}
+}
+
+// post sends a payload, retrying with exponential backoff, and reports
+// whether the webhook accepted it
+func (n *Notifier) post(p payload) bool {
+	backoff := n.config.Backoff
+	for attempt := 0; attempt <= max(n.config.MaxRetries, 0); attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err := n.send(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// send makes a single delivery attempt
+func (n *Notifier) send(p payload) error {
+	resp, err := n.client.Post(n.config.URL, p.contentType, bytes.NewReader(p.body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
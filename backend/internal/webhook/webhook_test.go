@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acagliol/arbitrax/backend/internal/execreport"
+	"github.com/acagliol/arbitrax/backend/internal/matching"
+	"github.com/acagliol/arbitrax/backend/internal/models"
+)
+
+func TestNotifierPostsTrades(t *testing.T) {
+	received := make(chan models.Trade, 1)
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt so delivery has to retry
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var trade models.Trade
+		if err := json.NewDecoder(r.Body).Decode(&trade); err != nil {
+			t.Errorf("Expected a JSON trade, got %v", err)
+		}
+		received <- trade
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(Config{URL: server.URL, Backoff: time.Millisecond})
+	me := matching.NewMatchingEngine()
+	me.OnEvent(notifier.HandleEvent)
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 150.0))
+	trades := me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 4, 0))
+	if len(trades) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(trades))
+	}
+
+	select {
+	case trade := <-received:
+		if trade.ID != trades[0].ID || trade.Price != 150.0 || trade.Quantity != 4 {
+			t.Errorf("Expected the executed trade in the payload, got %+v", trade)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the webhook to receive the trade")
+	}
+
+	notifier.Close()
+	if attempts.Load() != 2 || notifier.Failed() != 0 {
+		t.Errorf("Expected delivery on the second attempt, got %d attempts and %d failures", attempts.Load(), notifier.Failed())
+	}
+}
+
+func TestSlowWebhookDoesNotBlockMatching(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(Config{URL: server.URL, BufferSize: 4, MaxRetries: -1})
+	me := matching.NewMatchingEngine()
+	me.OnEvent(notifier.HandleEvent)
+
+	start := time.Now()
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 100, 150.0))
+	for i := 0; i < 20; i++ {
+		me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 1, 0))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected matching to carry on while the webhook stalls, took %s", elapsed)
+	}
+
+	if notifier.Dropped() == 0 {
+		t.Error("Expected trades beyond the buffer to be dropped")
+	}
+
+	close(release)
+	notifier.Close()
+}
+
+func TestNotifierPostsExecutionReports(t *testing.T) {
+	received := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r.Header.Get("Content-Type") + " " + string(body)
+	}))
+	defer server.Close()
+
+	me := matching.NewMatchingEngine()
+	notifier := NewNotifier(Config{
+		URL:       server.URL,
+		Formatter: execreport.FIXFormatter{Delimiter: "|"},
+		Orders:    me.LookupOrder,
+	})
+	me.OnEvent(notifier.HandleEvent)
+
+	sell := models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 10, 150.0)
+	me.SubmitOrder(sell)
+	buy := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 4, 0)
+	me.SubmitOrder(buy)
+	notifier.Close()
+
+	reports := make([]string, 0, 2)
+	for len(received) > 0 {
+		reports = append(reports, <-received)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Expected a report for each side, got %d", len(reports))
+	}
+	for i, order := range []*models.Order{buy, sell} {
+		if !strings.HasPrefix(reports[i], "application/fix 35=8|37="+order.ID.String()+"|") {
+			t.Errorf("Expected a FIX report for order %s, got %s", order.ID, reports[i])
+		}
+	}
+	if !strings.Contains(reports[1], "|39=1|") || !strings.Contains(reports[1], "|151=6|") {
+		t.Errorf("Expected the resting sell reported partially filled with 6 left, got %s", reports[1])
+	}
+}
+
+func TestExecutionReportsShowEachFill(t *testing.T) {
+	received := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer server.Close()
+
+	me := matching.NewMatchingEngine()
+	notifier := NewNotifier(Config{
+		URL:       server.URL,
+		Formatter: execreport.FIXFormatter{Delimiter: "|"},
+		Orders:    me.LookupOrder,
+	})
+	me.OnEvent(notifier.HandleEvent)
+
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 4, 150.0))
+	me.SubmitOrder(models.NewOrder("AAPL", models.OrderTypeLimit, models.OrderSideSell, 6, 151.0))
+	buy := models.NewOrder("AAPL", models.OrderTypeMarket, models.OrderSideBuy, 10, 0)
+	me.SubmitOrder(buy)
+	notifier.Close()
+
+	// The buy's reports come first for each trade; each shows the order as
+	// that fill left it, not as the submission ended
+	reports := make([]string, 0, 4)
+	for len(received) > 0 {
+		reports = append(reports, <-received)
+	}
+	if len(reports) != 4 {
+		t.Fatalf("Expected 4 reports, got %d", len(reports))
+	}
+	if !strings.Contains(reports[0], "|39=1|") || !strings.Contains(reports[0], "|151=6|") || !strings.Contains(reports[0], "|14=4|") {
+		t.Errorf("Expected the first fill reported partial with 4 done and 6 left, got %s", reports[0])
+	}
+	if !strings.Contains(reports[2], "|39=2|") || !strings.Contains(reports[2], "|151=0|") || !strings.Contains(reports[2], "|14=10|") {
+		t.Errorf("Expected the second fill reported filled, got %s", reports[2])
+	}
+}